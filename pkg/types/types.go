@@ -0,0 +1,347 @@
+// Package types holds the JSON messages exchanged between the coordinator and players.
+package types
+
+import "time"
+
+// MoveRequest asks a player for its move in a position. When the game's history is
+// known, StartFEN and Moves describe how FEN was reached so the player can detect
+// repetitions and apply the fifty move rule.
+type MoveRequest struct {
+	FEN      string     `json:"fen"`
+	StartFEN string     `json:"start_fen,omitempty"`
+	Moves    []string   `json:"moves,omitempty"` // Moves played from StartFEN, in UCI notation
+	Error    string     `json:"error,omitempty"` // Why the player's previous answer was rejected
+	Clock    *MoveClock `json:"clock,omitempty"` // Nil for an untimed game
+}
+
+// MoveClock is the state of the clocks when a player is asked for a move. The player
+// to move loses on time once its remaining time, plus any delay, has passed.
+type MoveClock struct {
+	TimeControl     string `json:"time_control"`
+	WhiteMillis     int64  `json:"white_ms"`
+	BlackMillis     int64  `json:"black_ms"`
+	IncrementMillis int64  `json:"increment_ms,omitempty"` // Added after each move
+	DelayMillis     int64  `json:"delay_ms,omitempty"`     // Simple or Bronstein delay
+	MovesToGo       int    `json:"moves_to_go,omitempty"`  // Moves until the next time control, if any
+}
+
+// MoveResponse carries a player's move in UCI notation
+type MoveResponse struct {
+	Move string      `json:"move"`
+	Eval *Evaluation `json:"eval,omitempty"` // The player's assessment of the position, if it has one
+}
+
+// Evaluation is an engine's score for a position from the point of view of the side
+// to move, as in UCI
+type Evaluation struct {
+	Centipawns int      `json:"cp"`
+	Mate       int      `json:"mate,omitempty"` // Mate in this many moves when non-zero, negative when being mated
+	Depth      int      `json:"depth,omitempty"`
+	PV         []string `json:"pv,omitempty"` // Principal variation in UCI notation
+}
+
+// CreateGameRequest asks the coordinator to create a game between two players
+type CreateGameRequest struct {
+	WhitePlayerURL string `json:"white_player_url"`
+	BlackPlayerURL string `json:"black_player_url"`
+	StartFEN       string `json:"start_fen,omitempty"`    // Standard starting position when empty
+	TimeControl    string `json:"time_control,omitempty"` // e.g. "300+2", "40/5400+30", "300d5" or "h60"; untimed when empty
+
+	// IllegalMovePolicy is "forfeit" (default) to end the game on an illegal move, or
+	// "retry" to ask the player again up to IllegalMoveRetries times
+	IllegalMovePolicy  string `json:"illegal_move_policy,omitempty"`
+	IllegalMoveRetries int    `json:"illegal_move_retries,omitempty"`
+
+	// WhiteTranscript and BlackTranscript record the requests and replies exchanged with
+	// that player in the coordinator's transcript directory, as <id>-white.log and <id>-black.log
+	WhiteTranscript bool `json:"white_transcript,omitempty"`
+	BlackTranscript bool `json:"black_transcript,omitempty"`
+}
+
+// CreateGameResponse identifies a newly created game
+type CreateGameResponse struct {
+	ID string `json:"id"`
+}
+
+// GameList lists the games known to the coordinator
+type GameList struct {
+	Games []string `json:"games"`
+}
+
+// Game statuses reported in GameStatus
+const (
+	GameStatusInProgress = "in_progress"
+	GameStatusFinished   = "finished"
+)
+
+// GamePosition is a game's current position
+type GamePosition struct {
+	FEN        string `json:"fen"`
+	SideToMove string `json:"side_to_move"` // "white" or "black"
+}
+
+// GameMoves lists the moves played in a game, in UCI and standard algebraic notation
+type GameMoves struct {
+	StartFEN string   `json:"start_fen"`
+	UCI      []string `json:"uci"`
+	SAN      []string `json:"san"`
+}
+
+// GameMaterial describes the material each side has lost, as lowercase piece letters
+// ordered from pawns to queens
+type GameMaterial struct {
+	CapturedByWhite []string `json:"captured_by_white"`
+	CapturedByBlack []string `json:"captured_by_black"`
+	Balance         int      `json:"balance"` // White's material minus black's, in pawns
+}
+
+// GameClocks holds the players' remaining time. Untimed games have no time control
+// and zero remaining times.
+type GameClocks struct {
+	TimeControl string `json:"time_control,omitempty"`
+	WhiteMillis int64  `json:"white_ms"`
+	BlackMillis int64  `json:"black_ms"`
+	Running     string `json:"running,omitempty"` // Side whose clock is running, if any
+}
+
+// GameStatus tells whether a game is over and how it ended
+type GameStatus struct {
+	Status      string `json:"status"`
+	Result      string `json:"result"` // "1-0", "0-1", "1/2-1/2", or "*" while in progress
+	Termination string `json:"termination,omitempty"`
+}
+
+// GameState is the full state of a game
+type GameState struct {
+	ID       string       `json:"id"`
+	Position GamePosition `json:"position"`
+	Moves    GameMoves    `json:"moves"`
+	Material GameMaterial `json:"material"`
+	Clocks   GameClocks   `json:"clocks"`
+	Status   GameStatus   `json:"status"`
+	Log      []GameEvent  `json:"log"`
+}
+
+// GameEvent is an entry in a game's log, such as an illegal move attempt
+type GameEvent struct {
+	Time    time.Time `json:"time"`
+	Ply     int       `json:"ply"` // Number of moves played when the event happened
+	Side    string    `json:"side"`
+	Message string    `json:"message"`
+}
+
+// GameMoveUpdate is streamed to spectators after every move of a game
+type GameMoveUpdate struct {
+	Ply    int         `json:"ply"` // Number of moves played, including this one
+	Side   string      `json:"side"`
+	UCI    string      `json:"uci"`
+	SAN    string      `json:"san"`
+	FEN    string      `json:"fen"` // Position after the move
+	Clocks GameClocks  `json:"clocks"`
+	Eval   *Evaluation `json:"eval,omitempty"`
+}
+
+// TournamentPlayer is a player service entered in a tournament
+type TournamentPlayer struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Transcript bool   `json:"transcript,omitempty"` // Record a transcript of every game of the player, as in CreateGameRequest
+}
+
+// CreateTournamentRequest asks the coordinator to run a tournament. The game settings
+// apply to every game, as in CreateGameRequest.
+type CreateTournamentRequest struct {
+	Players     []TournamentPlayer `json:"players"`
+	Format      string             `json:"format"`                // "round-robin", "double-round-robin", "gauntlet" or "swiss"
+	Rounds      int                `json:"rounds,omitempty"`      // Games against each opponent in a gauntlet, 2 by default, or rounds of a Swiss tournament
+	Concurrency int                `json:"concurrency,omitempty"` // Games played at the same time, 1 by default
+
+	StartFEN           string `json:"start_fen,omitempty"`
+	TimeControl        string `json:"time_control,omitempty"`
+	IllegalMovePolicy  string `json:"illegal_move_policy,omitempty"`
+	IllegalMoveRetries int    `json:"illegal_move_retries,omitempty"`
+}
+
+// CreateTournamentResponse identifies a newly created tournament
+type CreateTournamentResponse struct {
+	ID string `json:"id"`
+}
+
+// TournamentList lists the tournaments known to the coordinator
+type TournamentList struct {
+	Tournaments []string `json:"tournaments"`
+}
+
+// TournamentGame is a game of a tournament. GameID refers to the game's endpoints
+// once it has started. A player with a bye sits out the round, scoring a win as white
+// without an opponent.
+type TournamentGame struct {
+	Round       int    `json:"round"`
+	White       string `json:"white"`
+	Black       string `json:"black,omitempty"`
+	Bye         bool   `json:"bye,omitempty"`
+	GameID      string `json:"game_id,omitempty"`
+	Result      string `json:"result"`
+	Termination string `json:"termination,omitempty"`
+}
+
+// TournamentStanding is a player's record in a tournament. Ties on points are broken
+// by Buchholz (the opponents' points), then Sonneborn-Berger (the points of the
+// opponents beaten plus half those drawn), then wins; players still level share a rank.
+type TournamentStanding struct {
+	Rank            int     `json:"rank"`
+	Player          string  `json:"player"`
+	Points          float64 `json:"points"`
+	Games           int     `json:"games"`
+	Wins            int     `json:"wins"`
+	Draws           int     `json:"draws"`
+	Losses          int     `json:"losses"`
+	Byes            int     `json:"byes,omitempty"`
+	Buchholz        float64 `json:"buchholz"`
+	SonnebornBerger float64 `json:"sonneborn_berger"`
+}
+
+// CrosstableRow holds a player's results against every player, in standings order:
+// "1", "½" or "0" per game, "x" against themselves
+type CrosstableRow struct {
+	Player  string   `json:"player"`
+	Points  float64  `json:"points"`
+	Results []string `json:"results"`
+}
+
+// TournamentState is the progress and standings of a tournament
+type TournamentState struct {
+	ID         string               `json:"id"`
+	Format     string               `json:"format"`
+	Status     string               `json:"status"` // GameStatusInProgress or GameStatusFinished
+	Players    []string             `json:"players"`
+	Games      []TournamentGame     `json:"games"`
+	Standings  []TournamentStanding `json:"standings"`
+	Crosstable []CrosstableRow      `json:"crosstable"`
+	Error      string               `json:"error,omitempty"` // Why the tournament stopped early
+}
+
+// RatingsRequest asks for ratings fitted to a set of games. Byes and unfinished games
+// are ignored, so a TournamentState can be sent as it is.
+type RatingsRequest struct {
+	Games     []TournamentGame `json:"games"`
+	Prior     *float64         `json:"prior,omitempty"`      // Virtual draws per player against the pool average; 2 if unset, 0 for a plain maximum-likelihood fit
+	Anchor    string           `json:"anchor,omitempty"`     // Player whose rating is fixed at AnchorElo; otherwise ratings average AnchorElo
+	AnchorElo float64          `json:"anchor_elo,omitempty"` // 0 by default
+}
+
+// PlayerRating is a player's rating fitted to all their games. Error is the half-width
+// of the 95% confidence interval.
+type PlayerRating struct {
+	Rank   int     `json:"rank"`
+	Player string  `json:"player"`
+	Elo    float64 `json:"elo"`
+	Error  float64 `json:"error"`
+	Games  int     `json:"games"`
+	Points float64 `json:"points"`
+}
+
+// MatchRating is the Elo difference between two players estimated from their games
+// against each other alone. LOS is the likelihood that Player is the stronger. The
+// difference is unbounded, and left at 0, when one player scored every point.
+type MatchRating struct {
+	Player    string  `json:"player"`
+	Opponent  string  `json:"opponent"`
+	Wins      int     `json:"wins"`
+	Draws     int     `json:"draws"`
+	Losses    int     `json:"losses"`
+	Elo       float64 `json:"elo"`
+	Error     float64 `json:"error"`
+	LOS       float64 `json:"los"`
+	DrawRatio float64 `json:"draw_ratio"`
+	Unbounded bool    `json:"unbounded,omitempty"`
+}
+
+// Ratings are ratings fitted to a set of games by maximum likelihood, as BayesElo
+// does, along with the head-to-head Elo difference of every pair of players who met
+type Ratings struct {
+	Players        []PlayerRating `json:"players"`
+	DrawElo        float64        `json:"draw_elo"`
+	WhiteAdvantage float64        `json:"white_advantage"`
+	Matches        []MatchRating  `json:"matches"`
+}
+
+// CreateSPRTRequest asks the coordinator to test whether a candidate is stronger than
+// a baseline with a sequential probability ratio test. Games are played in pairs from
+// the same opening with colours swapped until the log-likelihood ratio crosses a
+// bound. The game settings apply to every game, as in CreateGameRequest.
+type CreateSPRTRequest struct {
+	Candidate   TournamentPlayer `json:"candidate"`
+	Baseline    TournamentPlayer `json:"baseline"`
+	Elo0        float64          `json:"elo0"`                  // Elo gain of H0; 0 and 5 when neither bound is given
+	Elo1        float64          `json:"elo1"`                  // Elo gain of H1
+	Alpha       float64          `json:"alpha,omitempty"`       // Chance of accepting H1 when H0 holds, 0.05 by default
+	Beta        float64          `json:"beta,omitempty"`        // Chance of accepting H0 when H1 holds, 0.05 by default
+	Openings    []string         `json:"openings,omitempty"`    // FENs played in turn, one per pair; the start position by default
+	MaxPairs    int              `json:"max_pairs,omitempty"`   // Pairs after which the test stops undecided, 10000 by default
+	Concurrency int              `json:"concurrency,omitempty"` // Pairs played at the same time, 1 by default
+
+	TimeControl        string `json:"time_control,omitempty"`
+	IllegalMovePolicy  string `json:"illegal_move_policy,omitempty"`
+	IllegalMoveRetries int    `json:"illegal_move_retries,omitempty"`
+}
+
+// CreateSPRTResponse identifies a newly created test
+type CreateSPRTResponse struct {
+	ID string `json:"id"`
+}
+
+// SPRTList lists the tests known to the coordinator
+type SPRTList struct {
+	Tests []string `json:"tests"`
+}
+
+// SPRT outcomes
+const (
+	SPRTAcceptH0     = "H0"           // The candidate is no stronger than Elo0
+	SPRTAcceptH1     = "H1"           // The candidate is at least Elo1 stronger
+	SPRTInconclusive = "inconclusive" // MaxPairs were played without a decision
+)
+
+// SPRTPair is a pair of games from the same opening, the candidate playing white in
+// the first and black in the second. Pairs with a game that failed are left out of
+// the statistics.
+type SPRTPair struct {
+	Opening string      `json:"opening,omitempty"`
+	Games   [2]SPRTGame `json:"games"`
+}
+
+// SPRTGame is one game of a pair
+type SPRTGame struct {
+	GameID      string `json:"game_id,omitempty"`
+	Result      string `json:"result"`
+	Termination string `json:"termination,omitempty"`
+}
+
+// SPRTState is the progress of a test. Wins, draws and losses are the candidate's;
+// the pentanomial counts pairs in which the candidate scored 0, ½, 1, 1½ and 2 points.
+// Elo, its 95% error margin and the LOS are estimated from the pairs.
+type SPRTState struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"` // GameStatusInProgress or GameStatusFinished
+	Candidate   string     `json:"candidate"`
+	Baseline    string     `json:"baseline"`
+	Elo0        float64    `json:"elo0"`
+	Elo1        float64    `json:"elo1"`
+	Alpha       float64    `json:"alpha"`
+	Beta        float64    `json:"beta"`
+	Result      string     `json:"result,omitempty"` // SPRTAcceptH0, SPRTAcceptH1 or SPRTInconclusive once finished
+	LLR         float64    `json:"llr"`
+	LowerBound  float64    `json:"lower_bound"`
+	UpperBound  float64    `json:"upper_bound"`
+	Pairs       int        `json:"pairs"`
+	Wins        int        `json:"wins"`
+	Draws       int        `json:"draws"`
+	Losses      int        `json:"losses"`
+	Pentanomial [5]int     `json:"pentanomial"`
+	Elo         float64    `json:"elo"`
+	EloError    float64    `json:"elo_error"`
+	LOS         float64    `json:"los"`
+	Games       []SPRTPair `json:"games"`
+	Error       string     `json:"error,omitempty"` // Why the test stopped early
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/clock"
+)
+
+// Reasons a game ends on time
+const (
+	terminationTimeForfeit = "time forfeit"
+	terminationTimeoutDraw = "time forfeit against insufficient material"
+)
+
+// defaultMoveTimeout is how long a player of an untimed game may take to answer
+const defaultMoveTimeout = 10 * time.Second
+
+// minMoveTimeout bounds the request to a player whose time is already up, as an HTTP
+// client without a timeout would wait forever; the flag falls once it returns
+const minMoveTimeout = time.Millisecond
+
+// setTimeControl makes the game timed, or untimed when spec is empty; the caller must
+// hold c.mu
+func (c *ChessCoordinator) setTimeControl(spec string) error {
+	if spec == "" {
+		c.clock = nil
+		return nil
+	}
+
+	tc, err := clock.Parse(spec)
+	if err != nil {
+		return err
+	}
+	c.clock = clock.New(tc)
+	return nil
+}
+
+// moveTimeout returns how long the side to move may take to answer after thinking for
+// elapsed; the caller must hold c.mu
+func (c *ChessCoordinator) moveTimeout(elapsed time.Duration) time.Duration {
+	if c.clock == nil {
+		return defaultMoveTimeout
+	}
+	timeout := c.clock.Available(c.board.IsWhiteToMove()) - elapsed
+	if timeout < minMoveTimeout {
+		timeout = minMoveTimeout
+	}
+	return timeout
+}
+
+// moveClock returns the clocks to send with a move request when the side to move has
+// been thinking for elapsed, or nil for an untimed game; the caller must hold c.mu
+func (c *ChessCoordinator) moveClock(elapsed time.Duration) *types.MoveClock {
+	if c.clock == nil {
+		return nil
+	}
+
+	whiteToMove := c.board.IsWhiteToMove()
+	white, black := c.remaining(whiteToMove, elapsed)
+	tc := c.clock.TimeControl()
+	moveClock := &types.MoveClock{
+		TimeControl: tc.String(),
+		WhiteMillis: white.Milliseconds(),
+		BlackMillis: black.Milliseconds(),
+		MovesToGo:   c.clock.MovesToGo(whiteToMove),
+	}
+	switch tc.Mode {
+	case clock.Increment:
+		moveClock.IncrementMillis = tc.Extra.Milliseconds()
+	case clock.SimpleDelay, clock.Bronstein:
+		moveClock.DelayMillis = tc.Extra.Milliseconds()
+	}
+	return moveClock
+}
+
+// remaining returns both players' time when the side to move, white or not, has been
+// thinking for elapsed; the caller must hold c.mu
+func (c *ChessCoordinator) remaining(whiteToMove bool, elapsed time.Duration) (white, black time.Duration) {
+	white, black = c.clock.Remaining(true), c.clock.Remaining(false)
+	running := &black
+	if whiteToMove {
+		running = &white
+	}
+	*running -= elapsed
+	if *running < 0 {
+		*running = 0
+	}
+	return white, black
+}
+
+// flag ends the game for the side to move running out of time. The opponent wins
+// unless they cannot possibly checkmate, in which case the game is drawn.
+func (c *ChessCoordinator) flag(p player) error {
+	c.logEvent(p.name, terminationTimeForfeit)
+	if !c.board.HasMatingMaterial(!c.board.IsWhiteToMove()) {
+		c.finish("1/2-1/2", terminationTimeoutDraw)
+	} else {
+		c.forfeit(terminationTimeForfeit)
+	}
+	return fmt.Errorf("%s ran out of time", p.name)
+}
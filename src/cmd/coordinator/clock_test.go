@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/board"
+)
+
+// slowPlayer answers with the first legal move after waiting for delay, and passes
+// every request it gets to requests
+func slowPlayer(t *testing.T, delay time.Duration, requests chan<- types.MoveRequest) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.MoveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if requests != nil {
+			requests <- req
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		position := board.NewBoard()
+		if err := position.SetFEN(req.FEN); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(types.MoveResponse{Move: position.LegalMoves()[0].String()})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLossOnTime(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		timeControl string
+		result      string
+		termination string
+	}{
+		{
+			name:        "Slow side loses",
+			timeControl: "0.2",
+			result:      "1-0",
+			termination: terminationTimeForfeit,
+		},
+		{
+			name:        "Draw when the opponent cannot mate",
+			fen:         "q3k3/8/8/8/8/8/8/4K3 w - - 0 1",
+			timeControl: "0.2",
+			result:      "1/2-1/2",
+			termination: terminationTimeoutDraw,
+		},
+		{
+			name:        "Flag falls once the delay is used up",
+			timeControl: "0.1d0.3",
+			result:      "1-0",
+			termination: terminationTimeForfeit,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			white := slowPlayer(t, 0, nil)
+			black := slowPlayer(t, 500*time.Millisecond, nil)
+
+			coordinator := NewChessCoordinator(white.URL, black.URL)
+			if err := coordinator.setTimeControl(test.timeControl); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if test.fen != "" {
+				if err := coordinator.reset(test.fen); err != nil {
+					t.Fatalf("Failed to set position: %v", err)
+				}
+			}
+
+			result, err := coordinator.playGame(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != test.result || coordinator.termination != test.termination {
+				t.Errorf("Expected %s (%s), got %s (%s)", test.result, test.termination, result, coordinator.termination)
+			}
+			if len(coordinator.moves) != 1 {
+				t.Errorf("Expected black to flag on its first move, got moves %q", coordinator.movesString())
+			}
+			if coordinator.clock.Remaining(false) != 0 {
+				t.Errorf("Expected black's clock at zero, got %v", coordinator.clock.Remaining(false))
+			}
+		})
+	}
+}
+
+func TestMoveRequestClock(t *testing.T) {
+	requests := make(chan types.MoveRequest, 2)
+	white := slowPlayer(t, 0, requests)
+	black := slowPlayer(t, 0, requests)
+
+	coordinator := NewChessCoordinator(white.URL, black.URL)
+	if err := coordinator.setTimeControl("40/300+2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := coordinator.playTurn(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	first, second := <-requests, <-requests
+	if first.Clock == nil || second.Clock == nil {
+		t.Fatal("Expected clocks in the move requests")
+	}
+	if first.Clock.TimeControl != "40/300+2" || first.Clock.WhiteMillis != 300000 || first.Clock.BlackMillis != 300000 ||
+		first.Clock.IncrementMillis != 2000 || first.Clock.MovesToGo != 40 {
+		t.Errorf("Unexpected clock in the first request: %+v", *first.Clock)
+	}
+	if second.Clock.WhiteMillis <= 300000 || second.Clock.WhiteMillis > 302000 {
+		t.Errorf("Expected white's increment in the second request, got %+v", *second.Clock)
+	}
+
+	clocks := coordinator.state().Clocks
+	if clocks.TimeControl != "40/300+2" || clocks.Running != "" || clocks.BlackMillis <= 300000 {
+		t.Errorf("Unexpected clocks: %+v", clocks)
+	}
+}
+
+func TestUntimedMoveRequest(t *testing.T) {
+	requests := make(chan types.MoveRequest, 1)
+	white := slowPlayer(t, 0, requests)
+	coordinator := NewChessCoordinator(white.URL, white.URL)
+
+	if _, err := coordinator.playTurn(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req := <-requests; req.Clock != nil {
+		t.Errorf("Expected no clock for an untimed game, got %+v", *req.Clock)
+	}
+
+	if err := coordinator.setTimeControl("fast"); err == nil || !strings.Contains(err.Error(), "fast") {
+		t.Errorf("Expected error for an invalid time control, got %v", err)
+	}
+}
+
+func TestMoveTimeout(t *testing.T) {
+	coordinator := NewChessCoordinator("", "")
+	if timeout := coordinator.moveTimeout(time.Hour); timeout != defaultMoveTimeout {
+		t.Errorf("Expected %v for an untimed game, got %v", defaultMoveTimeout, timeout)
+	}
+
+	if err := coordinator.setTimeControl("60d2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if timeout := coordinator.moveTimeout(2 * time.Second); timeout != time.Minute {
+		t.Errorf("Expected the time left plus the delay, got %v", timeout)
+	}
+
+	// A player out of time, as on a retry after an illegal move, still gets a bounded request
+	if timeout := coordinator.moveTimeout(2 * time.Minute); timeout != minMoveTimeout {
+		t.Errorf("Expected %v once the time is up, got %v", minMoveTimeout, timeout)
+	}
+}
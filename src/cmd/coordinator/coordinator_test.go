@@ -1,7 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/shehio/envoy/src/internal/transcript"
+	"github.com/shehio/envoy/pkg/types"
 )
 
 func TestNewChessCoordinator(t *testing.T) {
@@ -134,4 +142,121 @@ func TestMakeMove(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestGetMoveSendsHistory(t *testing.T) {
+	var received types.MoveRequest
+	player := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(types.MoveResponse{Move: "g1f3"})
+	}))
+	defer player.Close()
+
+	coordinator := NewChessCoordinator(player.URL, player.URL)
+	startFEN := coordinator.board.GetFEN()
+	for _, move := range []string{"g1f3", "g8f6"} {
+		if err := coordinator.makeMove(move); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	fen := coordinator.board.GetFEN()
+	if _, err := coordinator.getMoveFromPlayer(fen); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if received.FEN != fen || received.StartFEN != startFEN {
+		t.Errorf("Unexpected request %+v", received)
+	}
+	if !reflect.DeepEqual(received.Moves, []string{"g1f3", "g8f6"}) {
+		t.Errorf("Expected the move history, got %v", received.Moves)
+	}
+
+	// A position outside the game is sent without history
+	received = types.MoveRequest{}
+	if _, err := coordinator.getMoveFromPlayer(startFEN); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.StartFEN != "" || received.Moves != nil {
+		t.Errorf("Expected no history, got %+v", received)
+	}
+}
+
+func TestPlayerTranscript(t *testing.T) {
+	player := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(types.MoveResponse{Move: "e2e4"})
+	}))
+	defer player.Close()
+
+	var white, black strings.Builder
+	coordinator := NewChessCoordinator(player.URL, player.URL)
+	coordinator.whiteTranscript = transcript.New(&white)
+	coordinator.blackTranscript = transcript.New(&black)
+
+	if _, err := coordinator.getMoveFromPlayer(coordinator.board.GetFEN()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(white.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a request and a reply, got %q", lines)
+	}
+	if !strings.Contains(lines[0], "[white] > {") || !strings.Contains(lines[1], `[white] < 200 {"move":"e2e4"}`) {
+		t.Errorf("Unexpected transcript %q", lines)
+	}
+
+	if black.Len() != 0 {
+		t.Errorf("Expected nothing in the black transcript, got %q", black.String())
+	}
+}
+
+func TestOpenTranscript(t *testing.T) {
+	if record, err := openTranscript(""); err != nil || record != nil {
+		t.Errorf("Expected no transcript, got %v, %v", record, err)
+	}
+
+	if _, err := openTranscript(t.TempDir() + "/missing/white.log"); err == nil {
+		t.Error("Expected error but got none")
+	}
+
+	if record, err := openTranscript(t.TempDir() + "/white.log"); err != nil || record == nil {
+		t.Errorf("Expected a transcript, got %v, %v", record, err)
+	}
+}
+
+func TestIllegalMoveSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		retries     string
+		expected    int
+		expectError bool
+	}{
+		{name: "Unset", expected: 0},
+		{name: "Forfeit", policy: "forfeit", expected: 0},
+		{name: "Retry with count", policy: "retry", retries: "5", expected: 5},
+		{name: "Retry with default count", policy: "retry", expected: defaultIllegalMoveRetries},
+		{name: "Negative count", policy: "retry", retries: "-1", expectError: true},
+		{name: "Non-numeric count", policy: "retry", retries: "many", expectError: true},
+		{name: "Non-numeric count without policy", retries: "3x", expectError: true},
+		{name: "Unknown policy", policy: "ignore", retries: "2", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			retries, err := illegalMoveSettings(test.policy, test.retries)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if retries != test.expected {
+				t.Errorf("Expected %d retries, got %d", test.expected, retries)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/board"
+	"github.com/shehio/envoy/src/internal/clock"
+)
+
+// Reasons a game ends that the board cannot decide by itself
+const (
+	terminationRepetition  = "threefold repetition"
+	terminationIllegalMove = "illegal move"
+	terminationPlayerError = "player error"
+)
+
+// Policies for a player that answers with an illegal move
+const (
+	illegalMoveForfeit = "forfeit" // The player loses at once
+	illegalMoveRetry   = "retry"   // The player is told why and asked again
+)
+
+// defaultIllegalMoveRetries is how often a player is asked again under the retry
+// policy when no count is given
+const defaultIllegalMoveRetries = 3
+
+// illegalMoveRetries returns how many times a player may retry after an illegal move
+// under the named policy
+func illegalMoveRetries(policy string, retries int) (int, error) {
+	switch policy {
+	case "", illegalMoveForfeit:
+		return 0, nil
+	case illegalMoveRetry:
+		if retries < 0 {
+			return 0, fmt.Errorf("invalid retry count %d", retries)
+		}
+		if retries == 0 {
+			return defaultIllegalMoveRetries, nil
+		}
+		return retries, nil
+	default:
+		return 0, fmt.Errorf("unknown illegal move policy %q", policy)
+	}
+}
+
+// reset starts a new game from fen; the caller must hold c.mu
+func (c *ChessCoordinator) reset(fen string) error {
+	b := board.NewBoard()
+	if err := b.SetFEN(fen); err != nil {
+		return err
+	}
+
+	c.board = b
+	c.startFEN = b.GetFEN()
+	c.moves = nil
+	c.san = nil
+	c.events = nil
+	c.repetitions = map[string]int{b.RepetitionKey(): 1}
+	if c.clock != nil {
+		c.clock = clock.New(c.clock.TimeControl())
+	}
+	c.finish("*", "")
+	c.checkGameOver()
+	return nil
+}
+
+// errGameOver is returned when a move is requested in a finished game
+var errGameOver = fmt.Errorf("game is over")
+
+// playGame asks the players for their moves in turn until the game ends, validating
+// every move, and returns the recorded result. A player that fails to answer loses the
+// game, as does one that runs out of retries for illegal moves.
+func (c *ChessCoordinator) playGame(ctx context.Context) (string, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return "*", err
+		}
+		if _, err := c.playTurn(); err == errGameOver {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	log.Printf("Game over: %s (%s) after %d moves", c.result, c.termination, len(c.moves))
+	return c.result, nil
+}
+
+// playTurn asks the side to move for its move and plays it. An illegal move is
+// recorded in the game log and, while retries remain, sent back to the player with the
+// reason it was rejected; the player's clock keeps running meanwhile. The game's lock
+// is released while waiting for the player, so its state can be read meanwhile.
+func (c *ChessCoordinator) playTurn() (string, error) {
+	c.turn.Lock()
+	defer c.turn.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.result != "*" {
+		return "", errGameOver
+	}
+	p := c.playerToMove()
+	white := c.board.IsWhiteToMove()
+	req := c.moveRequest(c.board.GetFEN())
+
+	start := time.Now()
+	c.turnStarted = start
+	defer func() {
+		c.turnStarted = time.Time{}
+		if c.result != "*" {
+			c.publish(streamEventEnd, c.snapshot().Status)
+		}
+	}()
+
+	for attempt := 0; ; attempt++ {
+		timeout := c.moveTimeout(time.Since(start))
+		c.mu.Unlock()
+		resp, err := c.requestMove(p, req, timeout)
+		move := resp.Move
+		c.mu.Lock()
+
+		elapsed := time.Since(start)
+		if c.clock != nil && c.clock.Expired(white, elapsed) {
+			c.clock.Punch(white, elapsed)
+			return "", c.flag(p)
+		}
+
+		if err != nil {
+			c.logEvent(p.name, fmt.Sprintf("%s: %v", terminationPlayerError, err))
+			c.forfeit(fmt.Sprintf("%s: %v", terminationPlayerError, err))
+			return "", err
+		}
+
+		err = c.playMove(move)
+		if err == nil {
+			if c.clock != nil {
+				c.clock.Punch(white, elapsed)
+			}
+			c.publishMove(resp.Eval)
+			return move, nil
+		}
+
+		c.logEvent(p.name, fmt.Sprintf("illegal move %q (attempt %d): %v", move, attempt+1, err))
+		p.transcript.Note(p.name, fmt.Sprintf("rejected %q: %v", move, err))
+		if attempt >= c.illegalMoveRetries {
+			c.forfeit(fmt.Sprintf("%s: %v", terminationIllegalMove, err))
+			return move, err
+		}
+		req.Error = fmt.Sprintf("illegal move %q: %v", move, err)
+		req.Clock = c.moveClock(elapsed)
+	}
+}
+
+// playMove applies move and ends the game if the move finished it
+func (c *ChessCoordinator) playMove(moveStr string) error {
+	if err := c.makeMove(moveStr); err != nil {
+		return err
+	}
+	c.checkGameOver()
+	return nil
+}
+
+// logEvent records something that happened in the game; the caller must hold c.mu
+func (c *ChessCoordinator) logEvent(side, message string) {
+	log.Printf("%s: %s", side, message)
+	c.events = append(c.events, types.GameEvent{
+		Time:    time.Now().UTC(),
+		Ply:     len(c.moves),
+		Side:    side,
+		Message: message,
+	})
+}
+
+// checkGameOver records the result if the game has ended by rule
+func (c *ChessCoordinator) checkGameOver() {
+	if termination := c.board.Termination(); termination != "" {
+		c.finish(c.board.Result(), termination)
+	} else if c.repetitions[c.board.RepetitionKey()] >= 3 {
+		c.finish("1/2-1/2", terminationRepetition)
+	}
+}
+
+// forfeit ends the game as a loss for the side to move
+func (c *ChessCoordinator) forfeit(reason string) {
+	result := "0-1"
+	if !c.board.IsWhiteToMove() {
+		result = "1-0"
+	}
+	c.finish(result, reason)
+}
+
+// finish records the game's result. Once the game has ended nothing more is sent to the
+// players, so their transcripts are closed.
+func (c *ChessCoordinator) finish(result, termination string) {
+	ended := c.result == "*" && result != "*"
+	c.result = result
+	c.termination = termination
+	if result != "*" {
+		c.closeTranscripts()
+	}
+	if ended && c.onFinish != nil {
+		c.onFinish(c.id)
+	}
+}
+
+// closeTranscripts closes the players' transcripts
+func (c *ChessCoordinator) closeTranscripts() {
+	if err := c.whiteTranscript.Close(); err != nil {
+		log.Printf("Failed to close white transcript: %v", err)
+	}
+	if err := c.blackTranscript.Close(); err != nil {
+		log.Printf("Failed to close black transcript: %v", err)
+	}
+}
+
+// movesString returns the moves played so far, separated by spaces; the caller must hold c.mu
+func (c *ChessCoordinator) movesString() string {
+	return strings.Join(c.moves, " ")
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/board"
+)
+
+// scriptedPlayer answers with the given moves in order and then with the first legal
+// move of each position
+func scriptedPlayer(t *testing.T, moves ...string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.MoveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		move := ""
+		if len(moves) > 0 {
+			move, moves = moves[0], moves[1:]
+		} else {
+			position := board.NewBoard()
+			if err := position.SetFEN(req.FEN); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			move = position.LegalMoves()[0].String()
+		}
+		json.NewEncoder(w).Encode(types.MoveResponse{Move: move})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPlayGame(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		white       []string
+		black       []string
+		result      string
+		termination string
+		moves       string
+	}{
+		{
+			name:        "Checkmate",
+			white:       []string{"f2f3", "g2g4"},
+			black:       []string{"e7e5", "d8h4"},
+			result:      "0-1",
+			termination: board.TerminationCheckmate,
+			moves:       "f2f3 e7e5 g2g4 d8h4",
+		},
+		{
+			name:        "Threefold repetition",
+			white:       []string{"g1f3", "f3g1", "g1f3", "f3g1"},
+			black:       []string{"g8f6", "f6g8", "g8f6", "f6g8"},
+			result:      "1/2-1/2",
+			termination: terminationRepetition,
+			moves:       "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8",
+		},
+		{
+			name:        "Stalemate",
+			fen:         "7k/8/5QK1/8/8/8/8/8 w - - 0 1",
+			white:       []string{"f6f7"},
+			result:      "1/2-1/2",
+			termination: board.TerminationStalemate,
+			moves:       "f6f7",
+		},
+		{
+			name:        "Fifty-move rule",
+			fen:         "7k/8/8/8/8/8/8/R6K w - - 99 80",
+			white:       []string{"a1a2"},
+			result:      "1/2-1/2",
+			termination: board.TerminationFiftyMoveRule,
+			moves:       "a1a2",
+		},
+		{
+			name:        "Illegal move loses",
+			white:       []string{"e2e4"},
+			black:       []string{"e7e4"},
+			result:      "1-0",
+			termination: terminationIllegalMove,
+			moves:       "e2e4",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			white := scriptedPlayer(t, test.white...)
+			black := scriptedPlayer(t, test.black...)
+
+			coordinator := NewChessCoordinator(white.URL, black.URL)
+			if test.fen != "" {
+				if err := coordinator.reset(test.fen); err != nil {
+					t.Fatalf("Failed to set position: %v", err)
+				}
+			}
+
+			result, err := coordinator.playGame(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != test.result {
+				t.Errorf("Expected result %s, got %s", test.result, result)
+			}
+			if !strings.HasPrefix(coordinator.termination, test.termination) {
+				t.Errorf("Expected termination %q, got %q", test.termination, coordinator.termination)
+			}
+			if coordinator.movesString() != test.moves {
+				t.Errorf("Expected moves %q, got %q", test.moves, coordinator.movesString())
+			}
+		})
+	}
+}
+
+func TestPlayGameUnreachablePlayer(t *testing.T) {
+	white := scriptedPlayer(t)
+	coordinator := NewChessCoordinator(white.URL, "http://127.0.0.1:1")
+
+	result, err := coordinator.playGame(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "1-0" || !strings.HasPrefix(coordinator.termination, terminationPlayerError) {
+		t.Errorf("Expected black to forfeit, got %s (%s)", result, coordinator.termination)
+	}
+
+	if err := coordinator.makeMove("e7e5"); err == nil {
+		t.Error("Expected error for a move after the game ended but got none")
+	}
+}
+
+func TestLegacyMove(t *testing.T) {
+	white := scriptedPlayer(t, "e2e4", "e1e3", "d2d4")
+	black := scriptedPlayer(t, "e7e5")
+	broken := false
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken {
+			http.Error(w, "Out of order", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, black.URL, http.StatusTemporaryRedirect)
+	}))
+	defer flaky.Close()
+
+	coordinator := NewChessCoordinator(white.URL, flaky.URL)
+	start := coordinator.board.GetFEN()
+	afterE4 := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+
+	steps := []struct {
+		name     string
+		fen      string
+		broken   bool
+		status   int
+		expected string
+	}{
+		{name: "Caller's position", fen: start, status: http.StatusOK, expected: "e2e4"},
+		{name: "Player error is retryable", fen: afterE4, broken: true, status: http.StatusInternalServerError},
+		{name: "Retry", fen: afterE4, status: http.StatusOK, expected: "e7e5"},
+		{name: "Illegal move is rejected", status: http.StatusBadRequest},
+		{name: "Game goes on", status: http.StatusOK, expected: "d2d4"},
+	}
+
+	for _, step := range steps {
+		broken = step.broken
+		body, _ := json.Marshal(types.MoveRequest{FEN: step.fen})
+		recorder := httptest.NewRecorder()
+		coordinator.serveLegacyMove(recorder, httptest.NewRequest(http.MethodPost, "/move", strings.NewReader(string(body))))
+
+		if recorder.Code != step.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", step.name, step.status, recorder.Code, recorder.Body)
+		}
+		if step.expected == "" {
+			continue
+		}
+		var resp types.MoveResponse
+		if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil || resp.Move != step.expected {
+			t.Errorf("%s: expected %s, got %+v: %v", step.name, step.expected, resp, err)
+		}
+	}
+
+	if coordinator.result != "*" || coordinator.movesString() != "e2e4 e7e5 d2d4" {
+		t.Errorf("Expected the game in progress after e2e4 e7e5 d2d4, got %s after %q", coordinator.result, coordinator.movesString())
+	}
+}
+
+func TestIllegalMoveRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		retries     int
+		expected    int
+		expectError bool
+	}{
+		{name: "Default forfeits", expected: 0},
+		{name: "Forfeit", policy: "forfeit", retries: 5, expected: 0},
+		{name: "Retry with count", policy: "retry", retries: 2, expected: 2},
+		{name: "Retry with default count", policy: "retry", expected: defaultIllegalMoveRetries},
+		{name: "Negative count", policy: "retry", retries: -1, expectError: true},
+		{name: "Unknown policy", policy: "ignore", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			retries, err := illegalMoveRetries(test.policy, test.retries)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if retries != test.expected {
+				t.Errorf("Expected %d retries, got %d", test.expected, retries)
+			}
+		})
+	}
+}
+
+func TestIllegalMovePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  int
+		white    []string
+		black    []string
+		result   string
+		moves    string
+		attempts int // Illegal moves expected in the game log
+		errors   int // Error messages expected to be sent to black
+	}{
+		{
+			name:     "Forfeit",
+			white:    []string{"e2e4"},
+			black:    []string{"e7e4"},
+			result:   "1-0",
+			moves:    "e2e4",
+			attempts: 1,
+		},
+		{
+			name:     "Retry then legal move",
+			retries:  2,
+			white:    []string{"f2f3", "g2g4"},
+			black:    []string{"e7e5", "e7e4", "e8e6", "d8h4"},
+			result:   "0-1",
+			moves:    "f2f3 e7e5 g2g4 d8h4",
+			attempts: 2,
+			errors:   2,
+		},
+		{
+			name:     "Retries exhausted",
+			retries:  1,
+			white:    []string{"e2e4"},
+			black:    []string{"e7e4", "e7e3"},
+			result:   "1-0",
+			moves:    "e2e4",
+			attempts: 2,
+			errors:   1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			white := scriptedPlayer(t, test.white...)
+
+			// Black answers with its script and remembers the errors it was sent
+			var errors []string
+			script := test.black
+			black := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req types.MoveRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				if req.Error != "" {
+					errors = append(errors, req.Error)
+				}
+				move := script[0]
+				script = script[1:]
+				json.NewEncoder(w).Encode(types.MoveResponse{Move: move})
+			}))
+			defer black.Close()
+
+			coordinator := NewChessCoordinator(white.URL, black.URL)
+			coordinator.illegalMoveRetries = test.retries
+
+			result, err := coordinator.playGame(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != test.result {
+				t.Errorf("Expected result %s, got %s (%s)", test.result, result, coordinator.termination)
+			}
+			if coordinator.movesString() != test.moves {
+				t.Errorf("Expected moves %q, got %q", test.moves, coordinator.movesString())
+			}
+
+			if len(coordinator.events) != test.attempts {
+				t.Errorf("Expected %d illegal attempts in the game log, got %d", test.attempts, len(coordinator.events))
+			}
+			for _, event := range coordinator.events {
+				if event.Side != "black" || !strings.Contains(event.Message, "illegal move") {
+					t.Errorf("Unexpected game log entry: %+v", event)
+				}
+			}
+			if len(errors) != test.errors {
+				t.Errorf("Expected %d error messages to the player, got %v", test.errors, errors)
+			}
+		})
+	}
+}
@@ -2,69 +2,252 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/shehio/envoy/src/internal/board"
+	"github.com/shehio/envoy/src/internal/clock"
+	"github.com/shehio/envoy/src/internal/transcript"
 	"github.com/shehio/envoy/pkg/types"
 )
 
+const (
+	transcriptMaxBytes   = 10 << 20
+	transcriptMaxBackups = 5
+
+	// shutdownTimeout is how long the server waits for open requests when stopping
+	shutdownTimeout = 5 * time.Second
+)
+
 type ChessCoordinator struct {
+	mu   sync.Mutex // Guards the game state below
+	turn sync.Mutex // Held while a player is asked for a move, so one turn runs at a time
+
 	whitePlayerURL string
 	blackPlayerURL string
 	board         *board.Board
+	startFEN      string         // Position the game started from
+	moves         []string       // Moves played since startFEN, in UCI notation
+	san           []string       // The same moves in standard algebraic notation
+	repetitions   map[string]int // Occurrences of each position, for threefold repetition
+	result        string         // "1-0", "0-1", "1/2-1/2", or "*" while the game is in progress
+	termination   string         // Why the game ended
+
+	// Game log of notable events such as illegal move attempts
+	events []types.GameEvent
+
+	// Spectators streaming the game, see stream.go
+	subscribers map[chan streamEvent]struct{}
+
+	// Optional records of the requests and replies exchanged with each player
+	whiteTranscript *transcript.Transcript
+	blackTranscript *transcript.Transcript
+
+	id          string // Registry ID, empty for the default game
+	playing     bool   // Whether the game is being played to the end in the background
+
+	clock       *clock.Clock // Players' clocks, nil for an untimed game
+	turnStarted time.Time    // When the side to move was asked for its move, zero between turns
+
+	illegalMoveRetries int // How often a player may answer again after an illegal move
+
+	onFinish func(id string) // Called with c.mu held once the game has ended, see gameRegistry.add
 }
 
 func NewChessCoordinator(whitePlayerURL, blackPlayerURL string) *ChessCoordinator {
+	b := board.NewBoard()
 	return &ChessCoordinator{
 		whitePlayerURL: whitePlayerURL,
 		blackPlayerURL: blackPlayerURL,
-		board:         board.NewBoard(),
+		board:         b,
+		startFEN:      b.GetFEN(),
+		repetitions:   map[string]int{b.RepetitionKey(): 1},
+		result:        "*",
 	}
 }
 
 func (c *ChessCoordinator) getMoveFromPlayer(fen string) (string, error) {
-	url := c.whitePlayerURL
+	c.mu.Lock()
+	p := c.playerToMove()
+	req := c.moveRequest(fen)
+	c.mu.Unlock()
+
+	resp, err := c.requestMove(p, req, defaultMoveTimeout)
+	return resp.Move, err
+}
+
+// player is the service playing one side of a game
+type player struct {
+	name       string // Side, prefixed with the game ID when the game has one
+	url        string
+	transcript *transcript.Transcript
+}
+
+// playerToMove returns the player whose turn it is
+func (c *ChessCoordinator) playerToMove() player {
+	p := player{name: "white", url: c.whitePlayerURL, transcript: c.whiteTranscript}
 	if !c.board.IsWhiteToMove() {
-		url = c.blackPlayerURL
+		p = player{name: "black", url: c.blackPlayerURL, transcript: c.blackTranscript}
+	}
+	if c.id != "" {
+		p.name = c.id + "/" + p.name
 	}
+	return p
+}
 
+// moveRequest builds the request for a move in fen
+func (c *ChessCoordinator) moveRequest(fen string) types.MoveRequest {
 	req := types.MoveRequest{FEN: fen}
+
+	// Send the history and clocks when asked about the game's current position
+	if fen == c.board.GetFEN() {
+		req.StartFEN = c.startFEN
+		req.Moves = append([]string(nil), c.moves...)
+		req.Clock = c.moveClock(0)
+	}
+	return req
+}
+
+// requestMove sends req to the player and returns its reply, giving up after timeout.
+// It does not touch the game, so it runs without holding the game's lock.
+func (c *ChessCoordinator) requestMove(p player, req types.MoveRequest, timeout time.Duration) (types.MoveResponse, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return types.MoveResponse{}, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	p.transcript.Sent(p.name, string(jsonData))
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Post(p.url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		p.transcript.Note(p.name, fmt.Sprintf("request failed: %v", err))
+		return types.MoveResponse{}, fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		p.transcript.Note(p.name, fmt.Sprintf("reading response failed: %v", err))
+		return types.MoveResponse{}, fmt.Errorf("failed to read response: %v", err)
+	}
+	p.transcript.Received(p.name, fmt.Sprintf("%d %s", resp.StatusCode, strings.TrimSpace(string(body))))
+
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("player returned status %d", resp.StatusCode)
+		return types.MoveResponse{}, fmt.Errorf("player returned status %d", resp.StatusCode)
 	}
 
 	var moveResp types.MoveResponse
-	if err := json.NewDecoder(resp.Body).Decode(&moveResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
+	if err := json.Unmarshal(body, &moveResp); err != nil {
+		return types.MoveResponse{}, fmt.Errorf("failed to decode response: %v", err)
 	}
 
-	return moveResp.Move, nil
+	return moveResp, nil
 }
 
+// makeMove applies a move to the game after checking it against the legal moves; the
+// caller must hold c.mu
 func (c *ChessCoordinator) makeMove(moveStr string) error {
+	if c.result != "*" {
+		return fmt.Errorf("game is over: %s (%s)", c.result, c.termination)
+	}
+
 	move, err := board.ParseMove(moveStr)
 	if err != nil {
 		return fmt.Errorf("invalid move format: %v", err)
 	}
-	return c.board.MakeMove(move)
+	if !c.board.IsLegal(move) {
+		return fmt.Errorf("%s is not legal in %s", moveStr, c.board.GetFEN())
+	}
+	san := c.board.SAN(move)
+	if err := c.board.MakeMove(move); err != nil {
+		return err
+	}
+	c.moves = append(c.moves, move.String())
+	c.san = append(c.san, san)
+	c.repetitions[c.board.RepetitionKey()]++
+	return nil
+}
+
+// serveLegacyMove handles POST /move for the default game: the player to move is asked
+// for its move in the request's FEN and the move is played. A failed request is
+// reported as a server error the caller may retry and an illegal move as a bad
+// request; neither ends the game. The move is not timed.
+func (c *ChessCoordinator) serveLegacyMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Hold the turn so the move cannot interleave with one played through /games
+	c.turn.Lock()
+	defer c.turn.Unlock()
+
+	move, err := c.getMoveFromPlayer(req.FEN)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	c.mu.Lock()
+	err = c.playMove(move)
+	if err == nil {
+		c.publishMove(nil)
+		if c.result != "*" {
+			c.publish(streamEventEnd, c.snapshot().Status)
+		}
+	}
+	c.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(types.MoveResponse{Move: move})
+}
+
+// illegalMoveSettings returns the retries allowed after an illegal move for the policy
+// and retry count given as text, as in ILLEGAL_MOVE_POLICY and ILLEGAL_MOVE_RETRIES
+func illegalMoveSettings(policy, retries string) (int, error) {
+	count := 0
+	if retries != "" {
+		var err error
+		if count, err = strconv.Atoi(retries); err != nil {
+			return 0, fmt.Errorf("invalid retry count %q", retries)
+		}
+	}
+	return illegalMoveRetries(policy, count)
+}
+
+// openTranscript opens the transcript described by spec: empty disables it, "-" writes
+// to stderr and anything else names a rotating file
+func openTranscript(spec string) (*transcript.Transcript, error) {
+	switch spec {
+	case "":
+		return nil, nil
+	case "-":
+		return transcript.New(os.Stderr), nil
+	}
+
+	return transcript.Open(spec, transcriptMaxBytes, transcriptMaxBackups)
 }
 
 // ASCII representation of the board
@@ -139,36 +322,64 @@ func fenToASCII(fen string) string {
 }
 
 func main() {
+	play := flag.Bool("play", false, "Play one complete game between the players and exit")
+	transcriptDir := flag.String("transcript-dir", "", "Directory for the player transcripts games ask for, which are disabled without it")
+	flag.Parse()
+
 	whitePlayerURL := os.Getenv("WHITE_PLAYER_URL")
 	blackPlayerURL := os.Getenv("BLACK_PLAYER_URL")
 
 	coordinator := NewChessCoordinator(whitePlayerURL, blackPlayerURL)
 
-	http.HandleFunc("/move", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
+	// WHITE_TRANSCRIPT and BLACK_TRANSCRIPT enable a transcript per player
+	var err error
+	if coordinator.whiteTranscript, err = openTranscript(os.Getenv("WHITE_TRANSCRIPT")); err != nil {
+		log.Fatalf("Failed to open white transcript: %v", err)
+	}
+	if coordinator.blackTranscript, err = openTranscript(os.Getenv("BLACK_TRANSCRIPT")); err != nil {
+		log.Fatalf("Failed to open black transcript: %v", err)
+	}
 
-		var req types.MoveRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
-		}
+	// TIME_CONTROL makes the game timed, e.g. "300+2"; see clock.Parse for the format
+	if err := coordinator.setTimeControl(os.Getenv("TIME_CONTROL")); err != nil {
+		log.Fatalf("Invalid time control: %v", err)
+	}
 
-		move, err := coordinator.getMoveFromPlayer(req.FEN)
+	// ILLEGAL_MOVE_POLICY is "forfeit" (default) or "retry", with up to ILLEGAL_MOVE_RETRIES retries
+	if coordinator.illegalMoveRetries, err = illegalMoveSettings(os.Getenv("ILLEGAL_MOVE_POLICY"), os.Getenv("ILLEGAL_MOVE_RETRIES")); err != nil {
+		log.Fatalf("Invalid illegal move settings: %v", err)
+	}
+
+	if *play {
+		result, err := coordinator.playGame(context.Background())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			log.Fatalf("Game aborted: %v", err)
 		}
+		fmt.Printf("%s %s {%s}\n", coordinator.movesString(), result, coordinator.termination)
+		return
+	}
 
-		if err := coordinator.makeMove(move); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+	http.HandleFunc("/move", coordinator.serveLegacyMove)
 
-		json.NewEncoder(w).Encode(types.MoveResponse{Move: move})
-	})
+	// Games created through /games run independently of the default game above,
+	// which is also registered so it can be driven through the same endpoints
+	registry := newGameRegistry()
+	registry.transcriptDir = *transcriptDir
+	coordinator.id = defaultGameID
+	registry.add(coordinator)
+	http.Handle("/games", registry)
+	http.Handle("/games/", registry)
+
+	// Tournaments create their games in the same registry
+	tournaments := newTournamentRegistry(registry)
+	http.Handle("/tournaments", tournaments)
+	http.Handle("/tournaments/", tournaments)
+	http.HandleFunc("/ratings", handleRatings)
+
+	// SPRT games go in the same registry too
+	tests := newSPRTRegistry(registry)
+	http.Handle("/sprt", tests)
+	http.Handle("/sprt/", tests)
 
 	// Add visualization endpoint
 	http.HandleFunc("/visualize", func(w http.ResponseWriter, r *http.Request) {
@@ -193,8 +404,24 @@ func main() {
 		port = "8080"
 	}
 
+	// Stop on SIGINT or SIGTERM, closing every game's transcripts on the way out
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{Addr: ":" + port}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
 	log.Printf("Starting coordinator on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+	<-stopped
+	registry.closeTranscripts()
+	log.Printf("Coordinator stopped")
 } 
\ No newline at end of file
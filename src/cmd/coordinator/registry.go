@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/transcript"
+)
+
+// defaultMaxFinishedGames is how many finished games the registry keeps
+const defaultMaxFinishedGames = 1000
+
+// defaultGameID is the ID of the game the coordinator starts with, which is never evicted
+const defaultGameID = "default"
+
+// gameRegistry holds the games run by the coordinator. Each game has its own lock, so
+// requests for different games never wait on each other. Once more than maxFinished
+// games have ended, the ones that ended first are evicted.
+type gameRegistry struct {
+	mu          sync.Mutex
+	games       map[string]*ChessCoordinator
+	finished    []string // IDs of the finished games, in the order they ended
+	maxFinished int      // Finished games kept, no limit if zero
+
+	transcriptDir string // Where games asking for player transcripts write them, disabled if empty
+}
+
+func newGameRegistry() *gameRegistry {
+	return &gameRegistry{games: make(map[string]*ChessCoordinator), maxFinished: defaultMaxFinishedGames}
+}
+
+// create starts a game as described by req and returns its ID
+func (r *gameRegistry) create(req types.CreateGameRequest) (string, error) {
+	if req.WhitePlayerURL == "" || req.BlackPlayerURL == "" {
+		return "", fmt.Errorf("both player URLs are required")
+	}
+
+	game := NewChessCoordinator(req.WhitePlayerURL, req.BlackPlayerURL)
+	if req.StartFEN != "" {
+		if err := game.reset(req.StartFEN); err != nil {
+			return "", fmt.Errorf("invalid start FEN: %v", err)
+		}
+	}
+	if err := game.setTimeControl(req.TimeControl); err != nil {
+		return "", fmt.Errorf("invalid time control: %v", err)
+	}
+
+	retries, err := illegalMoveRetries(req.IllegalMovePolicy, req.IllegalMoveRetries)
+	if err != nil {
+		return "", err
+	}
+	game.illegalMoveRetries = retries
+
+	id, err := newGameID()
+	if err != nil {
+		return "", err
+	}
+	game.id = id
+
+	if game.whiteTranscript, err = r.openTranscript(req.WhiteTranscript, id, "white"); err != nil {
+		return "", err
+	}
+	if game.blackTranscript, err = r.openTranscript(req.BlackTranscript, id, "black"); err != nil {
+		game.closeTranscripts()
+		return "", err
+	}
+
+	r.add(game)
+	return id, nil
+}
+
+// openTranscript opens the transcript of one side of game id if it was asked for
+func (r *gameRegistry) openTranscript(enabled bool, id, side string) (*transcript.Transcript, error) {
+	if !enabled {
+		return nil, nil
+	}
+	if r.transcriptDir == "" {
+		return nil, fmt.Errorf("transcripts are not enabled on this coordinator")
+	}
+
+	record, err := openTranscript(filepath.Join(r.transcriptDir, id+"-"+side+".log"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s transcript: %v", side, err)
+	}
+	return record, nil
+}
+
+// checkTranscripts returns an error if one of players asks for transcripts that
+// this registry cannot write
+func (r *gameRegistry) checkTranscripts(players ...types.TournamentPlayer) error {
+	for _, p := range players {
+		if p.Transcript && r.transcriptDir == "" {
+			return fmt.Errorf("transcripts are not enabled on this coordinator")
+		}
+	}
+	return nil
+}
+
+// closeTranscripts closes the transcripts of every game, for when the server stops
+func (r *gameRegistry) closeTranscripts() {
+	r.mu.Lock()
+	games := make([]*ChessCoordinator, 0, len(r.games))
+	for _, game := range r.games {
+		games = append(games, game)
+	}
+	r.mu.Unlock()
+
+	for _, game := range games {
+		game.mu.Lock()
+		game.closeTranscripts()
+		game.mu.Unlock()
+	}
+}
+
+// play creates a game from req and plays it to the end, calling started with the
+// game's ID as soon as it exists. A game that could not be created or finished has the
+// result "*" and the error as its termination.
+func (r *gameRegistry) play(ctx context.Context, req types.CreateGameRequest, started func(id string)) (id, result, termination string) {
+	id, err := r.create(req)
+	if err != nil {
+		return "", "*", fmt.Sprintf("failed to create game: %v", err)
+	}
+	game, _ := r.get(id)
+	started(id)
+
+	result, err = game.playGame(ctx)
+	if err != nil {
+		return id, "*", err.Error()
+	}
+
+	game.mu.Lock()
+	defer game.mu.Unlock()
+	return id, result, game.termination
+}
+
+// add registers a game under its ID. A game that has already ended counts as
+// finished at once; any other is counted when it ends.
+func (r *gameRegistry) add(game *ChessCoordinator) {
+	game.mu.Lock()
+	defer game.mu.Unlock()
+	game.onFinish = r.finish
+
+	r.mu.Lock()
+	r.games[game.id] = game
+	r.mu.Unlock()
+
+	if game.result != "*" {
+		r.finish(game.id)
+	}
+}
+
+// finish records that the game with the given ID has ended and evicts the oldest
+// finished games beyond maxFinished. The default game is never evicted. Games call it
+// with their own lock held, so it must not lock any game.
+func (r *gameRegistry) finish(id string) {
+	if id == defaultGameID {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = append(r.finished, id)
+	if r.maxFinished <= 0 {
+		return
+	}
+	for len(r.finished) > r.maxFinished {
+		delete(r.games, r.finished[0])
+		r.finished = r.finished[1:]
+	}
+}
+
+// get returns the game with the given ID
+func (r *gameRegistry) get(id string) (*ChessCoordinator, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	game, ok := r.games[id]
+	return game, ok
+}
+
+// ids returns the IDs of all games in sorted order
+func (r *gameRegistry) ids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.games))
+	for id := range r.games {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// newGameID returns a random game ID
+func newGameID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate game ID: %v", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// ServeHTTP handles /games and /games/{id}/...
+func (r *gameRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, "/games"), "/")
+	if path == "" {
+		r.serveGames(w, req)
+		return
+	}
+
+	id, action, _ := strings.Cut(path, "/")
+	game, ok := r.get(id)
+	if !ok {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	switch action {
+	case "", "position", "moves", "material", "clocks", "status", "log":
+		r.serveState(w, req, game, action)
+	case "stream":
+		r.serveStream(w, req, game)
+	case "move":
+		r.serveMove(w, req, game)
+	case "play":
+		r.servePlay(w, req, game)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// serveGames lists games on GET and creates one on POST
+func (r *gameRegistry) serveGames(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(types.GameList{Games: r.ids()})
+	case http.MethodPost:
+		var create types.CreateGameRequest
+		if err := json.NewDecoder(req.Body).Decode(&create); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		id, err := r.create(create)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(types.CreateGameResponse{ID: id})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveState returns the whole state of the game, or the part named by action
+func (r *gameRegistry) serveState(w http.ResponseWriter, req *http.Request, game *ChessCoordinator, action string) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := game.state()
+	var body interface{}
+	switch action {
+	case "position":
+		body = state.Position
+	case "moves":
+		body = state.Moves
+	case "material":
+		body = state.Material
+	case "clocks":
+		body = state.Clocks
+	case "status":
+		body = state.Status
+	case "log":
+		body = state.Log
+	default:
+		body = state
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// serveMove plays a single turn of the game
+func (r *gameRegistry) serveMove(w http.ResponseWriter, req *http.Request, game *ChessCoordinator) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	move, err := game.playTurn()
+	if err == errGameOver {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	json.NewEncoder(w).Encode(types.MoveResponse{Move: move})
+}
+
+// servePlay plays the game to the end in the background
+func (r *gameRegistry) servePlay(w http.ResponseWriter, req *http.Request, game *ChessCoordinator) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	game.mu.Lock()
+	if game.playing || game.result != "*" {
+		game.mu.Unlock()
+		http.Error(w, "Game is already being played or over", http.StatusConflict)
+		return
+	}
+	game.playing = true
+	game.mu.Unlock()
+
+	go func() {
+		game.playGame(context.Background())
+
+		game.mu.Lock()
+		game.playing = false
+		game.mu.Unlock()
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+)
+
+// createGame creates a game through the registry's HTTP API and returns its ID
+func createGame(t *testing.T, server *httptest.Server, req types.CreateGameRequest) string {
+	t.Helper()
+
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(server.URL+"/games", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var created types.CreateGameResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if created.ID == "" {
+		t.Fatal("Expected a game ID")
+	}
+	return created.ID
+}
+
+func TestCreateGame(t *testing.T) {
+	white := scriptedPlayer(t)
+	black := scriptedPlayer(t)
+
+	tests := []struct {
+		name        string
+		req         types.CreateGameRequest
+		expectError bool
+	}{
+		{
+			name: "Standard start",
+			req:  types.CreateGameRequest{WhitePlayerURL: white.URL, BlackPlayerURL: black.URL},
+		},
+		{
+			name: "Custom start and time control",
+			req: types.CreateGameRequest{
+				WhitePlayerURL: white.URL,
+				BlackPlayerURL: black.URL,
+				StartFEN:       "7k/8/8/8/8/8/8/R6K w - - 0 1",
+				TimeControl:    "40/300+2",
+			},
+		},
+		{
+			name:        "Missing player",
+			req:         types.CreateGameRequest{WhitePlayerURL: white.URL},
+			expectError: true,
+		},
+		{
+			name:        "Invalid time control",
+			req:         types.CreateGameRequest{WhitePlayerURL: white.URL, BlackPlayerURL: black.URL, TimeControl: "soon"},
+			expectError: true,
+		},
+		{
+			name:        "Invalid FEN",
+			req:         types.CreateGameRequest{WhitePlayerURL: white.URL, BlackPlayerURL: black.URL, StartFEN: "invalid"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newGameRegistry()
+			id, err := registry.create(tt.req)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			game, ok := registry.get(id)
+			if !ok {
+				t.Fatalf("Game %s not registered", id)
+			}
+			if game.clocks().TimeControl != tt.req.TimeControl {
+				t.Errorf("Expected time control %q, got %q", tt.req.TimeControl, game.clocks().TimeControl)
+			}
+			if tt.req.StartFEN != "" && game.startFEN != tt.req.StartFEN {
+				t.Errorf("Expected start FEN %q, got %q", tt.req.StartFEN, game.startFEN)
+			}
+		})
+	}
+}
+
+func TestRegistryEndpoints(t *testing.T) {
+	registry := newGameRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	white := scriptedPlayer(t, "e2e4")
+	black := scriptedPlayer(t, "e7e5")
+	id := createGame(t, server, types.CreateGameRequest{WhitePlayerURL: white.URL, BlackPlayerURL: black.URL})
+
+	resp, err := http.Get(server.URL + "/games")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var list types.GameList
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if len(list.Games) != 1 || list.Games[0] != id {
+		t.Errorf("Expected games [%s], got %v", id, list.Games)
+	}
+
+	for _, want := range []string{"e2e4", "e7e5"} {
+		resp, err := http.Post(server.URL+"/games/"+id+"/move", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var move types.MoveResponse
+		json.NewDecoder(resp.Body).Decode(&move)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || move.Move != want {
+			t.Errorf("Expected move %s with status 200, got %q with status %d", want, move.Move, resp.StatusCode)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "Unknown game", method: http.MethodPost, path: "/games/missing/move", status: http.StatusNotFound},
+		{name: "Unknown action", method: http.MethodPost, path: "/games/" + id + "/undo", status: http.StatusNotFound},
+		{name: "Wrong method", method: http.MethodGet, path: "/games/" + id + "/move", status: http.StatusMethodNotAllowed},
+		{name: "Wrong method on list", method: http.MethodDelete, path: "/games", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRegistryPlaysGamesConcurrently(t *testing.T) {
+	registry := newGameRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	// Each game ends in fool's mate; neither may see the other's moves
+	var ids []string
+	for i := 0; i < 2; i++ {
+		white := scriptedPlayer(t, "f2f3", "g2g4")
+		black := scriptedPlayer(t, "e7e5", "d8h4")
+		ids = append(ids, createGame(t, server, types.CreateGameRequest{WhitePlayerURL: white.URL, BlackPlayerURL: black.URL}))
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			resp, err := http.Post(server.URL+"/games/"+id+"/play", "application/json", nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted {
+				t.Errorf("Expected status %d, got %d", http.StatusAccepted, resp.StatusCode)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, id := range ids {
+		game, _ := registry.get(id)
+		for {
+			game.mu.Lock()
+			result, moves, playing := game.result, game.movesString(), game.playing
+			game.mu.Unlock()
+
+			if result != "*" && !playing {
+				if result != "0-1" || moves != "f2f3 e7e5 g2g4 d8h4" {
+					t.Errorf("Game %s: expected 0-1 after fool's mate, got %s after %q", id, result, moves)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Game %s did not finish", id)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	resp, err := http.Post(server.URL+"/games/"+ids[0]+"/play", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status %d for a finished game, got %d", http.StatusConflict, resp.StatusCode)
+	}
+}
+
+func TestGameTranscripts(t *testing.T) {
+	white := scriptedPlayer(t, "f2f3", "g2g4")
+	black := scriptedPlayer(t, "e7e5", "d8h4")
+	req := types.CreateGameRequest{WhitePlayerURL: white.URL, BlackPlayerURL: black.URL, WhiteTranscript: true}
+
+	// Without a transcript directory, transcripts cannot be asked for
+	if _, err := newGameRegistry().create(req); err == nil {
+		t.Error("Expected error but got none")
+	}
+
+	registry := newGameRegistry()
+	registry.transcriptDir = t.TempDir()
+	id, result, termination := registry.play(context.Background(), req, func(string) {})
+	if result != "0-1" {
+		t.Fatalf("Expected 0-1, got %s (%s)", result, termination)
+	}
+
+	// The game closed the transcript when it ended
+	game, _ := registry.get(id)
+	game.whiteTranscript.Note(id+"/white", "after the game")
+
+	data, err := os.ReadFile(filepath.Join(registry.transcriptDir, id+"-white.log"))
+	if err != nil {
+		t.Fatalf("Failed to read transcript: %v", err)
+	}
+	if !strings.Contains(string(data), "["+id+`/white] < 200 {"move":"g2g4"}`) {
+		t.Errorf("Expected white's moves in the transcript, got:\n%s", data)
+	}
+	if strings.Contains(string(data), "after the game") {
+		t.Errorf("Expected the transcript to be closed, got:\n%s", data)
+	}
+
+	if _, err := os.Stat(filepath.Join(registry.transcriptDir, id+"-black.log")); !os.IsNotExist(err) {
+		t.Errorf("Expected no black transcript, got %v", err)
+	}
+}
+
+func TestRegistryEvictsFinishedGames(t *testing.T) {
+	registry := newGameRegistry()
+	registry.maxFinished = 2
+
+	// The default game and games a to e in order; all but c are over
+	for _, id := range []string{defaultGameID, "a", "b", "c", "d", "e"} {
+		game := NewChessCoordinator("", "")
+		game.id = id
+		if id != "c" {
+			game.result = "1-0"
+		}
+		registry.add(game)
+	}
+
+	if ids := strings.Join(registry.ids(), " "); ids != "c d default e" {
+		t.Errorf("Expected the oldest finished games to be evicted, got %q", ids)
+	}
+
+	// A game in progress is kept however old it is
+	game := NewChessCoordinator("", "")
+	game.id = "f"
+	game.result = "0-1"
+	registry.add(game)
+	if ids := strings.Join(registry.ids(), " "); ids != "c default e f" {
+		t.Errorf("Expected c, default, e and f to remain, got %q", ids)
+	}
+
+	// Once it ends, it is the newest finished game
+	c, _ := registry.get("c")
+	c.mu.Lock()
+	c.finish("1/2-1/2", terminationRepetition)
+	c.mu.Unlock()
+	if ids := strings.Join(registry.ids(), " "); ids != "c default f" {
+		t.Errorf("Expected c, default and f to remain, got %q", ids)
+	}
+}
+
+// getJSON decodes the JSON body of a GET request to url into v
+func getJSON(t *testing.T, url string, v interface{}) int {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestGameStateEndpoints(t *testing.T) {
+	registry := newGameRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	white := scriptedPlayer(t, "e2e4", "e4d5")
+	black := scriptedPlayer(t, "d7d5")
+	id := createGame(t, server, types.CreateGameRequest{WhitePlayerURL: white.URL, BlackPlayerURL: black.URL, TimeControl: "300+2"})
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Post(server.URL+"/games/"+id+"/move", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	base := server.URL + "/games/" + id
+
+	var position types.GamePosition
+	getJSON(t, base+"/position", &position)
+	if position.FEN != "rnbqkbnr/ppp1pppp/8/3P4/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2" || position.SideToMove != "black" {
+		t.Errorf("Unexpected position: %+v", position)
+	}
+
+	var moves types.GameMoves
+	getJSON(t, base+"/moves", &moves)
+	if strings.Join(moves.UCI, " ") != "e2e4 d7d5 e4d5" || strings.Join(moves.SAN, " ") != "e4 d5 exd5" {
+		t.Errorf("Unexpected moves: %+v", moves)
+	}
+
+	var material types.GameMaterial
+	getJSON(t, base+"/material", &material)
+	if strings.Join(material.CapturedByWhite, "") != "p" || len(material.CapturedByBlack) != 0 || material.Balance != 1 {
+		t.Errorf("Unexpected material: %+v", material)
+	}
+
+	var clocks types.GameClocks
+	getJSON(t, base+"/clocks", &clocks)
+	if clocks.TimeControl != "300+2" {
+		t.Errorf("Expected time control 300+2, got %q", clocks.TimeControl)
+	}
+
+	var status types.GameStatus
+	getJSON(t, base+"/status", &status)
+	if status.Status != types.GameStatusInProgress || status.Result != "*" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	var state types.GameState
+	getJSON(t, base, &state)
+	if state.ID != id || state.Position != position || state.Status != status {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+
+	var ignored types.GameState
+	if code := getJSON(t, server.URL+"/games/missing/status", &ignored); code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, code)
+	}
+}
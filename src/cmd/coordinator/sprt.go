@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/board"
+	"github.com/shehio/envoy/src/internal/clock"
+	"github.com/shehio/envoy/src/internal/ratings"
+)
+
+// Bounds of an SPRT when a request gives neither, the usual test of a small gain
+const (
+	defaultSPRTElo0  = 0
+	defaultSPRTElo1  = 5
+	defaultSPRTAlpha = 0.05
+	defaultSPRTBeta  = 0.05
+
+	// defaultSPRTMaxPairs ends a test that would otherwise run forever when the
+	// players' strength lies between the bounds
+	defaultSPRTMaxPairs = 10000
+)
+
+// sprtRun is a sequential probability ratio test being played by the coordinator
+type sprtRun struct {
+	mu sync.Mutex // Guards pairs, pentanomial, record, finished and err
+
+	id          string
+	candidate   types.TournamentPlayer
+	baseline    types.TournamentPlayer
+	sprt        ratings.SPRT
+	openings    []string
+	maxPairs    int
+	concurrency int
+	settings    types.CreateGameRequest // Settings for every game, without the players and opening
+
+	pairs       []sprtPair
+	pentanomial ratings.Pentanomial
+	record      ratings.Record // The candidate's results in scored pairs
+	finished    bool
+	err         error // Why the test stopped early
+}
+
+// sprtPair is a pair of games from one opening, the candidate playing white first
+type sprtPair struct {
+	opening string
+	games   [2]types.SPRTGame
+}
+
+// newSPRT validates req and fills in its defaults
+func newSPRT(req types.CreateSPRTRequest) (*sprtRun, error) {
+	candidate, baseline := req.Candidate, req.Baseline
+	for _, p := range []*types.TournamentPlayer{&candidate, &baseline} {
+		if p.URL == "" {
+			return nil, fmt.Errorf("both player URLs are required")
+		}
+		if p.Name == "" {
+			p.Name = p.URL
+		}
+	}
+	if candidate.Name == baseline.Name {
+		return nil, fmt.Errorf("candidate and baseline share the name %q", candidate.Name)
+	}
+
+	sprt := ratings.SPRT{Elo0: req.Elo0, Elo1: req.Elo1, Alpha: req.Alpha, Beta: req.Beta}
+	if sprt.Elo0 == 0 && sprt.Elo1 == 0 {
+		sprt.Elo0, sprt.Elo1 = defaultSPRTElo0, defaultSPRTElo1
+	}
+	if sprt.Alpha == 0 {
+		sprt.Alpha = defaultSPRTAlpha
+	}
+	if sprt.Beta == 0 {
+		sprt.Beta = defaultSPRTBeta
+	}
+	if err := sprt.Validate(); err != nil {
+		return nil, err
+	}
+
+	for i, fen := range req.Openings {
+		if err := board.NewBoard().SetFEN(fen); err != nil {
+			return nil, fmt.Errorf("invalid opening %d: %v", i+1, err)
+		}
+	}
+	maxPairs := req.MaxPairs
+	if maxPairs < 0 {
+		return nil, fmt.Errorf("max pairs cannot be negative, got %d", maxPairs)
+	}
+	if maxPairs == 0 {
+		maxPairs = defaultSPRTMaxPairs
+	}
+	if req.TimeControl != "" {
+		if _, err := clock.Parse(req.TimeControl); err != nil {
+			return nil, fmt.Errorf("invalid time control: %v", err)
+		}
+	}
+	if _, err := illegalMoveRetries(req.IllegalMovePolicy, req.IllegalMoveRetries); err != nil {
+		return nil, err
+	}
+
+	t := &sprtRun{
+		candidate:   candidate,
+		baseline:    baseline,
+		sprt:        sprt,
+		openings:    req.Openings,
+		maxPairs:    maxPairs,
+		concurrency: req.Concurrency,
+		settings: types.CreateGameRequest{
+			TimeControl:        req.TimeControl,
+			IllegalMovePolicy:  req.IllegalMovePolicy,
+			IllegalMoveRetries: req.IllegalMoveRetries,
+		},
+	}
+	if t.concurrency < 1 {
+		t.concurrency = 1
+	}
+	return t, nil
+}
+
+// run plays pairs, at most t.concurrency at a time, until the test is decided, creating
+// each game in games so it can be followed like any other game. Pairs still being
+// played when the LLR crosses a bound are finished and counted, and play resumes if
+// they bring it back between the bounds.
+func (t *sprtRun) run(ctx context.Context, games *gameRegistry) {
+	var wg sync.WaitGroup
+	for w := 0; w < t.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, ok := t.next()
+				if !ok {
+					return
+				}
+				t.playPair(ctx, games, i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	t.mu.Lock()
+	t.finished = true
+	t.mu.Unlock()
+
+	state := t.state()
+	log.Printf("SPRT %s finished: %s after %d pairs (LLR %.2f)", t.id, state.Result, state.Pairs, state.LLR)
+}
+
+// next adds a pair to play and returns its index, or false when the test is over
+func (t *sprtRun) next() (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil || t.sprt.Decide(t.pentanomial) != "" {
+		return 0, false
+	}
+	if len(t.pairs) >= t.maxPairs {
+		return 0, false
+	}
+
+	pair := sprtPair{games: [2]types.SPRTGame{{Result: "*"}, {Result: "*"}}}
+	if len(t.openings) > 0 {
+		pair.opening = t.openings[len(t.pairs)%len(t.openings)]
+	}
+	t.pairs = append(t.pairs, pair)
+	return len(t.pairs) - 1, true
+}
+
+// playPair plays both games of the i-th pair and scores it. A game that fails stops
+// the test, since the players or the settings are likely at fault.
+func (t *sprtRun) playPair(ctx context.Context, games *gameRegistry, i int) {
+	t.mu.Lock()
+	opening := t.pairs[i].opening
+	t.mu.Unlock()
+
+	var scores [2]float64
+	for g := range scores {
+		req := t.settings
+		req.StartFEN = opening
+		req.WhitePlayerURL, req.BlackPlayerURL = t.candidate.URL, t.baseline.URL
+		req.WhiteTranscript, req.BlackTranscript = t.candidate.Transcript, t.baseline.Transcript
+		if g == 1 {
+			req.WhitePlayerURL, req.BlackPlayerURL = t.baseline.URL, t.candidate.URL
+			req.WhiteTranscript, req.BlackTranscript = t.baseline.Transcript, t.candidate.Transcript
+		}
+
+		id, result, termination := games.play(ctx, req, func(id string) {
+			t.mu.Lock()
+			t.pairs[i].games[g].GameID = id
+			t.mu.Unlock()
+		})
+
+		t.mu.Lock()
+		t.pairs[i].games[g] = types.SPRTGame{GameID: id, Result: result, Termination: termination}
+		t.mu.Unlock()
+
+		if result == "*" {
+			t.mu.Lock()
+			if t.err == nil {
+				t.err = fmt.Errorf("game %d of pair %d failed: %s", g+1, i+1, termination)
+				log.Printf("SPRT %s stopped: %v", t.id, t.err)
+			}
+			t.mu.Unlock()
+			return
+		}
+		scores[g] = candidateScore(result, g == 0)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pentanomial.Add(scores[0], scores[1])
+	for _, score := range scores {
+		switch score {
+		case 1:
+			t.record.Wins++
+		case 0.5:
+			t.record.Draws++
+		default:
+			t.record.Losses++
+		}
+	}
+}
+
+// candidateScore returns the points the candidate scored in a decided game
+func candidateScore(result string, white bool) float64 {
+	score := 0.5
+	switch result {
+	case "1-0":
+		score = 1
+	case "0-1":
+		score = 0
+	}
+	if !white {
+		score = 1 - score
+	}
+	return score
+}
+
+// state returns the test's progress and statistics
+func (t *sprtRun) state() types.SPRTState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := types.SPRTState{
+		ID:          t.id,
+		Status:      types.GameStatusInProgress,
+		Candidate:   t.candidate.Name,
+		Baseline:    t.baseline.Name,
+		Elo0:        t.sprt.Elo0,
+		Elo1:        t.sprt.Elo1,
+		Alpha:       t.sprt.Alpha,
+		Beta:        t.sprt.Beta,
+		LLR:         t.sprt.LLR(t.pentanomial),
+		Pairs:       t.pentanomial.Pairs(),
+		Wins:        t.record.Wins,
+		Draws:       t.record.Draws,
+		Losses:      t.record.Losses,
+		Pentanomial: t.pentanomial,
+		Games:       []types.SPRTPair{},
+	}
+	state.LowerBound, state.UpperBound = t.sprt.Bounds()
+	if t.err != nil {
+		state.Error = t.err.Error()
+	}
+
+	if t.finished {
+		state.Status = types.GameStatusFinished
+		switch t.sprt.Decide(t.pentanomial) {
+		case ratings.AcceptH0:
+			state.Result = types.SPRTAcceptH0
+		case ratings.AcceptH1:
+			state.Result = types.SPRTAcceptH1
+		default:
+			if t.err == nil {
+				state.Result = types.SPRTInconclusive
+			}
+		}
+	}
+
+	// An unbounded estimate, from a clean sweep, is left at 0 with the LOS showing
+	// which way it went
+	if state.Pairs > 0 {
+		d, _ := ratings.PentanomialElo(t.pentanomial)
+		state.Elo, state.EloError, state.LOS = d.Elo, d.Error, d.LOS
+	}
+
+	for _, p := range t.pairs {
+		state.Games = append(state.Games, types.SPRTPair{Opening: p.opening, Games: p.games})
+	}
+	return state
+}
+
+// sprtRegistry holds the tests run by the coordinator. Their games are created in the
+// game registry.
+type sprtRegistry struct {
+	mu    sync.Mutex
+	tests map[string]*sprtRun
+	games *gameRegistry
+}
+
+func newSPRTRegistry(games *gameRegistry) *sprtRegistry {
+	return &sprtRegistry{tests: make(map[string]*sprtRun), games: games}
+}
+
+// create starts the test described by req in the background and returns its ID
+func (r *sprtRegistry) create(req types.CreateSPRTRequest) (string, error) {
+	t, err := newSPRT(req)
+	if err != nil {
+		return "", err
+	}
+	if err := r.games.checkTranscripts(t.candidate, t.baseline); err != nil {
+		return "", err
+	}
+
+	id, err := newGameID()
+	if err != nil {
+		return "", err
+	}
+	t.id = id
+
+	r.mu.Lock()
+	r.tests[id] = t
+	r.mu.Unlock()
+
+	go t.run(context.Background(), r.games)
+	return id, nil
+}
+
+// get returns the test with the given ID
+func (r *sprtRegistry) get(id string) (*sprtRun, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tests[id]
+	return t, ok
+}
+
+// ids returns the IDs of all tests in sorted order
+func (r *sprtRegistry) ids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.tests))
+	for id := range r.tests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ServeHTTP handles /sprt and /sprt/{id}
+func (r *sprtRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/sprt"), "/")
+	if id == "" {
+		r.serveTests(w, req)
+		return
+	}
+	if strings.Contains(id, "/") {
+		http.NotFound(w, req)
+		return
+	}
+
+	t, ok := r.get(id)
+	if !ok {
+		http.Error(w, "Test not found", http.StatusNotFound)
+		return
+	}
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.state())
+}
+
+// serveTests lists tests on GET and starts one on POST
+func (r *sprtRegistry) serveTests(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(types.SPRTList{Tests: r.ids()})
+	case http.MethodPost:
+		var create types.CreateSPRTRequest
+		if err := json.NewDecoder(req.Body).Decode(&create); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		id, err := r.create(create)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(types.CreateSPRTResponse{ID: id})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+)
+
+func TestNewSPRT(t *testing.T) {
+	candidate := types.TournamentPlayer{Name: "new", URL: "http://new"}
+	baseline := types.TournamentPlayer{Name: "old", URL: "http://old"}
+
+	tests := []struct {
+		name        string
+		req         types.CreateSPRTRequest
+		elo0, elo1  float64
+		expectError bool
+	}{
+		{name: "Defaults", req: types.CreateSPRTRequest{Candidate: candidate, Baseline: baseline}, elo0: 0, elo1: 5},
+		{name: "Non-regression bounds", req: types.CreateSPRTRequest{Candidate: candidate, Baseline: baseline, Elo0: -5, Elo1: 0}, elo0: -5, elo1: 0},
+		{name: "Names from URLs", req: types.CreateSPRTRequest{Candidate: types.TournamentPlayer{URL: "http://a"}, Baseline: types.TournamentPlayer{URL: "http://b"}}, elo0: 0, elo1: 5},
+		{name: "Missing URL", req: types.CreateSPRTRequest{Candidate: candidate}, expectError: true},
+		{name: "Same name", req: types.CreateSPRTRequest{Candidate: candidate, Baseline: types.TournamentPlayer{Name: "new", URL: "http://old"}}, expectError: true},
+		{name: "Reversed bounds", req: types.CreateSPRTRequest{Candidate: candidate, Baseline: baseline, Elo0: 5, Elo1: 1}, expectError: true},
+		{name: "Invalid alpha", req: types.CreateSPRTRequest{Candidate: candidate, Baseline: baseline, Alpha: 0.7}, expectError: true},
+		{name: "Invalid opening", req: types.CreateSPRTRequest{Candidate: candidate, Baseline: baseline, Openings: []string{"not a fen"}}, expectError: true},
+		{name: "Negative max pairs", req: types.CreateSPRTRequest{Candidate: candidate, Baseline: baseline, MaxPairs: -1}, expectError: true},
+		{name: "Invalid time control", req: types.CreateSPRTRequest{Candidate: candidate, Baseline: baseline, TimeControl: "fast"}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run, err := newSPRT(test.req)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if run.sprt.Elo0 != test.elo0 || run.sprt.Elo1 != test.elo1 {
+				t.Errorf("Expected bounds [%g, %g], got [%g, %g]", test.elo0, test.elo1, run.sprt.Elo0, run.sprt.Elo1)
+			}
+			if run.sprt.Alpha != defaultSPRTAlpha || run.sprt.Beta != defaultSPRTBeta {
+				t.Errorf("Expected default alpha and beta, got %g and %g", run.sprt.Alpha, run.sprt.Beta)
+			}
+			if run.maxPairs != defaultSPRTMaxPairs {
+				t.Errorf("Expected %d max pairs by default, got %d", defaultSPRTMaxPairs, run.maxPairs)
+			}
+			if run.candidate.Name == "" || run.baseline.Name == "" {
+				t.Errorf("Expected both players named, got %+v and %+v", run.candidate, run.baseline)
+			}
+		})
+	}
+}
+
+// waitForSPRT polls the test at url until it finishes
+func waitForSPRT(t *testing.T, url string) types.SPRTState {
+	t.Helper()
+
+	var state types.SPRTState
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		getJSON(t, url, &state)
+		if state.Status == types.GameStatusFinished {
+			return state
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Test did not finish: %+v", state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	games := newGameRegistry()
+	server := httptest.NewServer(newSPRTRegistry(games))
+	defer server.Close()
+
+	// A broken player loses every game on its first turn
+	good := types.TournamentPlayer{Name: "good", URL: scriptedPlayer(t).URL}
+	broken := types.TournamentPlayer{Name: "broken", URL: brokenPlayer(t).URL}
+	openings := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq - 0 1",
+	}
+
+	tests := []struct {
+		name        string
+		req         types.CreateSPRTRequest
+		result      string
+		pentanomial [5]int
+		wins        int
+		losses      int
+	}{
+		{
+			name:        "Stronger candidate",
+			req:         types.CreateSPRTRequest{Candidate: good, Baseline: broken, Openings: openings},
+			result:      types.SPRTAcceptH1,
+			pentanomial: [5]int{0, 0, 0, 0, 2},
+			wins:        4,
+		},
+		{
+			name:        "Weaker candidate",
+			req:         types.CreateSPRTRequest{Candidate: broken, Baseline: good, Openings: openings},
+			result:      types.SPRTAcceptH0,
+			pentanomial: [5]int{2, 0, 0, 0, 0},
+			losses:      4,
+		},
+		{
+			name:        "Pair limit",
+			req:         types.CreateSPRTRequest{Candidate: good, Baseline: broken, Alpha: 1e-6, Beta: 1e-6, MaxPairs: 2},
+			result:      types.SPRTInconclusive,
+			pentanomial: [5]int{0, 0, 0, 0, 2},
+			wins:        4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, _ := json.Marshal(test.req)
+			resp, err := http.Post(server.URL+"/sprt", "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var created types.CreateSPRTResponse
+			json.NewDecoder(resp.Body).Decode(&created)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+			}
+
+			state := waitForSPRT(t, server.URL+"/sprt/"+created.ID)
+			if state.Error != "" {
+				t.Fatalf("Unexpected error: %s", state.Error)
+			}
+			if state.Result != test.result {
+				t.Errorf("Expected result %q, got %q (LLR %.2f)", test.result, state.Result, state.LLR)
+			}
+			if state.Pentanomial != test.pentanomial {
+				t.Errorf("Expected pentanomial %v, got %v", test.pentanomial, state.Pentanomial)
+			}
+			if state.Wins != test.wins || state.Draws != 0 || state.Losses != test.losses {
+				t.Errorf("Expected %d-0-%d, got %d-%d-%d", test.wins, test.losses, state.Wins, state.Draws, state.Losses)
+			}
+
+			if len(state.Games) != state.Pairs {
+				t.Fatalf("Expected %d pairs of games, got %d", state.Pairs, len(state.Games))
+			}
+			for i, pair := range state.Games {
+				if test.req.Openings != nil && pair.Opening != openings[i%2] {
+					t.Errorf("Pair %d: expected opening %q, got %q", i+1, openings[i%2], pair.Opening)
+				}
+
+				// The candidate has white in the first game and black in the second
+				for g, game := range pair.Games {
+					played, ok := games.get(game.GameID)
+					if !ok {
+						t.Fatalf("Game %s is not in the game registry", game.GameID)
+					}
+					white := test.req.Candidate.URL
+					if g == 1 {
+						white = test.req.Baseline.URL
+					}
+					if played.whitePlayerURL != white {
+						t.Errorf("Pair %d game %d: expected white %s, got %s", i+1, g+1, white, played.whitePlayerURL)
+					}
+				}
+			}
+		})
+	}
+
+	var list types.SPRTList
+	getJSON(t, server.URL+"/sprt", &list)
+	if len(list.Tests) != len(tests) {
+		t.Errorf("Expected %d tests, got %v", len(tests), list.Tests)
+	}
+	var state types.SPRTState
+	if code := getJSON(t, server.URL+"/sprt/missing", &state); code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, code)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/board"
+)
+
+// state returns a snapshot of the game for the state endpoints
+func (c *ChessCoordinator) state() types.GameState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.snapshot()
+}
+
+// snapshot returns the state of the game; the caller must hold c.mu
+func (c *ChessCoordinator) snapshot() types.GameState {
+	side := "white"
+	if !c.board.IsWhiteToMove() {
+		side = "black"
+	}
+
+	status := types.GameStatusInProgress
+	if c.result != "*" {
+		status = types.GameStatusFinished
+	}
+
+	return types.GameState{
+		ID: c.id,
+		Position: types.GamePosition{
+			FEN:        c.board.GetFEN(),
+			SideToMove: side,
+		},
+		Moves: types.GameMoves{
+			StartFEN: c.startFEN,
+			UCI:      append([]string{}, c.moves...),
+			SAN:      append([]string{}, c.san...),
+		},
+		Material: types.GameMaterial{
+			// White captures black's pieces and the other way round
+			CapturedByWhite: pieceLetters(c.board.Captured(false)),
+			CapturedByBlack: pieceLetters(c.board.Captured(true)),
+			Balance:         c.board.MaterialBalance(),
+		},
+		Clocks: c.clocks(),
+		Status: types.GameStatus{
+			Status:      status,
+			Result:      c.result,
+			Termination: c.termination,
+		},
+		Log: append([]types.GameEvent{}, c.events...),
+	}
+}
+
+// clocks returns the players' remaining time, counting the time the side to move has
+// been thinking; the caller must hold c.mu
+func (c *ChessCoordinator) clocks() types.GameClocks {
+	if c.clock == nil {
+		return types.GameClocks{}
+	}
+
+	var elapsed time.Duration
+	var running string
+	if !c.turnStarted.IsZero() {
+		elapsed = time.Since(c.turnStarted)
+		running = "white"
+		if !c.board.IsWhiteToMove() {
+			running = "black"
+		}
+	}
+
+	white, black := c.remaining(c.board.IsWhiteToMove(), elapsed)
+	return types.GameClocks{
+		TimeControl: c.clock.TimeControl().String(),
+		WhiteMillis: white.Milliseconds(),
+		BlackMillis: black.Milliseconds(),
+		Running:     running,
+	}
+}
+
+// pieceLetters returns the lowercase letters of the pieces
+func pieceLetters(pieces []board.Piece) []string {
+	letters := []string{}
+	for _, piece := range pieces {
+		letters = append(letters, strings.ToLower(piece.String()))
+	}
+	return letters
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+)
+
+// Names of the server-sent events streamed for a game
+const (
+	streamEventState = "state" // types.GameState, sent first
+	streamEventMove  = "move"  // types.GameMoveUpdate
+	streamEventClock = "clock" // types.GameClocks, sent while a clock runs
+	streamEventEnd   = "end"   // types.GameStatus, sent last
+)
+
+const (
+	// streamBuffer is how many events a spectator may fall behind before it is dropped
+	streamBuffer = 64
+	// streamClockInterval is how often running clocks are streamed
+	streamClockInterval = time.Second
+)
+
+// streamEvent is an event sent to the spectators of a game
+type streamEvent struct {
+	name string
+	data interface{}
+}
+
+// subscribe returns the current state of the game and a channel receiving its
+// events from then on. The channel is closed when unsubscribe is called or the
+// spectator falls too far behind.
+func (c *ChessCoordinator) subscribe() (types.GameState, <-chan streamEvent, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	events := make(chan streamEvent, streamBuffer)
+	if c.subscribers == nil {
+		c.subscribers = make(map[chan streamEvent]struct{})
+	}
+	c.subscribers[events] = struct{}{}
+
+	unsubscribe := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subscribers[events]; ok {
+			delete(c.subscribers, events)
+			close(events)
+		}
+	}
+	return c.snapshot(), events, unsubscribe
+}
+
+// publish sends an event to every spectator; the caller must hold c.mu. Spectators
+// whose buffer is full are dropped rather than holding up the game.
+func (c *ChessCoordinator) publish(name string, data interface{}) {
+	for events := range c.subscribers {
+		select {
+		case events <- streamEvent{name: name, data: data}:
+		default:
+			delete(c.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// publishMove sends the last move played to every spectator; the caller must hold c.mu
+func (c *ChessCoordinator) publishMove(eval *types.Evaluation) {
+	ply := len(c.moves)
+	if ply == 0 {
+		return
+	}
+
+	// The side that moved is the one no longer to move
+	side := "black"
+	if !c.board.IsWhiteToMove() {
+		side = "white"
+	}
+
+	c.publish(streamEventMove, types.GameMoveUpdate{
+		Ply:    ply,
+		Side:   side,
+		UCI:    c.moves[ply-1],
+		SAN:    c.san[ply-1],
+		FEN:    c.board.GetFEN(),
+		Clocks: c.clocks(),
+		Eval:   eval,
+	})
+}
+
+// serveStream streams the game's events to a spectator as server-sent events until
+// the game ends or the spectator disconnects
+func (r *gameRegistry) serveStream(w http.ResponseWriter, req *http.Request, game *ChessCoordinator) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	state, events, unsubscribe := game.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(name string, data interface{}) bool {
+		if err := writeEvent(w, name, data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if !send(streamEventState, state) || state.Status.Status == types.GameStatusFinished {
+		return
+	}
+
+	ticker := time.NewTicker(streamClockInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok || !send(event.name, event.data) || event.name == streamEventEnd {
+				return
+			}
+		case <-ticker.C:
+			if clocks := game.state().Clocks; clocks.Running != "" {
+				if !send(streamEventClock, clocks) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeEvent writes a server-sent event with data encoded as JSON
+func writeEvent(w http.ResponseWriter, name string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", name, err)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+	return err
+}
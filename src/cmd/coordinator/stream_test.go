@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+)
+
+// sseEvent is a server-sent event read by a test
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents reads server-sent events from the stream until it ends
+func readEvents(t *testing.T, scanner *bufio.Scanner) []sseEvent {
+	t.Helper()
+
+	var events []sseEvent
+	var event sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, event)
+			event = sseEvent{}
+		}
+	}
+	return events
+}
+
+func TestStream(t *testing.T) {
+	registry := newGameRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	// White reports an evaluation with each move
+	script := []string{"f2f3", "g2g4"}
+	white := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		move := script[0]
+		script = script[1:]
+		json.NewEncoder(w).Encode(types.MoveResponse{Move: move, Eval: &types.Evaluation{Centipawns: -50, Depth: 10}})
+	}))
+	defer white.Close()
+	black := scriptedPlayer(t, "e7e5", "d8h4")
+
+	id := createGame(t, server, types.CreateGameRequest{WhitePlayerURL: white.URL, BlackPlayerURL: black.URL, TimeControl: "60+1"})
+
+	resp, err := http.Get(server.URL + "/games/" + id + "/stream")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", contentType)
+	}
+
+	// The first event is the state, sent when subscribing, so the game starts after it
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && scanner.Text() != "" {
+	}
+	play, err := http.Post(server.URL+"/games/"+id+"/play", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	play.Body.Close()
+
+	events := readEvents(t, scanner)
+
+	var names []string
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	if got := strings.Join(names, " "); got != "move move move move end" {
+		t.Fatalf("Expected four moves and the end, got %q", got)
+	}
+
+	var first types.GameMoveUpdate
+	if err := json.Unmarshal([]byte(events[0].data), &first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.Ply != 1 || first.Side != "white" || first.UCI != "f2f3" || first.SAN != "f3" ||
+		first.FEN != "rnbqkbnr/pppppppp/8/8/8/5P2/PPPPP1PP/RNBQKBNR b KQkq - 0 1" {
+		t.Errorf("Unexpected first move: %+v", first)
+	}
+	if first.Eval == nil || first.Eval.Centipawns != -50 || first.Eval.Depth != 10 {
+		t.Errorf("Expected white's evaluation, got %+v", first.Eval)
+	}
+	if first.Clocks.TimeControl != "60+1" || first.Clocks.WhiteMillis <= 60000 {
+		t.Errorf("Expected white's clock after the increment, got %+v", first.Clocks)
+	}
+
+	var last types.GameMoveUpdate
+	json.Unmarshal([]byte(events[3].data), &last)
+	if last.SAN != "Qh4#" || last.Eval != nil {
+		t.Errorf("Unexpected last move: %+v", last)
+	}
+
+	var status types.GameStatus
+	json.Unmarshal([]byte(events[4].data), &status)
+	if status.Status != types.GameStatusFinished || status.Result != "0-1" {
+		t.Errorf("Unexpected end: %+v", status)
+	}
+}
+
+func TestStreamFinishedGame(t *testing.T) {
+	registry := newGameRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	white := scriptedPlayer(t)
+	id := createGame(t, server, types.CreateGameRequest{
+		WhitePlayerURL: white.URL,
+		BlackPlayerURL: white.URL,
+		StartFEN:       "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+	})
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(server.URL + "/games/" + id + "/stream")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	events := readEvents(t, bufio.NewScanner(resp.Body))
+	if len(events) != 1 || events[0].name != streamEventState {
+		t.Fatalf("Expected only the state of a finished game, got %v", events)
+	}
+
+	var state types.GameState
+	json.Unmarshal([]byte(events[0].data), &state)
+	if state.Status.Termination != "stalemate" {
+		t.Errorf("Expected stalemate, got %+v", state.Status)
+	}
+}
+
+func TestSlowSpectatorIsDropped(t *testing.T) {
+	coordinator := NewChessCoordinator("", "")
+	_, events, unsubscribe := coordinator.subscribe()
+	defer unsubscribe()
+
+	coordinator.mu.Lock()
+	for i := 0; i <= streamBuffer; i++ {
+		coordinator.publish(streamEventClock, types.GameClocks{})
+	}
+	coordinator.mu.Unlock()
+
+	count := 0
+	for range events {
+		count++
+	}
+	if count != streamBuffer {
+		t.Errorf("Expected %d buffered events before the spectator was dropped, got %d", streamBuffer, count)
+	}
+}
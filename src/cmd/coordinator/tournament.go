@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/board"
+	"github.com/shehio/envoy/src/internal/clock"
+	"github.com/shehio/envoy/src/internal/ratings"
+	"github.com/shehio/envoy/src/internal/tournament"
+)
+
+// defaultGauntletRounds is how many games the challenger of a gauntlet plays against
+// each opponent when no count is given
+const defaultGauntletRounds = 2
+
+// tournamentRun is a tournament being played by the coordinator
+type tournamentRun struct {
+	mu sync.Mutex // Guards games and finished
+
+	id          string
+	format      string
+	players     []types.TournamentPlayer
+	rounds      int // Rounds of a Swiss tournament
+	concurrency int
+	settings    types.CreateGameRequest // Settings for every game, without the players
+
+	games    []tournamentGame
+	finished bool
+	err      error // Why the tournament stopped early
+}
+
+// tournamentGame is a pairing of a tournament and the game played for it
+type tournamentGame struct {
+	tournament.Game
+	gameID      string
+	termination string
+}
+
+// newTournament validates req and pairs its players
+func newTournament(req types.CreateTournamentRequest) (*tournamentRun, error) {
+	players := append([]types.TournamentPlayer(nil), req.Players...)
+	names := map[string]bool{}
+	for i := range players {
+		if players[i].URL == "" {
+			return nil, fmt.Errorf("player %d has no URL", i+1)
+		}
+		if players[i].Name == "" {
+			players[i].Name = players[i].URL
+		}
+		if names[players[i].Name] {
+			return nil, fmt.Errorf("duplicate player name %q", players[i].Name)
+		}
+		names[players[i].Name] = true
+	}
+
+	if req.StartFEN != "" {
+		if err := board.NewBoard().SetFEN(req.StartFEN); err != nil {
+			return nil, fmt.Errorf("invalid start FEN: %v", err)
+		}
+	}
+	if req.TimeControl != "" {
+		if _, err := clock.Parse(req.TimeControl); err != nil {
+			return nil, fmt.Errorf("invalid time control: %v", err)
+		}
+	}
+	if _, err := illegalMoveRetries(req.IllegalMovePolicy, req.IllegalMoveRetries); err != nil {
+		return nil, err
+	}
+
+	rounds := req.Rounds
+	var pairings []tournament.Pairing
+	if req.Format == tournament.Swiss {
+		if len(players) < 2 {
+			return nil, fmt.Errorf("a tournament needs at least 2 players, got %d", len(players))
+		}
+		if rounds == 0 {
+			rounds = tournament.SwissRounds(len(players))
+		}
+
+		// Beyond this some player would run out of new opponents
+		maxRounds := len(players) - 1
+		if len(players)%2 == 1 {
+			maxRounds = len(players)
+		}
+		if rounds < 1 || rounds > maxRounds {
+			return nil, fmt.Errorf("a Swiss tournament of %d players needs between 1 and %d rounds, got %d", len(players), maxRounds, rounds)
+		}
+	} else {
+		if rounds == 0 {
+			rounds = defaultGauntletRounds
+		}
+		var err error
+		if pairings, err = tournament.Pairings(req.Format, len(players), rounds); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &tournamentRun{
+		format:      req.Format,
+		players:     players,
+		rounds:      rounds,
+		concurrency: req.Concurrency,
+		settings: types.CreateGameRequest{
+			StartFEN:           req.StartFEN,
+			TimeControl:        req.TimeControl,
+			IllegalMovePolicy:  req.IllegalMovePolicy,
+			IllegalMoveRetries: req.IllegalMoveRetries,
+		},
+	}
+	if t.concurrency < 1 {
+		t.concurrency = 1
+	}
+	for _, p := range pairings {
+		t.games = append(t.games, tournamentGame{Game: tournament.Game{Pairing: p}})
+	}
+	return t, nil
+}
+
+// run plays every game of the tournament, at most t.concurrency at a time, creating
+// each in games so it can be followed like any other game. Swiss rounds are paired
+// once the previous round has finished.
+func (t *tournamentRun) run(ctx context.Context, games *gameRegistry) {
+	if t.format == tournament.Swiss {
+		for round := 1; round <= t.rounds; round++ {
+			first, err := t.pairSwissRound(round)
+			if err != nil {
+				t.mu.Lock()
+				t.err = err
+				t.mu.Unlock()
+				log.Printf("Tournament %s stopped: %v", t.id, err)
+				break
+			}
+			t.playGames(ctx, games, first)
+		}
+	} else {
+		t.playGames(ctx, games, 0)
+	}
+
+	t.mu.Lock()
+	t.finished = true
+	t.mu.Unlock()
+	log.Printf("Tournament %s finished", t.id)
+}
+
+// pairSwissRound adds the games of a Swiss round and returns the index of the first.
+// A bye is scored at once.
+func (t *tournamentRun) pairSwissRound(round int) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	results := make([]tournament.Game, len(t.games))
+	for i, g := range t.games {
+		results[i] = g.Game
+	}
+	pairings, err := tournament.SwissRound(len(t.players), round, t.rounds, results)
+	if err != nil {
+		return 0, err
+	}
+
+	first := len(t.games)
+	for _, p := range pairings {
+		g := tournamentGame{Game: tournament.Game{Pairing: p}}
+		if p.Black == tournament.Bye {
+			g.Result = tournament.WhiteWins
+			g.termination = "bye"
+		}
+		t.games = append(t.games, g)
+	}
+	return first, nil
+}
+
+// playGames plays the games from index first on that have no result yet
+func (t *tournamentRun) playGames(ctx context.Context, games *gameRegistry, first int) {
+	t.mu.Lock()
+	last := len(t.games)
+	t.mu.Unlock()
+
+	sem := make(chan struct{}, t.concurrency)
+	var wg sync.WaitGroup
+	for i := first; i < last; i++ {
+		t.mu.Lock()
+		decided := t.games[i].Result != ""
+		t.mu.Unlock()
+		if decided {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			t.play(ctx, games, i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// play plays the tournament's i-th game and records its result
+func (t *tournamentRun) play(ctx context.Context, games *gameRegistry, i int) {
+	t.mu.Lock()
+	pairing := t.games[i].Pairing
+	t.mu.Unlock()
+
+	req := t.settings
+	req.WhitePlayerURL = t.players[pairing.White].URL
+	req.BlackPlayerURL = t.players[pairing.Black].URL
+	req.WhiteTranscript = t.players[pairing.White].Transcript
+	req.BlackTranscript = t.players[pairing.Black].Transcript
+
+	id, result, termination := games.play(ctx, req, func(id string) {
+		t.mu.Lock()
+		t.games[i].gameID = id
+		t.mu.Unlock()
+	})
+	t.record(i, id, result, termination)
+}
+
+// record stores the outcome of the tournament's i-th game
+func (t *tournamentRun) record(i int, gameID, result, termination string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.games[i].gameID = gameID
+	t.games[i].Result = result
+	t.games[i].termination = termination
+}
+
+// state returns the tournament's progress, standings and crosstable
+func (t *tournamentRun) state() types.TournamentState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := types.TournamentState{
+		ID:     t.id,
+		Format: t.format,
+		Status: types.GameStatusInProgress,
+	}
+	if t.finished {
+		state.Status = types.GameStatusFinished
+	}
+	if t.err != nil {
+		state.Error = t.err.Error()
+	}
+
+	for _, p := range t.players {
+		state.Players = append(state.Players, p.Name)
+	}
+
+	results := make([]tournament.Game, len(t.games))
+	for i, g := range t.games {
+		results[i] = g.Game
+		game := types.TournamentGame{
+			Round:       g.Round,
+			White:       t.players[g.White].Name,
+			GameID:      g.gameID,
+			Result:      gameResult(g.Result),
+			Termination: g.termination,
+		}
+		if g.Black == tournament.Bye {
+			game.Bye = true
+		} else {
+			game.Black = t.players[g.Black].Name
+		}
+		state.Games = append(state.Games, game)
+	}
+
+	standings := tournament.Standings(len(t.players), results)
+	for i, s := range standings {
+		rank := i + 1
+		if i > 0 && s.Tied(standings[i-1]) {
+			rank = state.Standings[i-1].Rank
+		}
+		state.Standings = append(state.Standings, types.TournamentStanding{
+			Rank:            rank,
+			Player:          t.players[s.Player].Name,
+			Points:          s.Points,
+			Games:           s.Games,
+			Wins:            s.Wins,
+			Draws:           s.Draws,
+			Losses:          s.Losses,
+			Byes:            s.Byes,
+			Buchholz:        s.Buchholz,
+			SonnebornBerger: s.SonnebornBerger,
+		})
+	}
+
+	// Rows and columns of the crosstable follow the standings
+	table := tournament.Crosstable(len(t.players), results)
+	for _, row := range standings {
+		cells := make([]string, 0, len(standings))
+		for _, column := range standings {
+			cells = append(cells, table[row.Player][column.Player])
+		}
+		state.Crosstable = append(state.Crosstable, types.CrosstableRow{
+			Player:  t.players[row.Player].Name,
+			Points:  row.Points,
+			Results: cells,
+		})
+	}
+	return state
+}
+
+// gameResult returns the result to report for a game, "*" until it is decided
+func gameResult(result string) string {
+	if result == "" {
+		return "*"
+	}
+	return result
+}
+
+// tournamentRegistry holds the tournaments run by the coordinator. Their games are
+// created in the game registry.
+type tournamentRegistry struct {
+	mu          sync.Mutex
+	tournaments map[string]*tournamentRun
+	games       *gameRegistry
+}
+
+func newTournamentRegistry(games *gameRegistry) *tournamentRegistry {
+	return &tournamentRegistry{tournaments: make(map[string]*tournamentRun), games: games}
+}
+
+// create starts the tournament described by req in the background and returns its ID
+func (r *tournamentRegistry) create(req types.CreateTournamentRequest) (string, error) {
+	t, err := newTournament(req)
+	if err != nil {
+		return "", err
+	}
+	if err := r.games.checkTranscripts(t.players...); err != nil {
+		return "", err
+	}
+
+	id, err := newGameID()
+	if err != nil {
+		return "", err
+	}
+	t.id = id
+
+	r.mu.Lock()
+	r.tournaments[id] = t
+	r.mu.Unlock()
+
+	go t.run(context.Background(), r.games)
+	return id, nil
+}
+
+// get returns the tournament with the given ID
+func (r *tournamentRegistry) get(id string) (*tournamentRun, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tournaments[id]
+	return t, ok
+}
+
+// ids returns the IDs of all tournaments in sorted order
+func (r *tournamentRegistry) ids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.tournaments))
+	for id := range r.tournaments {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ServeHTTP handles /tournaments, /tournaments/{id} and /tournaments/{id}/ratings
+func (r *tournamentRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, "/tournaments"), "/")
+	if path == "" {
+		r.serveTournaments(w, req)
+		return
+	}
+
+	id, part, _ := strings.Cut(path, "/")
+	if part != "" && part != "ratings" {
+		http.NotFound(w, req)
+		return
+	}
+
+	t, ok := r.get(id)
+	if !ok {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := t.state()
+	if part == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+		return
+	}
+
+	// Ratings cover the games decided so far, anchored as the query asks
+	query := req.URL.Query()
+	anchorElo, err := queryFloat(query, "anchor_elo")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ratingsReq := types.RatingsRequest{
+		Games:     state.Games,
+		Anchor:    query.Get("anchor"),
+		AnchorElo: anchorElo,
+	}
+
+	// An absent prior means the default, while prior=0 asks for none
+	if query.Get("prior") != "" {
+		prior, err := queryFloat(query, "prior")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ratingsReq.Prior = &prior
+	}
+	serveRatings(w, ratingsReq)
+}
+
+// queryFloat returns the number in a query parameter, 0 if it is absent
+func queryFloat(query url.Values, name string) (float64, error) {
+	v := query.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return f, nil
+}
+
+// serveRatings responds with the ratings fitted to the games of req
+func serveRatings(w http.ResponseWriter, req types.RatingsRequest) {
+	report, err := ratings.Report(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleRatings fits ratings to the games posted to /ratings
+func handleRatings(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ratingsReq types.RatingsRequest
+	if err := json.NewDecoder(req.Body).Decode(&ratingsReq); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	serveRatings(w, ratingsReq)
+}
+
+// serveTournaments lists tournaments on GET and starts one on POST
+func (r *tournamentRegistry) serveTournaments(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(types.TournamentList{Tournaments: r.ids()})
+	case http.MethodPost:
+		var create types.CreateTournamentRequest
+		if err := json.NewDecoder(req.Body).Decode(&create); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		id, err := r.create(create)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(types.CreateTournamentResponse{ID: id})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+)
+
+// brokenPlayer fails every move request, so it loses each game on its first turn
+func brokenPlayer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Out of order", http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewTournament(t *testing.T) {
+	players := []types.TournamentPlayer{{Name: "a", URL: "http://a"}, {Name: "b", URL: "http://b"}, {URL: "http://c"}}
+
+	tests := []struct {
+		name        string
+		req         types.CreateTournamentRequest
+		games       int
+		expectError bool
+	}{
+		{name: "Round robin", req: types.CreateTournamentRequest{Players: players, Format: "round-robin"}, games: 3},
+		{name: "Double round robin", req: types.CreateTournamentRequest{Players: players, Format: "double-round-robin"}, games: 6},
+		{name: "Gauntlet with default rounds", req: types.CreateTournamentRequest{Players: players, Format: "gauntlet"}, games: 4},
+		{name: "Gauntlet", req: types.CreateTournamentRequest{Players: players, Format: "gauntlet", Rounds: 3}, games: 6},
+		{
+			name:        "Single player",
+			req:         types.CreateTournamentRequest{Players: players[:1], Format: "round-robin"},
+			expectError: true,
+		},
+		{
+			name:        "Duplicate names",
+			req:         types.CreateTournamentRequest{Players: append(players, players[0]), Format: "round-robin"},
+			expectError: true,
+		},
+		{
+			name:        "Missing URL",
+			req:         types.CreateTournamentRequest{Players: append(players, types.TournamentPlayer{Name: "d"}), Format: "round-robin"},
+			expectError: true,
+		},
+		{name: "Swiss with default rounds", req: types.CreateTournamentRequest{Players: players, Format: "swiss"}},
+		{
+			name:        "Swiss with too many rounds",
+			req:         types.CreateTournamentRequest{Players: players, Format: "swiss", Rounds: 4},
+			expectError: true,
+		},
+		{
+			name:        "Unknown format",
+			req:         types.CreateTournamentRequest{Players: players, Format: "knockout"},
+			expectError: true,
+		},
+		{
+			name:        "Invalid start FEN",
+			req:         types.CreateTournamentRequest{Players: players, Format: "round-robin", StartFEN: "8/8/8 w - - 0 1"},
+			expectError: true,
+		},
+		{
+			name:        "Invalid time control",
+			req:         types.CreateTournamentRequest{Players: players, Format: "round-robin", TimeControl: "soon"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tournament, err := newTournament(tt.req)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(tournament.games) != tt.games {
+				t.Errorf("Expected %d games, got %d", tt.games, len(tournament.games))
+			}
+			if tournament.players[2].Name != "http://c" {
+				t.Errorf("Expected an unnamed player to be named after its URL, got %q", tournament.players[2].Name)
+			}
+			if players[2].Name != "" {
+				t.Error("Expected the request's players to be left alone")
+			}
+		})
+	}
+}
+
+func TestTournament(t *testing.T) {
+	games := newGameRegistry()
+	tournaments := newTournamentRegistry(games)
+	server := httptest.NewServer(tournaments)
+	defer server.Close()
+
+	// The good player beats both broken ones; a broken player wins when it has black
+	// against the other, which fails first
+	body, _ := json.Marshal(types.CreateTournamentRequest{
+		Players: []types.TournamentPlayer{
+			{Name: "broken-1", URL: brokenPlayer(t).URL},
+			{Name: "good", URL: scriptedPlayer(t).URL},
+			{Name: "broken-2", URL: brokenPlayer(t).URL},
+		},
+		Format:      "double-round-robin",
+		Concurrency: 2,
+	})
+	resp, err := http.Post(server.URL+"/tournaments", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var created types.CreateTournamentResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	var state types.TournamentState
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		getJSON(t, server.URL+"/tournaments/"+created.ID, &state)
+		if state.Status == types.GameStatusFinished {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Tournament did not finish: %+v", state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(state.Games) != 6 {
+		t.Fatalf("Expected 6 games, got %d", len(state.Games))
+	}
+	for _, g := range state.Games {
+		if g.Result == "*" || g.GameID == "" {
+			t.Errorf("Expected every game to be played, got %+v", g)
+		}
+		if _, ok := games.get(g.GameID); !ok {
+			t.Errorf("Game %s is not in the game registry", g.GameID)
+		}
+	}
+
+	expected := []types.TournamentStanding{
+		{Rank: 1, Player: "good", Points: 4, Games: 4, Wins: 4, Buchholz: 4, SonnebornBerger: 4},
+		{Rank: 2, Player: "broken-1", Points: 1, Games: 4, Wins: 1, Losses: 3, Buchholz: 10, SonnebornBerger: 1},
+		{Rank: 2, Player: "broken-2", Points: 1, Games: 4, Wins: 1, Losses: 3, Buchholz: 10, SonnebornBerger: 1},
+	}
+	if !reflect.DeepEqual(state.Standings, expected) {
+		t.Errorf("Unexpected standings: %+v", state.Standings)
+	}
+
+	crosstable := []types.CrosstableRow{
+		{Player: "good", Points: 4, Results: []string{"x", "11", "11"}},
+		{Player: "broken-1", Points: 1, Results: []string{"00", "x", "10"}},
+		{Player: "broken-2", Points: 1, Results: []string{"00", "01", "x"}},
+	}
+	if !reflect.DeepEqual(state.Crosstable, crosstable) {
+		t.Errorf("Unexpected crosstable: %+v", state.Crosstable)
+	}
+
+	var list types.TournamentList
+	getJSON(t, server.URL+"/tournaments", &list)
+	if len(list.Tournaments) != 1 || list.Tournaments[0] != created.ID {
+		t.Errorf("Expected tournaments [%s], got %v", created.ID, list.Tournaments)
+	}
+	if code := getJSON(t, server.URL+"/tournaments/missing", &state); code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, code)
+	}
+
+	var rated types.Ratings
+	if code := getJSON(t, server.URL+"/tournaments/"+created.ID+"/ratings?anchor=broken-1&anchor_elo=1000", &rated); code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if len(rated.Players) != 3 || rated.Players[0].Player != "good" {
+		t.Fatalf("Expected the good player rated first, got %+v", rated.Players)
+	}
+	for _, p := range rated.Players[1:] {
+		if p.Player == "broken-1" && p.Elo != 1000 {
+			t.Errorf("Expected broken-1 anchored at 1000, got %.1f", p.Elo)
+		}
+		if p.Elo >= rated.Players[0].Elo {
+			t.Errorf("Expected %s rated below the good player, got %+v", p.Player, rated.Players)
+		}
+	}
+	if len(rated.Matches) != 3 {
+		t.Errorf("Expected 3 matches, got %+v", rated.Matches)
+	}
+
+	for path, code := range map[string]int{
+		"/ratings?prior=many":    http.StatusBadRequest,
+		"/ratings?anchor=nobody": http.StatusUnprocessableEntity,
+		"/standings":             http.StatusNotFound,
+	} {
+		if got := getJSON(t, server.URL+"/tournaments/"+created.ID+path, &rated); got != code {
+			t.Errorf("%s: expected status %d, got %d", path, code, got)
+		}
+	}
+}
+
+func TestRatingsEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(handleRatings))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{
+			name:   "Games",
+			method: http.MethodPost,
+			body:   `{"games": [{"white": "a", "black": "b", "result": "1-0"}, {"white": "b", "black": "a", "result": "1/2-1/2"}]}`,
+			code:   http.StatusOK,
+		},
+		{name: "No games", method: http.MethodPost, body: `{"games": []}`, code: http.StatusUnprocessableEntity},
+		{
+			name:   "Perfect score without prior",
+			method: http.MethodPost,
+			body:   `{"prior": 0, "games": [{"white": "a", "black": "b", "result": "1-0"}]}`,
+			code:   http.StatusUnprocessableEntity,
+		},
+		{name: "Invalid body", method: http.MethodPost, body: `{`, code: http.StatusBadRequest},
+		{name: "Wrong method", method: http.MethodGet, code: http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(test.method, server.URL, bytes.NewReader([]byte(test.body)))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.code {
+				t.Fatalf("Expected status %d, got %d", test.code, resp.StatusCode)
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+
+			var rated types.Ratings
+			if err := json.NewDecoder(resp.Body).Decode(&rated); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(rated.Players) != 2 || rated.Players[0].Player != "a" || rated.Players[0].Points != 1.5 {
+				t.Errorf("Expected a rated first with 1.5 points, got %+v", rated.Players)
+			}
+		})
+	}
+}
+
+func TestSwissTournament(t *testing.T) {
+	tournaments := newTournamentRegistry(newGameRegistry())
+
+	players := []types.TournamentPlayer{{Name: "good", URL: scriptedPlayer(t).URL}}
+	for _, name := range []string{"broken-1", "broken-2", "broken-3", "broken-4"} {
+		players = append(players, types.TournamentPlayer{Name: name, URL: brokenPlayer(t).URL})
+	}
+
+	id, err := tournaments.create(types.CreateTournamentRequest{Players: players, Format: "swiss", Concurrency: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tournament, _ := tournaments.get(id)
+
+	var state types.TournamentState
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state = tournament.state()
+		if state.Status == types.GameStatusFinished {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Tournament did not finish: %+v", state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if state.Error != "" {
+		t.Fatalf("Unexpected error: %s", state.Error)
+	}
+
+	// Five players need three rounds, each with two games and a bye
+	if len(state.Games) != 9 {
+		t.Fatalf("Expected 9 games, got %d: %+v", len(state.Games), state.Games)
+	}
+	met := map[[2]string]bool{}
+	byes := map[int]int{}
+	for _, g := range state.Games {
+		if g.Result == "*" {
+			t.Errorf("Expected every game to be decided, got %+v", g)
+		}
+		if g.Bye {
+			byes[g.Round]++
+			continue
+		}
+		pair := [2]string{g.White, g.Black}
+		if pair[0] > pair[1] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		if met[pair] {
+			t.Errorf("Rematch between %v", pair)
+		}
+		met[pair] = true
+	}
+	for round := 1; round <= 3; round++ {
+		if byes[round] != 1 {
+			t.Errorf("Expected one bye in round %d, got %d", round, byes[round])
+		}
+	}
+
+	if top := state.Standings[0]; top.Player != "good" || top.Points != 3 || top.Rank != 1 {
+		t.Errorf("Expected the good player to win with 3 points, got %+v", top)
+	}
+}
+
+func TestTournamentTranscripts(t *testing.T) {
+	players := []types.TournamentPlayer{
+		{Name: "good", URL: scriptedPlayer(t).URL, Transcript: true},
+		{Name: "broken", URL: brokenPlayer(t).URL},
+	}
+	req := types.CreateTournamentRequest{Players: players, Format: "double-round-robin"}
+
+	// Without a transcript directory the tournament is rejected up front
+	if _, err := newTournamentRegistry(newGameRegistry()).create(req); err == nil {
+		t.Error("Expected error but got none")
+	}
+
+	games := newGameRegistry()
+	games.transcriptDir = t.TempDir()
+	tournaments := newTournamentRegistry(games)
+	id, err := tournaments.create(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tournament, _ := tournaments.get(id)
+
+	var state types.TournamentState
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state = tournament.state()
+		if state.Status == types.GameStatusFinished {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Tournament did not finish: %+v", state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Only the good player's side of each game is recorded
+	for _, g := range state.Games {
+		recorded, skipped := "white", "black"
+		if g.Black == "good" {
+			recorded, skipped = skipped, recorded
+		}
+		if _, err := os.Stat(filepath.Join(games.transcriptDir, g.GameID+"-"+recorded+".log")); err != nil {
+			t.Errorf("Expected a %s transcript for game %s: %v", recorded, g.GameID, err)
+		}
+		if _, err := os.Stat(filepath.Join(games.transcriptDir, g.GameID+"-"+skipped+".log")); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s transcript for game %s, got %v", skipped, g.GameID, err)
+		}
+	}
+}
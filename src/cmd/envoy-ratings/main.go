@@ -0,0 +1,119 @@
+// Command envoy-ratings rates players from game results: ratings fitted to all the
+// games by maximum likelihood, as BayesElo does, and the Elo difference of every pair
+// of players with its error margin. Games are read as JSON with a "games" list, such
+// as a tournament fetched from the coordinator, from files, stdin or a URL.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/ratings"
+)
+
+func main() {
+	url := flag.String("url", "", "Fetch the games from a URL, such as http://localhost:8080/tournaments/{id}")
+	prior := flag.Float64("prior", ratings.DefaultPrior, "Virtual draws per player against the pool average")
+	anchor := flag.String("anchor", "", "Player whose rating is fixed at -anchor-elo; otherwise ratings average it")
+	anchorElo := flag.Float64("anchor-elo", 0, "Rating of the anchor or the pool average")
+	asJSON := flag.Bool("json", false, "Print the ratings as JSON")
+	flag.Parse()
+
+	req := types.RatingsRequest{Prior: prior, Anchor: *anchor, AnchorElo: *anchorElo}
+	switch {
+	case *url != "":
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(*url)
+		if err != nil {
+			log.Fatalf("Failed to fetch games: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			log.Fatalf("Failed to fetch games: %s", resp.Status)
+		}
+		if err := readGames(resp.Body, &req); err != nil {
+			log.Fatalf("Failed to read games from %s: %v", *url, err)
+		}
+	case flag.NArg() == 0:
+		if err := readGames(os.Stdin, &req); err != nil {
+			log.Fatalf("Failed to read games: %v", err)
+		}
+	default:
+		for _, name := range flag.Args() {
+			f, err := os.Open(name)
+			if err != nil {
+				log.Fatalf("Failed to open games: %v", err)
+			}
+			err = readGames(f, &req)
+			f.Close()
+			if err != nil {
+				log.Fatalf("Failed to read games from %s: %v", name, err)
+			}
+		}
+	}
+
+	report, err := ratings.Report(req)
+	if err != nil {
+		log.Fatalf("Failed to rate players: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = writeReport(os.Stdout, report)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write ratings: %v", err)
+	}
+}
+
+// readGames appends the games of a JSON document to req
+func readGames(r io.Reader, req *types.RatingsRequest) error {
+	var doc struct {
+		Games []types.TournamentGame `json:"games"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	req.Games = append(req.Games, doc.Games...)
+	return nil
+}
+
+// writeReport prints the ratings and the head-to-head differences as tables
+func writeReport(w io.Writer, report types.Ratings) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Rank\tPlayer\tElo\tError\tGames\tPoints\tScore")
+	for _, p := range report.Players {
+		fmt.Fprintf(tw, "%d\t%s\t%.0f\t±%.0f\t%d\t%.1f\t%.1f%%\n",
+			p.Rank, p.Player, p.Elo, p.Error, p.Games, p.Points, 100*p.Points/float64(p.Games))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nDraw Elo %.0f, white advantage %.0f\n\n", report.DrawElo, report.WhiteAdvantage)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Player\tOpponent\tW-D-L\tElo\tError\tLOS\tDraws")
+	for _, m := range report.Matches {
+		elo, margin := fmt.Sprintf("%+.0f", m.Elo), fmt.Sprintf("±%.0f", m.Error)
+		if m.Unbounded {
+			elo, margin = "+inf", "-"
+			if m.Wins == 0 {
+				elo = "-inf"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d-%d-%d\t%s\t%s\t%.1f%%\t%.0f%%\n",
+			m.Player, m.Opponent, m.Wins, m.Draws, m.Losses, elo, margin, 100*m.LOS, 100*m.DrawRatio)
+	}
+	return tw.Flush()
+}
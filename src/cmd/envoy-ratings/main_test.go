@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shehio/envoy/pkg/types"
+)
+
+func TestReadGames(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		games       int
+		expectError bool
+	}{
+		{name: "Games", input: `{"games": [{"white": "a", "black": "b", "result": "1-0"}]}`, games: 1},
+		{name: "Tournament state", input: `{"id": "t1", "status": "finished", "games": [{"round": 1, "white": "a", "black": "b", "result": "1-0"}, {"round": 1, "white": "c", "bye": true, "result": "1-0"}]}`, games: 2},
+		{name: "No games", input: `{}`},
+		{name: "Invalid JSON", input: `[`, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var req types.RatingsRequest
+			err := readGames(strings.NewReader(test.input), &req)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(req.Games) != test.games {
+				t.Errorf("Expected %d games, got %d", test.games, len(req.Games))
+			}
+		})
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	report := types.Ratings{
+		Players: []types.PlayerRating{
+			{Rank: 1, Player: "alpha", Elo: 52.4, Error: 30.1, Games: 4, Points: 3},
+			{Rank: 2, Player: "beta", Elo: -52.4, Error: 30.1, Games: 4, Points: 1},
+		},
+		DrawElo:        97.2,
+		WhiteAdvantage: 12.8,
+		Matches: []types.MatchRating{
+			{Player: "alpha", Opponent: "beta", Wins: 2, Draws: 2, Elo: 190.8, Error: 210.4, LOS: 0.9214, DrawRatio: 0.5},
+			{Player: "beta", Opponent: "gamma", Losses: 2, LOS: 0.0786, Unbounded: true},
+		},
+	}
+
+	var out strings.Builder
+	if err := writeReport(&out, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"1     alpha   52   ±30    4      3.0     75.0%",
+		"Draw Elo 97, white advantage 13",
+		"alpha   beta      2-2-0  +191  ±210   92.1%  50%",
+		"beta    gamma     0-0-2  -inf  -",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
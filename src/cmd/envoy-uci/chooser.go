@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/board"
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+// MoveChooser picks the move to play in a position. The position always has at least
+// one legal move and was reached by playing history's moves from its start position;
+// ctx is done when the GUI stops the search or its time is up.
+type MoveChooser interface {
+	ChooseMove(ctx context.Context, position *board.Board, history uci.Position, limits uci.SearchLimits) (board.Move, error)
+}
+
+// RandomChooser plays a uniformly random legal move
+type RandomChooser struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewRandomChooser creates a random chooser with the given seed
+func NewRandomChooser(seed int64) *RandomChooser {
+	return &RandomChooser{rand: rand.New(rand.NewSource(seed))}
+}
+
+// ChooseMove implements MoveChooser
+func (c *RandomChooser) ChooseMove(ctx context.Context, position *board.Board, history uci.Position, limits uci.SearchLimits) (board.Move, error) {
+	moves := position.LegalMoves()
+	if len(moves) == 0 {
+		return board.Move{}, fmt.Errorf("no legal moves")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return moves[c.rand.Intn(len(moves))], nil
+}
+
+// HTTPChooser asks an Envoy player service for its move, the same way the coordinator does
+type HTTPChooser struct {
+	URL    string
+	Client *http.Client
+}
+
+// ChooseMove implements MoveChooser
+func (c *HTTPChooser) ChooseMove(ctx context.Context, position *board.Board, history uci.Position, limits uci.SearchLimits) (board.Move, error) {
+	jsonData, err := json.Marshal(types.MoveRequest{FEN: position.FEN(), StartFEN: history.FEN, Moves: history.Moves})
+	if err != nil {
+		return board.Move{}, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return board.Move{}, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return board.Move{}, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return board.Move{}, fmt.Errorf("player returned status %d", resp.StatusCode)
+	}
+
+	var moveResp types.MoveResponse
+	if err := json.NewDecoder(resp.Body).Decode(&moveResp); err != nil {
+		return board.Move{}, fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	move, err := board.ParseMove(moveResp.Move)
+	if err != nil {
+		return board.Move{}, fmt.Errorf("player returned invalid move: %v", err)
+	}
+	if !position.IsLegal(move) {
+		return board.Move{}, fmt.Errorf("player returned illegal move %s", move)
+	}
+	return move, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shehio/envoy/pkg/types"
+	"github.com/shehio/envoy/src/internal/board"
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+func TestRandomChooser(t *testing.T) {
+	chooser := NewRandomChooser(1)
+	position := board.NewBoard()
+
+	for i := 0; i < 20; i++ {
+		move, err := chooser.ChooseMove(context.Background(), position, uci.Position{}, uci.SearchLimits{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !position.IsLegal(move) {
+			t.Fatalf("Chose illegal move %s", move)
+		}
+	}
+}
+
+func TestHTTPChooser(t *testing.T) {
+	tests := []struct {
+		name        string
+		reply       string
+		status      int
+		expectError bool
+	}{
+		{name: "Legal move", reply: "g1f3", status: http.StatusOK},
+		{name: "Illegal move", reply: "e2e5", status: http.StatusOK, expectError: true},
+		{name: "Malformed move", reply: "castle", status: http.StatusOK, expectError: true},
+		{name: "Player error", status: http.StatusInternalServerError, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := uci.Position{FEN: startFEN, Moves: []string{"e2e4", "e7e5"}}
+			position := board.NewBoard()
+			position.SetFEN("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2")
+			player := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req types.MoveRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FEN != position.FEN() {
+					t.Errorf("Unexpected request %+v: %v", req, err)
+				}
+				if req.StartFEN != history.FEN || strings.Join(req.Moves, " ") != "e2e4 e7e5" {
+					t.Errorf("Expected the game history, got %+v", req)
+				}
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(types.MoveResponse{Move: tt.reply})
+			}))
+			defer player.Close()
+
+			chooser := &HTTPChooser{URL: player.URL}
+			move, err := chooser.ChooseMove(context.Background(), position, history, uci.SearchLimits{})
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if move.String() != tt.reply {
+				t.Errorf("Expected %s, got %s", tt.reply, move)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Command envoy-uci exposes an Envoy player as a UCI engine on stdin and stdout, so
+// GUIs such as Cute Chess and the stockfish handler can drive it.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	chooserName := flag.String("chooser", "random", "Move chooser: random or http")
+	playerURL := flag.String("player-url", os.Getenv("PLAYER_URL"), "Player service asked for moves by the http chooser")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "Seed for the random chooser")
+	flag.Parse()
+
+	// stdout belongs to the protocol
+	log.SetOutput(os.Stderr)
+
+	var chooser MoveChooser
+	switch *chooserName {
+	case "random":
+		chooser = NewRandomChooser(*seed)
+	case "http":
+		if *playerURL == "" {
+			log.Fatal("The http chooser needs -player-url or PLAYER_URL")
+		}
+		chooser = &HTTPChooser{URL: *playerURL, Client: &http.Client{Timeout: 10 * time.Second}}
+	default:
+		log.Fatalf("Unknown chooser %q", *chooserName)
+	}
+
+	server := newServer("Envoy", "Envoy contributors", chooser, os.Stdout)
+	if err := server.run(os.Stdin); err != nil {
+		log.Fatalf("Failed to read commands: %v", err)
+	}
+}
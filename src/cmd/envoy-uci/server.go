@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/board"
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// defaultMovesToGo is the number of moves the remaining clock time is spread over when
+// the GUI does not send movestogo
+const defaultMovesToGo = 30
+
+// server speaks UCI to a GUI, keeping the position it is given and answering go
+// commands with moves from its chooser
+type server struct {
+	name    string
+	author  string
+	chooser MoveChooser
+	board   *board.Board
+	history uci.Position // How the GUI reached board
+
+	outMu sync.Mutex // Serializes writes from the command loop and searches
+	out   io.Writer
+
+	cancel  context.CancelFunc // Stops the running search, nil when idle
+	done    chan struct{}      // Closed when the running search has printed its move
+	endless bool               // Whether the running search waits for stop
+	limits  uci.SearchLimits   // Limits of the running search
+	hit     chan struct{}      // Closed on ponderhit, nil unless the running search ponders
+}
+
+// newServer creates a server writing its replies to out
+func newServer(name, author string, chooser MoveChooser, out io.Writer) *server {
+	return &server{
+		name:    name,
+		author:  author,
+		chooser: chooser,
+		board:   board.NewBoard(),
+		history: uci.Position{FEN: startFEN},
+		out:     out,
+	}
+}
+
+// run processes commands from in until quit or end of input. At the end of input a
+// bounded search still prints its move.
+func (s *server) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			s.println("id name " + s.name)
+			s.println("id author " + s.author)
+			s.println("uciok")
+		case "isready":
+			s.println("readyok")
+		case "ucinewgame":
+			s.stopSearch()
+			s.board = board.NewBoard()
+			s.history = uci.Position{FEN: startFEN}
+		case "position":
+			s.stopSearch()
+			if err := s.setPosition(fields[1:]); err != nil {
+				s.println("info string " + err.Error())
+			}
+		case "go":
+			limits, err := uci.ParseGo(line)
+			if err != nil {
+				s.println("info string " + err.Error())
+				continue
+			}
+			s.stopSearch()
+			s.startSearch(limits)
+		case "stop":
+			s.stopSearch()
+		case "ponderhit":
+			s.ponderHit()
+		case "quit":
+			s.stopSearch()
+			return nil
+		}
+	}
+
+	if s.endless {
+		s.stopSearch()
+	}
+	s.waitSearch()
+	return scanner.Err()
+}
+
+// println writes a single line to the GUI
+func (s *server) println(line string) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintln(s.out, line)
+}
+
+// setPosition handles the arguments of a position command. Moves are applied up to the
+// first illegal one.
+func (s *server) setPosition(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing position")
+	}
+
+	var fen string
+	var rest []string
+	switch args[0] {
+	case "startpos":
+		fen = startFEN
+		rest = args[1:]
+	case "fen":
+		end := 1
+		for end < len(args) && args[end] != "moves" {
+			end++
+		}
+		fen = strings.Join(args[1:end], " ")
+		rest = args[end:]
+	default:
+		return fmt.Errorf("unknown position type %q", args[0])
+	}
+
+	position := board.NewBoard()
+	if err := position.SetFEN(fen); err != nil {
+		return fmt.Errorf("invalid position: %v", err)
+	}
+	s.board = position
+	s.history = uci.Position{FEN: fen}
+
+	if len(rest) == 0 {
+		return nil
+	}
+	if rest[0] != "moves" {
+		return fmt.Errorf("unexpected %q after position", rest[0])
+	}
+
+	for _, text := range rest[1:] {
+		move, err := board.ParseMove(text)
+		if err != nil {
+			return fmt.Errorf("invalid move %s: %v", text, err)
+		}
+		if !position.IsLegal(move) {
+			return fmt.Errorf("illegal move %s", text)
+		}
+		position.MakeMove(move)
+		s.history.Moves = append(s.history.Moves, move.String())
+	}
+	return nil
+}
+
+// startSearch chooses a move for the current position in the background. Infinite and
+// ponder searches print their move only once stopped, as UCI requires; a ponder search
+// continues as a normal one after ponderhit.
+func (s *server) startSearch(limits uci.SearchLimits) {
+	position := *s.board
+	history := uci.Position{FEN: s.history.FEN, Moves: append([]string(nil), s.history.Moves...)}
+	ctx, cancel := context.WithCancel(context.Background())
+	if budget := thinkTime(limits, position.IsWhiteToMove()); budget > 0 {
+		cancel()
+		ctx, cancel = context.WithTimeout(context.Background(), budget)
+	}
+
+	done := make(chan struct{})
+	s.cancel = cancel
+	s.done = done
+	s.endless = limits.Infinite || limits.Ponder
+	s.limits = limits
+	var hit chan struct{}
+	if limits.Ponder && !limits.Infinite {
+		hit = make(chan struct{})
+	}
+	s.hit = hit
+
+	go func() {
+		defer close(done)
+
+		moves := position.LegalMoves()
+		if len(moves) == 0 {
+			s.println("bestmove 0000")
+			return
+		}
+
+		move, err := s.chooser.ChooseMove(ctx, &position, history, limits)
+		if err != nil {
+			// A move is owed in any case; fall back to the first legal one
+			s.println("info string " + err.Error())
+			move = moves[0]
+		}
+
+		if limits.Infinite {
+			<-ctx.Done()
+		} else if limits.Ponder {
+			select {
+			case <-ctx.Done():
+			case <-hit:
+			}
+		}
+		s.println("bestmove " + move.String())
+	}()
+}
+
+// ponderHit turns the running ponder search into a normal search under the same limits,
+// with the think time counted from now
+func (s *server) ponderHit() {
+	if s.cancel == nil || !s.limits.Ponder {
+		return
+	}
+
+	limits := s.limits
+	limits.Ponder = false
+	if budget := thinkTime(limits, s.board.IsWhiteToMove()); budget > 0 {
+		time.AfterFunc(budget, s.cancel)
+	}
+
+	s.limits = limits
+	s.endless = limits.Infinite
+	if s.hit != nil {
+		close(s.hit)
+		s.hit = nil
+	}
+}
+
+// stopSearch stops the running search and waits for its move
+func (s *server) stopSearch() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.waitSearch()
+}
+
+// waitSearch waits until the running search has printed its move
+func (s *server) waitSearch() {
+	if s.done == nil {
+		return
+	}
+	<-s.done
+	s.cancel()
+	s.cancel = nil
+	s.done = nil
+	s.hit = nil
+}
+
+// thinkTime returns how long a search may take, zero meaning until stopped or the
+// chooser is done
+func thinkTime(limits uci.SearchLimits, white bool) time.Duration {
+	if limits.Infinite || limits.Ponder {
+		return 0
+	}
+	if limits.MoveTime > 0 {
+		return limits.MoveTime
+	}
+
+	remaining, increment := limits.WTime, limits.WInc
+	if !white {
+		remaining, increment = limits.BTime, limits.BInc
+	}
+	if remaining <= 0 {
+		return 0
+	}
+
+	movesToGo := limits.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+	budget := remaining/time.Duration(movesToGo) + increment
+	if budget > remaining/2 {
+		budget = remaining / 2
+	}
+	return budget
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/board"
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+// recordingChooser plays the first legal move and records the positions it was asked about
+type recordingChooser struct {
+	fens    []string
+	history uci.Position
+	limit   uci.SearchLimits
+}
+
+func (c *recordingChooser) ChooseMove(ctx context.Context, position *board.Board, history uci.Position, limits uci.SearchLimits) (board.Move, error) {
+	c.fens = append(c.fens, position.FEN())
+	c.history = history
+	c.limit = limits
+	return position.LegalMoves()[0], nil
+}
+
+// runServer feeds commands to a server and returns its output lines
+func runServer(t *testing.T, chooser MoveChooser, commands ...string) []string {
+	t.Helper()
+
+	var out strings.Builder
+	server := newServer("Envoy", "Tests", chooser, &out)
+	if err := server.run(strings.NewReader(strings.Join(commands, "\n"))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func TestHandshake(t *testing.T) {
+	lines := runServer(t, &recordingChooser{}, "uci", "isready", "quit")
+	expected := []string{"id name Envoy", "id author Tests", "uciok", "readyok"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("Got %q; want %q", lines, expected)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{
+			name:     "Start position",
+			command:  "position startpos",
+			expected: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		},
+		{
+			name:     "Start position with moves",
+			command:  "position startpos moves e2e4 e7e5 g1f3",
+			expected: "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+		},
+		{
+			name:     "FEN with castling",
+			command:  "position fen r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1 moves e1g1",
+			expected: "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1",
+		},
+		{
+			name:     "Illegal move stops the move list",
+			command:  "position startpos moves e2e4 e2e4 e7e5",
+			expected: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chooser := &recordingChooser{}
+			runServer(t, chooser, test.command, "go depth 1")
+			if len(chooser.fens) != 1 || chooser.fens[0] != test.expected {
+				t.Errorf("Chooser saw %q; want %q", chooser.fens, test.expected)
+			}
+		})
+	}
+}
+
+func TestGo(t *testing.T) {
+	chooser := &recordingChooser{}
+	lines := runServer(t, chooser, "position startpos moves e2e4", "go wtime 1000 btime 2000 movestogo 10")
+
+	if lines[len(lines)-1] != "bestmove a7a6" {
+		t.Errorf("Expected bestmove a7a6, got %q", lines)
+	}
+	if chooser.limit.BTime != 2*time.Second || chooser.limit.MovesToGo != 10 {
+		t.Errorf("Chooser got limits %+v", chooser.limit)
+	}
+	if chooser.history.FEN != startFEN || strings.Join(chooser.history.Moves, " ") != "e2e4" {
+		t.Errorf("Chooser got history %+v", chooser.history)
+	}
+}
+
+func TestGoWithoutLegalMoves(t *testing.T) {
+	lines := runServer(t, &recordingChooser{}, "position fen 7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", "go movetime 10")
+	if lines[len(lines)-1] != "bestmove 0000" {
+		t.Errorf("Expected bestmove 0000, got %q", lines)
+	}
+}
+
+func TestInfiniteSearchWaitsForStop(t *testing.T) {
+	in, commands := io.Pipe()
+	out, replies := io.Pipe()
+	server := newServer("Envoy", "Tests", &recordingChooser{}, replies)
+
+	go func() {
+		server.run(in)
+		replies.Close()
+	}()
+	lines := bufio.NewScanner(out)
+
+	io.WriteString(commands, "go infinite\nisready\n")
+	if !lines.Scan() || lines.Text() != "readyok" {
+		t.Fatalf("Expected readyok before the move, got %q", lines.Text())
+	}
+
+	io.WriteString(commands, "stop\n")
+	if !lines.Scan() || lines.Text() != "bestmove b1c3" {
+		t.Fatalf("Expected bestmove after stop, got %q", lines.Text())
+	}
+
+	io.WriteString(commands, "quit\n")
+	if lines.Scan() {
+		t.Errorf("Unexpected output %q", lines.Text())
+	}
+}
+
+// waitingChooser plays the first legal move once its search is stopped or out of time
+type waitingChooser struct{}
+
+func (waitingChooser) ChooseMove(ctx context.Context, position *board.Board, history uci.Position, limits uci.SearchLimits) (board.Move, error) {
+	<-ctx.Done()
+	return position.LegalMoves()[0], nil
+}
+
+func TestPonderHit(t *testing.T) {
+	in, commands := io.Pipe()
+	out, replies := io.Pipe()
+	server := newServer("Envoy", "Tests", waitingChooser{}, replies)
+
+	go func() {
+		server.run(in)
+		replies.Close()
+	}()
+	lines := bufio.NewScanner(out)
+
+	// Pondering ignores the move time until ponderhit
+	io.WriteString(commands, "go ponder movetime 20\n")
+	time.Sleep(50 * time.Millisecond)
+	io.WriteString(commands, "isready\n")
+	if !lines.Scan() || lines.Text() != "readyok" {
+		t.Fatalf("Expected readyok while pondering, got %q", lines.Text())
+	}
+
+	// After ponderhit the search ends on its own once the move time is up
+	start := time.Now()
+	io.WriteString(commands, "ponderhit\n")
+	if !lines.Scan() || lines.Text() != "bestmove b1c3" {
+		t.Fatalf("Expected bestmove after ponderhit, got %q", lines.Text())
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Search ended after %v, before its move time", elapsed)
+	}
+
+	io.WriteString(commands, "quit\n")
+	if lines.Scan() {
+		t.Errorf("Unexpected output %q", lines.Text())
+	}
+}
+
+func TestThinkTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   uci.SearchLimits
+		white    bool
+		expected time.Duration
+	}{
+		{"Move time", uci.SearchLimits{MoveTime: 500 * time.Millisecond}, true, 500 * time.Millisecond},
+		{"Clock for white", uci.SearchLimits{WTime: time.Minute, WInc: time.Second, BTime: time.Second}, true, 3 * time.Second},
+		{"Clock for black", uci.SearchLimits{WTime: time.Minute, BTime: 10 * time.Second, MovesToGo: 5}, false, 2 * time.Second},
+		{"Capped at half the clock", uci.SearchLimits{WTime: time.Second, WInc: 5 * time.Second}, true, 500 * time.Millisecond},
+		{"Depth only", uci.SearchLimits{Depth: 5}, true, 0},
+		{"Infinite", uci.SearchLimits{Infinite: true, MoveTime: time.Second}, true, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if budget := thinkTime(test.limits, test.white); budget != test.expected {
+				t.Errorf("thinkTime() = %v; want %v", budget, test.expected)
+			}
+		})
+	}
+}
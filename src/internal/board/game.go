@@ -1,47 +1,191 @@
 package board
 
+import "strings"
+
+// Reasons a game ends by rule, as returned by Termination
+const (
+	TerminationKingCaptured         = "king captured"
+	TerminationCheckmate            = "checkmate"
+	TerminationStalemate            = "stalemate"
+	TerminationInsufficientMaterial = "insufficient material"
+	TerminationFiftyMoveRule        = "fifty-move rule"
+)
+
 // IsGameOver returns whether the game is over
 func (b *Board) IsGameOver() bool {
-	whiteKingFound := false
-	blackKingFound := false
+	return b.Termination() != ""
+}
 
-	for _, piece := range b.squares {
-		if piece == WhiteKing {
-			whiteKingFound = true
-		} else if piece == BlackKing {
-			blackKingFound = true
-		}
+// Termination returns why the game is over in this position, or an empty string if it
+// is not. Threefold repetition depends on the game's history and is not detected here.
+func (b *Board) Termination() string {
+	whiteKingFound, blackKingFound := b.kingsPresent()
+	if !whiteKingFound || !blackKingFound {
+		return TerminationKingCaptured
 	}
 
-	return !whiteKingFound || !blackKingFound
+	if len(b.LegalMoves()) == 0 {
+		if b.InCheck() {
+			return TerminationCheckmate
+		}
+		return TerminationStalemate
+	}
+	if b.IsInsufficientMaterial() {
+		return TerminationInsufficientMaterial
+	}
+	if b.halfMoveClock >= 100 {
+		return TerminationFiftyMoveRule
+	}
+	return ""
 }
 
 // Result returns the game result ("1-0", "0-1", "1/2-1/2", or "*")
 func (b *Board) Result() string {
-	if !b.IsGameOver() {
+	switch b.Termination() {
+	case "":
 		return "*"
+	case TerminationKingCaptured:
+		whiteKingFound, blackKingFound := b.kingsPresent()
+		if !whiteKingFound && blackKingFound {
+			return "0-1"
+		}
+		if !blackKingFound && whiteKingFound {
+			return "1-0"
+		}
+		return "1/2-1/2"
+	case TerminationCheckmate:
+		if b.whiteToMove {
+			return "0-1"
+		}
+		return "1-0"
+	default:
+		return "1/2-1/2"
 	}
+}
 
-	whiteKingFound := false
-	blackKingFound := false
-
+// kingsPresent returns whether each side still has its king
+func (b *Board) kingsPresent() (white, black bool) {
 	for _, piece := range b.squares {
 		if piece == WhiteKing {
-			whiteKingFound = true
+			white = true
 		} else if piece == BlackKing {
-			blackKingFound = true
+			black = true
 		}
 	}
+	return white, black
+}
 
-	if !whiteKingFound && !blackKingFound {
-		return "1/2-1/2"
+// IsInsufficientMaterial returns whether neither side can possibly checkmate: king
+// against king, king and a minor piece against king, or kings and bishops all on
+// squares of one colour
+func (b *Board) IsInsufficientMaterial() bool {
+	minors := 0
+	bishopColors := map[int]bool{}
+	knights := 0
+	for square, piece := range b.squares {
+		switch piece {
+		case WhitePawn, BlackPawn, WhiteRook, BlackRook, WhiteQueen, BlackQueen:
+			return false
+		case WhiteKnight, BlackKnight:
+			minors++
+			knights++
+		case WhiteBishop, BlackBishop:
+			minors++
+			bishopColors[(square/8+square%8)%2] = true
+		}
 	}
-	if !whiteKingFound {
-		return "0-1"
+
+	if minors <= 1 {
+		return true
 	}
-	if !blackKingFound {
-		return "1-0"
+	return knights == 0 && len(bishopColors) == 1
+}
+
+// HasMatingMaterial returns whether the given side has enough material to checkmate
+// with the opponent's help. A lone king or a king with a single minor piece has not.
+func (b *Board) HasMatingMaterial(white bool) bool {
+	minors := 0
+	for _, piece := range b.squares {
+		if piece == NoPiece || piece.IsWhitePiece() != white {
+			continue
+		}
+		switch piece {
+		case WhitePawn, BlackPawn, WhiteRook, BlackRook, WhiteQueen, BlackQueen:
+			return true
+		case WhiteKnight, BlackKnight, WhiteBishop, BlackBishop:
+			minors++
+		}
+	}
+	return minors >= 2
+}
+
+// HalfMoveClock returns the number of half moves since the last capture or pawn move
+func (b *Board) HalfMoveClock() int {
+	return b.halfMoveClock
+}
+
+// RepetitionKey identifies the position for repetition detection: placement, side to
+// move, castling rights and an en passant square only when a capture there is legal
+func (b *Board) RepetitionKey() string {
+	fields := strings.Fields(b.FEN())
+	enPassant := "-"
+	if b.enPassantSquare != "-" {
+		for _, move := range b.LegalMoves() {
+			piece := b.PieceAt(move.From)
+			if move.To == b.enPassantSquare && (piece == WhitePawn || piece == BlackPawn) {
+				enPassant = b.enPassantSquare
+				break
+			}
+		}
+	}
+	return strings.Join([]string{fields[0], fields[1], fields[2], enPassant}, " ")
+}
+
+// startingCount is the number of pieces of each kind a side starts with
+var startingCount = map[Piece]int{
+	WhitePawn: 8, WhiteKnight: 2, WhiteBishop: 2, WhiteRook: 2, WhiteQueen: 1,
+	BlackPawn: 8, BlackKnight: 2, BlackBishop: 2, BlackRook: 2, BlackQueen: 1,
+}
+
+// pieceValues are the conventional material values of the pieces in pawns
+var pieceValues = map[Piece]int{
+	WhitePawn: 1, WhiteKnight: 3, WhiteBishop: 3, WhiteRook: 5, WhiteQueen: 9,
+	BlackPawn: 1, BlackKnight: 3, BlackBishop: 3, BlackRook: 5, BlackQueen: 9,
+}
+
+// Captured returns the pieces missing from the board compared to the starting set,
+// ordered from pawns to queens. Pieces of the given colour are the ones captured by the
+// opponent. A promoted piece makes up for a missing piece of its kind, so counts are
+// approximate after promotions.
+func (b *Board) Captured(white bool) []Piece {
+	counts := map[Piece]int{}
+	for _, piece := range b.squares {
+		counts[piece]++
 	}
 
-	return "1/2-1/2"
-} 
\ No newline at end of file
+	first, last := BlackPawn, BlackQueen
+	if white {
+		first, last = WhitePawn, WhiteQueen
+	}
+
+	var captured []Piece
+	for piece := first; piece <= last; piece++ {
+		for i := counts[piece]; i < startingCount[piece]; i++ {
+			captured = append(captured, piece)
+		}
+	}
+	return captured
+}
+
+// MaterialBalance returns white's material minus black's, in pawns
+func (b *Board) MaterialBalance() int {
+	balance := 0
+	for _, piece := range b.squares {
+		if piece.IsWhitePiece() {
+			balance += pieceValues[piece]
+		} else {
+			balance -= pieceValues[piece]
+		}
+	}
+	return balance
+}
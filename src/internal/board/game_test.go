@@ -100,4 +100,133 @@ func TestResult(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+} 
+
+func TestTermination(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		termination string
+		result      string
+	}{
+		{"In progress", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "", "*"},
+		{"Checkmate", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", TerminationCheckmate, "0-1"},
+		{"Stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", TerminationStalemate, "1/2-1/2"},
+		{"Bare kings", "8/8/4k3/8/8/3K4/8/8 w - - 0 1", TerminationInsufficientMaterial, "1/2-1/2"},
+		{"King and knight", "8/8/4k3/8/8/3KN3/8/8 w - - 0 1", TerminationInsufficientMaterial, "1/2-1/2"},
+		{"Same coloured bishops", "8/8/4kb2/8/8/3KB3/8/8 w - - 0 1", TerminationInsufficientMaterial, "1/2-1/2"},
+		{"Opposite coloured bishops", "8/8/4k1b1/8/8/3KB3/8/8 w - - 0 1", "", "*"},
+		{"Fifty-move rule", "8/8/4k3/8/8/3K4/8/R7 w - - 100 80", TerminationFiftyMoveRule, "1/2-1/2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			board := NewBoard()
+			if err := board.SetFEN(test.fen); err != nil {
+				t.Fatalf("Failed to set FEN: %v", err)
+			}
+
+			if termination := board.Termination(); termination != test.termination {
+				t.Errorf("Termination() = %q, expected %q", termination, test.termination)
+			}
+			if result := board.Result(); result != test.result {
+				t.Errorf("Result() = %s, expected %s", result, test.result)
+			}
+		})
+	}
+}
+
+func TestHasMatingMaterial(t *testing.T) {
+	board := NewBoard()
+	if err := board.SetFEN("8/8/4k3/8/8/3KN3/4p3/8 w - - 0 1"); err != nil {
+		t.Fatalf("Failed to set FEN: %v", err)
+	}
+
+	if board.HasMatingMaterial(true) {
+		t.Error("A king and knight cannot mate")
+	}
+	if !board.HasMatingMaterial(false) {
+		t.Error("A pawn can still promote")
+	}
+}
+
+func TestRepetitionKey(t *testing.T) {
+	board := NewBoard()
+	board.SetFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+
+	// A double push without a possible capture does not change the key
+	board.MakeMove(Move{From: "e2", To: "e4"})
+	if key := board.RepetitionKey(); key != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -" {
+		t.Errorf("RepetitionKey() = %q", key)
+	}
+
+	board.SetFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3")
+	if key := board.RepetitionKey(); key != "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3" {
+		t.Errorf("RepetitionKey() = %q", key)
+	}
+}
+
+func TestCaptured(t *testing.T) {
+	tests := []struct {
+		name    string
+		fen     string
+		white   []Piece
+		black   []Piece
+		balance int
+	}{
+		{
+			name: "Starting position",
+			fen:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		},
+		{
+			name:    "White won a pawn",
+			fen:     "rnbqkbnr/ppp1pppp/8/3P4/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2",
+			black:   []Piece{BlackPawn},
+			balance: 1,
+		},
+		{
+			name:    "Black lost the queen and a knight",
+			fen:     "r1b1kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			black:   []Piece{BlackKnight, BlackQueen},
+			balance: 12,
+		},
+		{
+			name:    "Promoted queen",
+			fen:     "4k3/8/8/8/8/8/8/QQ2K3 w - - 0 1",
+			white:   []Piece{WhitePawn, WhitePawn, WhitePawn, WhitePawn, WhitePawn, WhitePawn, WhitePawn, WhitePawn, WhiteKnight, WhiteKnight, WhiteBishop, WhiteBishop, WhiteRook, WhiteRook},
+			black:   []Piece{BlackPawn, BlackPawn, BlackPawn, BlackPawn, BlackPawn, BlackPawn, BlackPawn, BlackPawn, BlackKnight, BlackKnight, BlackBishop, BlackBishop, BlackRook, BlackRook, BlackQueen},
+			balance: 18,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := NewBoard()
+			if err := b.SetFEN(test.fen); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := b.Captured(true); !equalPieces(got, test.white) {
+				t.Errorf("Captured(white) = %v; want %v", got, test.white)
+			}
+			if got := b.Captured(false); !equalPieces(got, test.black) {
+				t.Errorf("Captured(black) = %v; want %v", got, test.black)
+			}
+			if got := b.MaterialBalance(); got != test.balance {
+				t.Errorf("MaterialBalance() = %d; want %d", got, test.balance)
+			}
+		})
+	}
+}
+
+// equalPieces returns whether two piece lists hold the same pieces in the same order
+func equalPieces(a, b []Piece) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
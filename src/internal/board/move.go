@@ -3,6 +3,7 @@ package board
 import (
 	"fmt"
 	"strings"
+	"unicode"
 )
 
 // Move represents a chess move
@@ -28,38 +29,27 @@ func (b *Board) MakeMove(move Move) error {
 		return fmt.Errorf("invalid square")
 	}
 
-	// Handle promotion first
+	piece := b.squares[from]
+	if piece == NoPiece {
+		return fmt.Errorf("no piece at source square")
+	}
+
+	// Check the promotion before the turn so a bad promotion is reported as such
 	if move.Promotion != NoPiece {
 		toRank := to / 8
-		piece := b.squares[from]
-		if piece == NoPiece {
-			return fmt.Errorf("no piece at source square")
-		}
-		if piece == WhitePawn && toRank == 7 {
-			b.squares[to] = move.Promotion
-			b.squares[from] = NoPiece
-			b.whiteToMove = !b.whiteToMove
-			return nil
-		} else if piece == BlackPawn && toRank == 0 {
-			b.squares[to] = move.Promotion
-			b.squares[from] = NoPiece
-			b.whiteToMove = !b.whiteToMove
-			return nil
-		} else {
+		if !(piece == WhitePawn && toRank == 7 && isWhitePromotion(move.Promotion)) &&
+			!(piece == BlackPawn && toRank == 0 && isBlackPromotion(move.Promotion)) {
 			return fmt.Errorf("invalid promotion")
 		}
 	}
 
-	piece := b.squares[from]
-	if piece == NoPiece {
-		return fmt.Errorf("no piece at source square")
-	}
-
 	// Check if it's the correct player's turn
 	if (piece.IsWhitePiece() && !b.whiteToMove) || (!piece.IsWhitePiece() && b.whiteToMove) {
 		return fmt.Errorf("not your turn")
 	}
 
+	captured := b.squares[to]
+
 	// Handle en passant capture
 	if piece == WhitePawn || piece == BlackPawn {
 		fromFile := from % 8
@@ -68,7 +58,7 @@ func (b *Board) MakeMove(move Move) error {
 		toRank := to / 8
 
 		// Check for en passant capture
-		if abs(fromFile-toFile) == 1 && abs(fromRank-toRank) == 1 {
+		if abs(fromFile-toFile) == 1 && abs(fromRank-toRank) == 1 && captured == NoPiece {
 			if b.enPassantSquare != "-" {
 				epFile := int(b.enPassantSquare[0] - 'a')
 				epRank := int(b.enPassantSquare[1] - '1')
@@ -76,10 +66,11 @@ func (b *Board) MakeMove(move Move) error {
 					// Remove the captured pawn
 					capturedRank := fromRank
 					if piece == WhitePawn {
-						capturedRank = toRank + 1
-					} else {
 						capturedRank = toRank - 1
+					} else {
+						capturedRank = toRank + 1
 					}
+					captured = b.squares[capturedRank*8+toFile]
 					b.squares[capturedRank*8+toFile] = NoPiece
 				}
 			}
@@ -97,15 +88,29 @@ func (b *Board) MakeMove(move Move) error {
 		b.enPassantSquare = "-"
 	}
 
+	// Castling moves the rook along with the king
+	if (piece == WhiteKing || piece == BlackKing) && abs(from-to) == 2 {
+		if to > from {
+			b.squares[to-1] = b.squares[to+1]
+			b.squares[to+1] = NoPiece
+		} else {
+			b.squares[to+1] = b.squares[to-2]
+			b.squares[to-2] = NoPiece
+		}
+	}
+
 	// Make the move
 	b.squares[to] = piece
+	if move.Promotion != NoPiece {
+		b.squares[to] = move.Promotion
+	}
 	b.squares[from] = NoPiece
 
 	// Update turn
 	b.whiteToMove = !b.whiteToMove
 
 	// Update half move clock
-	if piece == WhitePawn || piece == BlackPawn || b.squares[to] != NoPiece {
+	if piece == WhitePawn || piece == BlackPawn || captured != NoPiece {
 		b.halfMoveClock = 0
 	} else {
 		b.halfMoveClock++
@@ -123,16 +128,18 @@ func (b *Board) MakeMove(move Move) error {
 	} else if piece == BlackKing {
 		b.blackKingsideCastle = false
 		b.blackQueensideCastle = false
-	} else if piece == WhiteRook {
-		if from == 0 {
+	}
+
+	// Moving a rook or capturing one on its home square ends that side's castling
+	for _, square := range []int{from, to} {
+		switch square {
+		case 0:
 			b.whiteQueensideCastle = false
-		} else if from == 7 {
+		case 7:
 			b.whiteKingsideCastle = false
-		}
-	} else if piece == BlackRook {
-		if from == 56 {
+		case 56:
 			b.blackQueensideCastle = false
-		} else if from == 63 {
+		case 63:
 			b.blackKingsideCastle = false
 		}
 	}
@@ -140,6 +147,48 @@ func (b *Board) MakeMove(move Move) error {
 	return nil
 }
 
+// ParseMove parses a move in UCI notation such as "e2e4" or "e7e8q". The promotion
+// piece takes the colour of the side promoting on the target rank.
+func ParseMove(s string) (Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, fmt.Errorf("invalid move length: %q", s)
+	}
+
+	move := Move{From: s[0:2], To: s[2:4]}
+	if squareToIndex(move.From) == -1 || squareToIndex(move.To) == -1 {
+		return Move{}, fmt.Errorf("invalid square in move: %q", s)
+	}
+
+	if len(s) == 5 {
+		char := rune(s[4])
+		switch move.To[1] {
+		case '8':
+			char = unicode.ToUpper(char)
+		case '1':
+			char = unicode.ToLower(char)
+		default:
+			return Move{}, fmt.Errorf("promotion not on the last rank: %q", s)
+		}
+
+		move.Promotion = charToPiece(char)
+		if !isWhitePromotion(move.Promotion) && !isBlackPromotion(move.Promotion) {
+			return Move{}, fmt.Errorf("invalid promotion piece: %q", s)
+		}
+	}
+
+	return move, nil
+}
+
+// isWhitePromotion returns whether a white pawn may promote to p
+func isWhitePromotion(p Piece) bool {
+	return p >= WhiteKnight && p <= WhiteQueen
+}
+
+// isBlackPromotion returns whether a black pawn may promote to p
+func isBlackPromotion(p Piece) bool {
+	return p >= BlackKnight && p <= BlackQueen
+}
+
 // Helper functions
 func squareToIndex(square string) int {
 	if len(square) != 2 {
@@ -167,4 +167,110 @@ func TestMoveString(t *testing.T) {
 			t.Errorf("Move.String() = %s; want %s", result, test.expected)
 		}
 	}
-} 
\ No newline at end of file
+}
+
+func TestParseMove(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    Move
+		expectError bool
+	}{
+		{input: "e2e4", expected: Move{From: "e2", To: "e4"}},
+		{input: "e7e8q", expected: Move{From: "e7", To: "e8", Promotion: WhiteQueen}},
+		{input: "a2a1n", expected: Move{From: "a2", To: "a1", Promotion: BlackKnight}},
+		{input: "e7e8k", expectError: true},
+		{input: "e6e7q", expectError: true},
+		{input: "e2e9", expectError: true},
+		{input: "e2", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			move, err := ParseMove(test.input)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if move != test.expected {
+				t.Errorf("ParseMove(%s) = %+v; want %+v", test.input, move, test.expected)
+			}
+			if move.String() != test.input {
+				t.Errorf("String() = %s; want %s", move.String(), test.input)
+			}
+		})
+	}
+}
+
+func TestMakeMoveBookkeeping(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		moves    []string
+		expected string
+	}{
+		{
+			name:     "Kingside castling moves the rook",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			moves:    []string{"e1g1"},
+			expected: "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1",
+		},
+		{
+			name:     "Queenside castling moves the rook",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+			moves:    []string{"e8c8"},
+			expected: "2kr3r/8/8/8/8/8/8/R3K2R w KQ - 1 2",
+		},
+		{
+			name:     "Capturing a rook removes castling rights",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			moves:    []string{"a1a8"},
+			expected: "R3k2r/8/8/8/8/8/8/4K2R b Kk - 0 1",
+		},
+		{
+			name:     "En passant capture",
+			fen:      "rnbqkbnr/pppppppp/8/4P3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2",
+			moves:    []string{"d7d5", "e5d6"},
+			expected: "rnbqkbnr/ppp1pppp/3P4/8/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 3",
+		},
+		{
+			name:     "Promotion",
+			fen:      "8/4P3/8/8/8/8/8/k6K w - - 3 40",
+			moves:    []string{"e7e8n"},
+			expected: "4N3/8/8/8/8/8/8/k6K b - - 0 40",
+		},
+		{
+			name:     "Quiet moves count towards the fifty move rule",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			moves:    []string{"g1f3", "g8f6", "f3g1"},
+			expected: "rnbqkb1r/pppppppp/5n2/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 3 2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			board := NewBoard()
+			if err := board.SetFEN(test.fen); err != nil {
+				t.Fatalf("Failed to set FEN: %v", err)
+			}
+
+			for _, s := range test.moves {
+				move, err := ParseMove(s)
+				if err != nil {
+					t.Fatalf("Failed to parse %s: %v", s, err)
+				}
+				if err := board.MakeMove(move); err != nil {
+					t.Fatalf("MakeMove(%s) failed: %v", s, err)
+				}
+			}
+
+			if fen := board.FEN(); fen != test.expected {
+				t.Errorf("FEN() = %s; want %s", fen, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package board
+
+import "fmt"
+
+// offset is a step on the board in files and ranks
+type offset struct {
+	file, rank int
+}
+
+var (
+	knightOffsets = []offset{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = []offset{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	bishopOffsets = []offset{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+	rookOffsets   = []offset{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+)
+
+// LegalMoves returns every legal move for the side to move
+func (b *Board) LegalMoves() []Move {
+	var legal []Move
+	for _, move := range b.pseudoLegalMoves() {
+		next := *b
+		if err := next.MakeMove(move); err != nil {
+			continue
+		}
+		if !next.isInCheck(b.whiteToMove) {
+			legal = append(legal, move)
+		}
+	}
+	return legal
+}
+
+// IsLegal returns whether move is legal in the current position
+func (b *Board) IsLegal(move Move) bool {
+	for _, legal := range b.LegalMoves() {
+		if legal == move {
+			return true
+		}
+	}
+	return false
+}
+
+// InCheck returns whether the side to move is in check
+func (b *Board) InCheck() bool {
+	return b.isInCheck(b.whiteToMove)
+}
+
+// PieceAt returns the piece on a square such as "e4", or NoPiece for an empty or invalid square
+func (b *Board) PieceAt(square string) Piece {
+	index := squareToIndex(square)
+	if index == -1 {
+		return NoPiece
+	}
+	return b.squares[index]
+}
+
+// isInCheck returns whether the king of the given side is attacked
+func (b *Board) isInCheck(white bool) bool {
+	king := BlackKing
+	if white {
+		king = WhiteKing
+	}
+	for square, piece := range b.squares {
+		if piece == king {
+			return b.isAttacked(square, !white)
+		}
+	}
+	return false
+}
+
+// isAttacked returns whether a piece of the given side attacks square
+func (b *Board) isAttacked(square int, byWhite bool) bool {
+	file, rank := square%8, square/8
+
+	pawn, knight, bishop, rook, queen, king := BlackPawn, BlackKnight, BlackBishop, BlackRook, BlackQueen, BlackKing
+	pawnRank := rank + 1
+	if byWhite {
+		pawn, knight, bishop, rook, queen, king = WhitePawn, WhiteKnight, WhiteBishop, WhiteRook, WhiteQueen, WhiteKing
+		pawnRank = rank - 1
+	}
+
+	for _, df := range []int{-1, 1} {
+		if p, ok := b.pieceOn(file+df, pawnRank); ok && p == pawn {
+			return true
+		}
+	}
+	for _, o := range knightOffsets {
+		if p, ok := b.pieceOn(file+o.file, rank+o.rank); ok && p == knight {
+			return true
+		}
+	}
+	for _, o := range kingOffsets {
+		if p, ok := b.pieceOn(file+o.file, rank+o.rank); ok && p == king {
+			return true
+		}
+	}
+	if b.slidingAttack(file, rank, bishopOffsets, bishop, queen) {
+		return true
+	}
+	return b.slidingAttack(file, rank, rookOffsets, rook, queen)
+}
+
+// slidingAttack returns whether the first piece along any of the directions is one of the attackers
+func (b *Board) slidingAttack(file, rank int, directions []offset, attackers ...Piece) bool {
+	for _, o := range directions {
+		for f, r := file+o.file, rank+o.rank; ; f, r = f+o.file, r+o.rank {
+			p, ok := b.pieceOn(f, r)
+			if !ok {
+				break
+			}
+			if p == NoPiece {
+				continue
+			}
+			for _, attacker := range attackers {
+				if p == attacker {
+					return true
+				}
+			}
+			break
+		}
+	}
+	return false
+}
+
+// pieceOn returns the piece at file and rank, and false if they are off the board
+func (b *Board) pieceOn(file, rank int) (Piece, bool) {
+	if file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return NoPiece, false
+	}
+	return b.squares[rank*8+file], true
+}
+
+// isOwn returns whether p belongs to the side to move
+func (b *Board) isOwn(p Piece) bool {
+	return p != NoPiece && p.IsWhitePiece() == b.whiteToMove
+}
+
+// pseudoLegalMoves returns the moves of the side to move, ignoring whether they leave
+// the king in check
+func (b *Board) pseudoLegalMoves() []Move {
+	var moves []Move
+	for square, piece := range b.squares {
+		if !b.isOwn(piece) {
+			continue
+		}
+
+		file, rank := square%8, square/8
+		switch piece {
+		case WhitePawn, BlackPawn:
+			moves = append(moves, b.pawnMoves(file, rank)...)
+		case WhiteKnight, BlackKnight:
+			moves = append(moves, b.stepMoves(file, rank, knightOffsets)...)
+		case WhiteBishop, BlackBishop:
+			moves = append(moves, b.slideMoves(file, rank, bishopOffsets)...)
+		case WhiteRook, BlackRook:
+			moves = append(moves, b.slideMoves(file, rank, rookOffsets)...)
+		case WhiteQueen, BlackQueen:
+			moves = append(moves, b.slideMoves(file, rank, bishopOffsets)...)
+			moves = append(moves, b.slideMoves(file, rank, rookOffsets)...)
+		case WhiteKing, BlackKing:
+			moves = append(moves, b.stepMoves(file, rank, kingOffsets)...)
+			moves = append(moves, b.castlingMoves()...)
+		}
+	}
+	return moves
+}
+
+// pawnMoves returns the pushes, captures and promotions of the pawn at file and rank
+func (b *Board) pawnMoves(file, rank int) []Move {
+	direction, startRank, lastRank := 1, 1, 7
+	promotions := []Piece{WhiteQueen, WhiteRook, WhiteBishop, WhiteKnight}
+	if !b.whiteToMove {
+		direction, startRank, lastRank = -1, 6, 0
+		promotions = []Piece{BlackQueen, BlackRook, BlackBishop, BlackKnight}
+	}
+
+	from := squareName(file, rank)
+	var moves []Move
+	add := func(toFile, toRank int) {
+		to := squareName(toFile, toRank)
+		if toRank != lastRank {
+			moves = append(moves, Move{From: from, To: to})
+			return
+		}
+		for _, promotion := range promotions {
+			moves = append(moves, Move{From: from, To: to, Promotion: promotion})
+		}
+	}
+
+	if p, ok := b.pieceOn(file, rank+direction); ok && p == NoPiece {
+		add(file, rank+direction)
+		if p, ok := b.pieceOn(file, rank+2*direction); rank == startRank && ok && p == NoPiece {
+			add(file, rank+2*direction)
+		}
+	}
+
+	for _, df := range []int{-1, 1} {
+		p, ok := b.pieceOn(file+df, rank+direction)
+		if !ok {
+			continue
+		}
+		if (p != NoPiece && !b.isOwn(p)) || squareName(file+df, rank+direction) == b.enPassantSquare {
+			add(file+df, rank+direction)
+		}
+	}
+
+	return moves
+}
+
+// stepMoves returns the moves of a piece moving a single step along each offset
+func (b *Board) stepMoves(file, rank int, offsets []offset) []Move {
+	from := squareName(file, rank)
+	var moves []Move
+	for _, o := range offsets {
+		if p, ok := b.pieceOn(file+o.file, rank+o.rank); ok && !b.isOwn(p) {
+			moves = append(moves, Move{From: from, To: squareName(file+o.file, rank+o.rank)})
+		}
+	}
+	return moves
+}
+
+// slideMoves returns the moves of a piece sliding along each direction until blocked
+func (b *Board) slideMoves(file, rank int, directions []offset) []Move {
+	from := squareName(file, rank)
+	var moves []Move
+	for _, o := range directions {
+		for f, r := file+o.file, rank+o.rank; ; f, r = f+o.file, r+o.rank {
+			p, ok := b.pieceOn(f, r)
+			if !ok || b.isOwn(p) {
+				break
+			}
+			moves = append(moves, Move{From: from, To: squareName(f, r)})
+			if p != NoPiece {
+				break
+			}
+		}
+	}
+	return moves
+}
+
+// castlingMoves returns the castling moves allowed by the castling rights, with the
+// squares between king and rook empty and the king not passing through check
+func (b *Board) castlingMoves() []Move {
+	rank, king, rook := 0, WhiteKing, WhiteRook
+	kingside, queenside := b.whiteKingsideCastle, b.whiteQueensideCastle
+	if !b.whiteToMove {
+		rank, king, rook = 7, BlackKing, BlackRook
+		kingside, queenside = b.blackKingsideCastle, b.blackQueensideCastle
+	}
+
+	base := rank * 8
+	if b.squares[base+4] != king || b.isAttacked(base+4, !b.whiteToMove) {
+		return nil
+	}
+
+	var moves []Move
+	if kingside && b.squares[base+7] == rook &&
+		b.squares[base+5] == NoPiece && b.squares[base+6] == NoPiece &&
+		!b.isAttacked(base+5, !b.whiteToMove) && !b.isAttacked(base+6, !b.whiteToMove) {
+		moves = append(moves, Move{From: squareName(4, rank), To: squareName(6, rank)})
+	}
+	if queenside && b.squares[base] == rook &&
+		b.squares[base+1] == NoPiece && b.squares[base+2] == NoPiece && b.squares[base+3] == NoPiece &&
+		!b.isAttacked(base+3, !b.whiteToMove) && !b.isAttacked(base+2, !b.whiteToMove) {
+		moves = append(moves, Move{From: squareName(4, rank), To: squareName(2, rank)})
+	}
+	return moves
+}
+
+// squareName returns the name of the square at file and rank, e.g. "e4"
+func squareName(file, rank int) string {
+	return fmt.Sprintf("%c%d", 'a'+file, rank+1)
+}
@@ -0,0 +1,89 @@
+package board
+
+import (
+	"testing"
+)
+
+// perft counts the leaf nodes of the legal move tree to the given depth
+func perft(b *Board, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+
+	nodes := 0
+	for _, move := range b.LegalMoves() {
+		next := *b
+		next.MakeMove(move)
+		nodes += perft(&next, depth-1)
+	}
+	return nodes
+}
+
+func TestPerft(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		depth    int
+		expected int
+	}{
+		{"Starting position", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 3, 8902},
+		{"Castling and pins", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 2, 2039},
+		{"En passant and checks", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 3, 2812},
+		{"Promotions", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 2, 264},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			board := NewBoard()
+			if err := board.SetFEN(test.fen); err != nil {
+				t.Fatalf("Failed to set FEN: %v", err)
+			}
+
+			if nodes := perft(board, test.depth); nodes != test.expected {
+				t.Errorf("perft(%d) = %d; want %d", test.depth, nodes, test.expected)
+			}
+		})
+	}
+}
+
+func TestInCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		expected bool
+		moves    int
+	}{
+		{"Starting position", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", false, 20},
+		{"Checkmate", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", true, 0},
+		{"Stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", false, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			board := NewBoard()
+			if err := board.SetFEN(test.fen); err != nil {
+				t.Fatalf("Failed to set FEN: %v", err)
+			}
+
+			if board.InCheck() != test.expected {
+				t.Errorf("InCheck() = %v; want %v", board.InCheck(), test.expected)
+			}
+			if moves := len(board.LegalMoves()); moves != test.moves {
+				t.Errorf("len(LegalMoves()) = %d; want %d", moves, test.moves)
+			}
+		})
+	}
+}
+
+func TestIsLegal(t *testing.T) {
+	board := NewBoard()
+	if !board.IsLegal(Move{From: "g1", To: "f3"}) {
+		t.Error("Expected g1f3 to be legal")
+	}
+	if board.IsLegal(Move{From: "e2", To: "e5"}) {
+		t.Error("Expected e2e5 to be illegal")
+	}
+	if board.PieceAt("e1") != WhiteKing {
+		t.Errorf("PieceAt(e1) = %v; want %v", board.PieceAt("e1"), WhiteKing)
+	}
+}
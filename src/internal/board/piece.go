@@ -29,6 +29,11 @@ func (p Piece) IsEmpty() bool {
 	return p == NoPiece
 }
 
+// String returns the FEN character of the piece, or an empty string for no piece
+func (p Piece) String() string {
+	return pieceToChar(p)
+}
+
 // isValidPiece returns whether the piece is valid
 func isValidPiece(p Piece) bool {
 	return p >= WhitePawn && p <= BlackKing
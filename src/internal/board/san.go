@@ -0,0 +1,81 @@
+package board
+
+import "strings"
+
+// SAN returns move in standard algebraic notation, e.g. "Nbd7", "exd6", "O-O" or
+// "e8=Q#". The move is expected to be legal in the current position.
+func (b *Board) SAN(move Move) string {
+	from := squareToIndex(move.From)
+	to := squareToIndex(move.To)
+	if from == -1 || to == -1 {
+		return move.String()
+	}
+	piece := b.squares[from]
+
+	var san strings.Builder
+	switch {
+	case (piece == WhiteKing || piece == BlackKing) && to-from == 2:
+		san.WriteString("O-O")
+	case (piece == WhiteKing || piece == BlackKing) && from-to == 2:
+		san.WriteString("O-O-O")
+	case piece == WhitePawn || piece == BlackPawn:
+		if move.From[0] != move.To[0] {
+			// Pawns only change file when capturing, en passant included
+			san.WriteByte(move.From[0])
+			san.WriteString("x")
+		}
+		san.WriteString(move.To)
+		if move.Promotion != NoPiece {
+			san.WriteString("=" + strings.ToUpper(pieceToChar(move.Promotion)))
+		}
+	default:
+		san.WriteString(strings.ToUpper(pieceToChar(piece)))
+		san.WriteString(b.disambiguation(move, piece))
+		if b.squares[to] != NoPiece {
+			san.WriteString("x")
+		}
+		san.WriteString(move.To)
+	}
+
+	next := *b
+	if err := next.MakeMove(move); err != nil {
+		return san.String()
+	}
+	if next.InCheck() {
+		if len(next.LegalMoves()) == 0 {
+			san.WriteString("#")
+		} else {
+			san.WriteString("+")
+		}
+	}
+	return san.String()
+}
+
+// disambiguation returns the file, rank or square needed to tell move apart from the
+// moves of other pieces of the same kind to the same square
+func (b *Board) disambiguation(move Move, piece Piece) string {
+	sameFile, sameRank, ambiguous := false, false, false
+	for _, other := range b.LegalMoves() {
+		if other.To != move.To || other.From == move.From || b.PieceAt(other.From) != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From[0] == move.From[0] {
+			sameFile = true
+		}
+		if other.From[1] == move.From[1] {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return move.From[:1]
+	case !sameRank:
+		return move.From[1:]
+	default:
+		return move.From
+	}
+}
@@ -0,0 +1,104 @@
+package board
+
+import "testing"
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		move     string
+		expected string
+	}{
+		{
+			name:     "Pawn push",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			move:     "e2e4",
+			expected: "e4",
+		},
+		{
+			name:     "Knight move",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			move:     "g1f3",
+			expected: "Nf3",
+		},
+		{
+			name:     "Pawn capture",
+			fen:      "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+			move:     "e4d5",
+			expected: "exd5",
+		},
+		{
+			name:     "En passant",
+			fen:      "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			move:     "e5f6",
+			expected: "exf6",
+		},
+		{
+			name:     "Kingside castling",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			move:     "e1g1",
+			expected: "O-O",
+		},
+		{
+			name:     "Queenside castling",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+			move:     "e8c8",
+			expected: "O-O-O",
+		},
+		{
+			name:     "File disambiguation",
+			fen:      "4k3/8/8/8/8/8/4K3/R6R w - - 0 1",
+			move:     "a1d1",
+			expected: "Rad1",
+		},
+		{
+			name:     "Rank disambiguation",
+			fen:      "4k3/R7/8/8/8/8/8/R3K3 w - - 0 1",
+			move:     "a1a4",
+			expected: "R1a4",
+		},
+		{
+			name:     "Square disambiguation",
+			fen:      "7k/2N5/8/8/8/2N1N3/8/4K3 w - - 0 1",
+			move:     "c3d5",
+			expected: "Nc3d5",
+		},
+		{
+			name:     "Piece capture with check",
+			fen:      "k6r/8/8/8/8/8/8/4K2R w K - 0 1",
+			move:     "h1h8",
+			expected: "Rxh8+",
+		},
+		{
+			name:     "Checkmate",
+			fen:      "rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2",
+			move:     "d8h4",
+			expected: "Qh4#",
+		},
+		{
+			name:     "Promotion with capture",
+			fen:      "3r3k/4P3/8/8/8/8/8/4K3 w - - 0 1",
+			move:     "e7d8q",
+			expected: "exd8=Q+",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := NewBoard()
+			if err := b.SetFEN(test.fen); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			move, err := ParseMove(test.move)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !b.IsLegal(move) {
+				t.Fatalf("Move %s is not legal in %s", test.move, test.fen)
+			}
+			if san := b.SAN(move); san != test.expected {
+				t.Errorf("SAN(%s) = %s; want %s", test.move, san, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package clock
+
+import "time"
+
+// Clock tracks the remaining time of both players under a time control. It does not
+// run by itself: the caller measures how long each move took and punches the clock.
+type Clock struct {
+	tc        TimeControl
+	remaining [2]time.Duration // Indexed by side, white first
+	moves     [2]int           // Moves each side has completed
+}
+
+// New creates a clock with both players at the start of the time control
+func New(tc TimeControl) *Clock {
+	c := &Clock{tc: tc}
+	c.remaining[0] = tc.Periods[0].Time
+	c.remaining[1] = tc.Periods[0].Time
+	return c
+}
+
+// TimeControl returns the clock's time control
+func (c *Clock) TimeControl() TimeControl {
+	return c.tc
+}
+
+// Remaining returns the time left on a player's clock
+func (c *Clock) Remaining(white bool) time.Duration {
+	return c.remaining[side(white)]
+}
+
+// MovesToGo returns the number of moves a player must make before the next time
+// control, or zero if the current period lasts for the rest of the game
+func (c *Clock) MovesToGo(white bool) int {
+	moves := c.moves[side(white)]
+	i, start := c.tc.period(moves)
+	if c.tc.Periods[i].Moves == 0 {
+		return 0
+	}
+	return start + c.tc.Periods[i].Moves - moves
+}
+
+// Available returns how long a player may think about the current move before the
+// flag falls
+func (c *Clock) Available(white bool) time.Duration {
+	available := c.remaining[side(white)]
+	if c.tc.Mode == SimpleDelay {
+		available += c.tc.Extra
+	}
+	return available
+}
+
+// Expired returns whether a player who has been thinking for elapsed has lost on time
+func (c *Clock) Expired(white bool, elapsed time.Duration) bool {
+	return elapsed > c.Available(white)
+}
+
+// Punch records a move that took elapsed, charging the player's clock and adding any
+// increment, returned delay or new period. It returns false, leaving the clock at
+// zero, if the flag fell before the move was made.
+func (c *Clock) Punch(white bool, elapsed time.Duration) bool {
+	i := side(white)
+	if c.Expired(white, elapsed) {
+		c.remaining[i] = 0
+		return false
+	}
+
+	switch c.tc.Mode {
+	case Increment:
+		c.remaining[i] += c.tc.Extra - elapsed
+	case SimpleDelay:
+		if elapsed > c.tc.Extra {
+			c.remaining[i] -= elapsed - c.tc.Extra
+		}
+	case Bronstein:
+		returned := elapsed
+		if returned > c.tc.Extra {
+			returned = c.tc.Extra
+		}
+		c.remaining[i] += returned - elapsed
+	case Hourglass:
+		c.remaining[i] -= elapsed
+		c.remaining[1-i] += elapsed
+	}
+
+	_, before := c.tc.period(c.moves[i])
+	c.moves[i]++
+	next, after := c.tc.period(c.moves[i])
+	if after != before {
+		c.remaining[i] += c.tc.Periods[next].Time
+	}
+	return true
+}
+
+// side returns the index of a player in the clock's arrays
+func side(white bool) int {
+	if white {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,130 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// move is a move punched on the clock in a test
+type move struct {
+	white   bool
+	elapsed time.Duration
+}
+
+func TestPunch(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name      string
+		tc        string
+		moves     []move
+		white     time.Duration
+		black     time.Duration
+		movesToGo int
+		flagged   bool
+	}{
+		{
+			name:  "Increment",
+			tc:    "60+2",
+			moves: []move{{true, 5 * s}, {false, 1 * s}},
+			white: 57 * s,
+			black: 61 * s,
+		},
+		{
+			name:  "Simple delay",
+			tc:    "60d3",
+			moves: []move{{true, 5 * s}, {false, 2 * s}},
+			white: 58 * s,
+			black: 60 * s,
+		},
+		{
+			name:  "Bronstein delay",
+			tc:    "60b3",
+			moves: []move{{true, 5 * s}, {false, 2 * s}},
+			white: 58 * s,
+			black: 60 * s,
+		},
+		{
+			name:  "Hourglass",
+			tc:    "h60",
+			moves: []move{{true, 10 * s}, {false, 4 * s}},
+			white: 54 * s,
+			black: 66 * s,
+		},
+		{
+			name:      "Moves per period repeats",
+			tc:        "2/60",
+			moves:     []move{{true, 10 * s}, {false, 1 * s}, {true, 10 * s}},
+			white:     100 * s,
+			black:     59 * s,
+			movesToGo: 2,
+		},
+		{
+			name:      "Next period",
+			tc:        "2/60:30",
+			moves:     []move{{true, 10 * s}, {false, 1 * s}, {true, 10 * s}},
+			white:     70 * s,
+			black:     59 * s,
+			movesToGo: 0,
+		},
+		{
+			name:    "Flag",
+			tc:      "10+5",
+			moves:   []move{{true, 11 * s}},
+			white:   0,
+			black:   10 * s,
+			flagged: true,
+		},
+		{
+			name:  "Simple delay saves the flag",
+			tc:    "10d5",
+			moves: []move{{true, 14 * s}},
+			white: 1 * s,
+			black: 10 * s,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, err := Parse(tt.tc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			c := New(tc)
+			flagged := false
+			for _, m := range tt.moves {
+				if !c.Punch(m.white, m.elapsed) {
+					flagged = true
+				}
+			}
+
+			if flagged != tt.flagged {
+				t.Errorf("Expected flagged %v, got %v", tt.flagged, flagged)
+			}
+			if c.Remaining(true) != tt.white || c.Remaining(false) != tt.black {
+				t.Errorf("Expected %v/%v remaining, got %v/%v", tt.white, tt.black, c.Remaining(true), c.Remaining(false))
+			}
+			if got := c.MovesToGo(true); got != tt.movesToGo {
+				t.Errorf("Expected %d moves to go, got %d", tt.movesToGo, got)
+			}
+		})
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	tc, err := Parse("10d5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	c := New(tc)
+	if got := c.Available(true); got != 15*time.Second {
+		t.Errorf("Expected 15s available with the delay, got %v", got)
+	}
+	if !c.Expired(true, 16*time.Second) {
+		t.Error("Expected the clock to expire after 16s")
+	}
+	if c.Expired(true, 15*time.Second) {
+		t.Error("Expected the clock not to expire after 15s")
+	}
+}
@@ -0,0 +1,178 @@
+// Package clock implements chess clocks for the time controls used in engine and
+// over-the-board play.
+package clock
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mode is how a clock treats the time a player spends on a move
+type Mode int
+
+const (
+	// Increment adds the increment after every move (Fischer)
+	Increment Mode = iota
+	// SimpleDelay does not start the clock until the delay has passed (US delay)
+	SimpleDelay
+	// Bronstein gives back the time spent on a move, up to the delay
+	Bronstein
+	// Hourglass moves the time spent on a move to the opponent's clock
+	Hourglass
+)
+
+// Period is a stage of a time control: Time to play Moves moves, or the rest of the
+// game when Moves is zero
+type Period struct {
+	Moves int
+	Time  time.Duration
+}
+
+// TimeControl describes how much time each player has
+type TimeControl struct {
+	Periods []Period // The last period repeats if it has a move count
+	Mode    Mode
+	Extra   time.Duration // Increment or delay, depending on the mode
+}
+
+// Parse parses a time control. Times are in seconds and may be fractional:
+//
+//	300+2          5 minutes with a 2 second increment
+//	300d2          5 minutes with a 2 second simple delay
+//	300b2          5 minutes with a 2 second Bronstein delay
+//	40/5400+30     40 moves in 90 minutes, repeating, with a 30 second increment
+//	40/5400:900+30 40 moves in 90 minutes, then 15 minutes for the rest of the game
+//	h60            hourglass with 1 minute
+func Parse(s string) (TimeControl, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return TimeControl{}, fmt.Errorf("empty time control")
+	}
+
+	var tc TimeControl
+	if strings.HasPrefix(s, "h") {
+		seconds, err := parseSeconds(s[1:])
+		if err != nil {
+			return TimeControl{}, fmt.Errorf("invalid hourglass time control %q: %v", s, err)
+		}
+		if seconds <= 0 {
+			return TimeControl{}, fmt.Errorf("invalid hourglass time control %q: no time", s)
+		}
+		tc.Mode = Hourglass
+		tc.Periods = []Period{{Time: seconds}}
+		return tc, nil
+	}
+
+	periods := s
+	if i := strings.IndexAny(s, "+db"); i != -1 {
+		switch s[i] {
+		case '+':
+			tc.Mode = Increment
+		case 'd':
+			tc.Mode = SimpleDelay
+		case 'b':
+			tc.Mode = Bronstein
+		}
+
+		extra, err := parseSeconds(s[i+1:])
+		if err != nil {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: %v", s, err)
+		}
+		tc.Extra = extra
+		periods = s[:i]
+	}
+
+	for _, text := range strings.Split(periods, ":") {
+		var period Period
+		if moves, seconds, ok := strings.Cut(text, "/"); ok {
+			n, err := strconv.Atoi(moves)
+			if err != nil || n <= 0 {
+				return TimeControl{}, fmt.Errorf("invalid move count in time control %q", s)
+			}
+			period.Moves = n
+			text = seconds
+		}
+
+		seconds, err := parseSeconds(text)
+		if err != nil {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: %v", s, err)
+		}
+		if seconds <= 0 {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: period without time", s)
+		}
+		period.Time = seconds
+		tc.Periods = append(tc.Periods, period)
+	}
+
+	for _, period := range tc.Periods[:len(tc.Periods)-1] {
+		if period.Moves == 0 {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: only the last period may be for the rest of the game", s)
+		}
+	}
+	return tc, nil
+}
+
+// String formats the time control the way Parse reads it
+func (tc TimeControl) String() string {
+	if tc.Mode == Hourglass {
+		return "h" + formatSeconds(tc.Periods[0].Time)
+	}
+
+	var periods []string
+	for _, period := range tc.Periods {
+		text := formatSeconds(period.Time)
+		if period.Moves > 0 {
+			text = fmt.Sprintf("%d/%s", period.Moves, text)
+		}
+		periods = append(periods, text)
+	}
+
+	s := strings.Join(periods, ":")
+	if tc.Extra > 0 {
+		s += map[Mode]string{Increment: "+", SimpleDelay: "d", Bronstein: "b"}[tc.Mode] + formatSeconds(tc.Extra)
+	}
+	return s
+}
+
+// period returns the period a player is in after playing moves moves, and the number
+// of moves played before it started
+func (tc TimeControl) period(moves int) (int, int) {
+	start := 0
+	for i, period := range tc.Periods {
+		if period.Moves == 0 || moves < start+period.Moves {
+			return i, start
+		}
+		if i == len(tc.Periods)-1 {
+			// The last period repeats
+			return i, start + (moves-start)/period.Moves*period.Moves
+		}
+		start += period.Moves
+	}
+	return len(tc.Periods) - 1, start
+}
+
+// parseSeconds parses a non-negative, finite number of seconds
+func parseSeconds(s string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(seconds) {
+		return 0, fmt.Errorf("invalid seconds %q", s)
+	}
+	if seconds < 0 {
+		return 0, fmt.Errorf("negative seconds %q", s)
+	}
+	if seconds > maxSeconds {
+		return 0, fmt.Errorf("too many seconds %q", s)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// maxSeconds is the longest time a duration holds, about 292 years
+var maxSeconds = time.Duration(math.MaxInt64).Seconds()
+
+// formatSeconds formats d in seconds without trailing zeros
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
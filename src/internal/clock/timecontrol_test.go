@@ -0,0 +1,93 @@
+package clock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    TimeControl
+		expectError bool
+	}{
+		{
+			name:     "Increment",
+			input:    "300+2",
+			expected: TimeControl{Periods: []Period{{Time: 300 * time.Second}}, Mode: Increment, Extra: 2 * time.Second},
+		},
+		{
+			name:     "Sudden death",
+			input:    "60",
+			expected: TimeControl{Periods: []Period{{Time: time.Minute}}},
+		},
+		{
+			name:     "Fractional increment",
+			input:    "10+0.1",
+			expected: TimeControl{Periods: []Period{{Time: 10 * time.Second}}, Mode: Increment, Extra: 100 * time.Millisecond},
+		},
+		{
+			name:     "Simple delay",
+			input:    "300d5",
+			expected: TimeControl{Periods: []Period{{Time: 300 * time.Second}}, Mode: SimpleDelay, Extra: 5 * time.Second},
+		},
+		{
+			name:     "Bronstein delay",
+			input:    "300b5",
+			expected: TimeControl{Periods: []Period{{Time: 300 * time.Second}}, Mode: Bronstein, Extra: 5 * time.Second},
+		},
+		{
+			name:     "Moves per period",
+			input:    "40/5400+30",
+			expected: TimeControl{Periods: []Period{{Moves: 40, Time: 5400 * time.Second}}, Mode: Increment, Extra: 30 * time.Second},
+		},
+		{
+			name:  "Several periods",
+			input: "40/5400:20/1800:900",
+			expected: TimeControl{Periods: []Period{
+				{Moves: 40, Time: 5400 * time.Second},
+				{Moves: 20, Time: 1800 * time.Second},
+				{Time: 900 * time.Second},
+			}},
+		},
+		{
+			name:     "Hourglass",
+			input:    "h60",
+			expected: TimeControl{Periods: []Period{{Time: time.Minute}}, Mode: Hourglass},
+		},
+		{name: "Empty", input: "", expectError: true},
+		{name: "Not a number", input: "five+2", expectError: true},
+		{name: "Bad move count", input: "0/300", expectError: true},
+		{name: "No time", input: "0+2", expectError: true},
+		{name: "Sudden death before a period", input: "300:40/300", expectError: true},
+		{name: "Bad hourglass", input: "hx", expectError: true},
+		{name: "Hourglass without time", input: "h0", expectError: true},
+		{name: "Infinite time", input: "inf+2", expectError: true},
+		{name: "Infinite increment", input: "300+Inf", expectError: true},
+		{name: "Not a number of seconds", input: "NaN", expectError: true},
+		{name: "Time overflows", input: "1e300", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, err := Parse(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tc, tt.expected) {
+				t.Errorf("Parse(%q) = %+v; want %+v", tt.input, tc, tt.expected)
+			}
+			if s := tc.String(); s != tt.input {
+				t.Errorf("String() = %q; want %q", s, tt.input)
+			}
+		})
+	}
+}
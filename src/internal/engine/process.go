@@ -0,0 +1,176 @@
+// Package engine runs chess engine processes for the protocol adapters. It owns the
+// process and the single reader of its output, so the UCI and XBoard handlers only
+// deal with the commands they send and the lines they read back.
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/transcript"
+)
+
+// Config describes the engine process to start
+type Config struct {
+	Path string   // Engine binary
+	Args []string // Extra command line arguments
+
+	Transcript *transcript.Transcript // Optional record of every line sent and received
+	InstanceID string                 // Names the engine in the transcript, defaults to its process ID
+}
+
+// Process is one running engine instance
+type Process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	lines  chan string   // Lines read from stdout by the reader goroutine, closed once the process is reaped
+	exited chan struct{} // Closed once the process has exited and been reaped
+	err    error         // Exit status, valid once exited is closed
+
+	transcript *transcript.Transcript
+	instance   string
+}
+
+// Start starts the engine binary described by config
+func Start(config Config) (*Process, error) {
+	cmd := exec.Command(config.Path, config.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %v", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %v", config.Path, err)
+	}
+
+	p := &Process{
+		cmd:        cmd,
+		stdin:      stdin,
+		stdout:     stdout,
+		lines:      make(chan string, 256),
+		exited:     make(chan struct{}),
+		transcript: config.Transcript,
+		instance:   config.InstanceID,
+	}
+	if p.instance == "" {
+		p.instance = fmt.Sprintf("pid %d", cmd.Process.Pid)
+	}
+	p.transcript.Note(p.instance, fmt.Sprintf("started %s (pid %d)", config.Path, cmd.Process.Pid))
+	go p.readLoop()
+
+	return p, nil
+}
+
+// readLoop is the only reader of the engine's stdout; it forwards every line to p.lines
+// and reaps the process once its output ends
+func (p *Process) readLoop() {
+	defer close(p.lines)
+
+	scanner := bufio.NewScanner(p.stdout)
+	for scanner.Scan() {
+		p.transcript.Received(p.instance, scanner.Text())
+		p.lines <- scanner.Text()
+	}
+
+	p.err = p.cmd.Wait()
+	if p.err != nil {
+		p.transcript.Note(p.instance, fmt.Sprintf("exited: %v", p.err))
+	} else {
+		p.transcript.Note(p.instance, "exited")
+	}
+	close(p.exited)
+}
+
+// Lines returns the engine's output, one line at a time. The channel is closed once the
+// process has exited and been reaped.
+func (p *Process) Lines() <-chan string {
+	return p.lines
+}
+
+// Exited returns a channel that is closed once the process has exited and been reaped
+func (p *Process) Exited() <-chan struct{} {
+	return p.exited
+}
+
+// Err returns the exit status of an exited process, nil for a clean exit
+func (p *Process) Err() error {
+	select {
+	case <-p.exited:
+		return p.err
+	default:
+		return nil
+	}
+}
+
+// Pid returns the operating system process ID
+func (p *Process) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+// Write sends a single command line to the process
+func (p *Process) Write(command string) error {
+	p.transcript.Sent(p.instance, command)
+	_, err := fmt.Fprintln(p.stdin, command)
+	return err
+}
+
+// HasExited returns whether the process has exited
+func (p *Process) HasExited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// Kill kills the process and waits until it has been reaped
+func (p *Process) Kill() {
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	p.stdin.Close()
+
+	// Drain output so the reader can reach EOF and reap the process
+	for range p.lines {
+	}
+}
+
+// Shutdown sends the quit commands, closes the engine's input and waits until the
+// process has been reaped, killing it if it is still running after timeout
+func (p *Process) Shutdown(timeout time.Duration, quit ...string) error {
+	if p.HasExited() {
+		p.Kill()
+		return nil
+	}
+
+	// Errors are ignored: the engine may already be gone
+	for _, command := range quit {
+		p.Write(command)
+	}
+	p.stdin.Close()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case _, ok := <-p.lines:
+			if !ok {
+				return nil
+			}
+		case <-timer.C:
+			p.Kill()
+			return fmt.Errorf("engine did not quit within %v and was killed", timeout)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/transcript"
+)
+
+// shell returns a config running script as the engine
+func shell(script string) Config {
+	return Config{Path: "sh", Args: []string{"-c", script}}
+}
+
+func TestProcessLines(t *testing.T) {
+	var out strings.Builder
+	config := shell(`read cmd; echo "got $cmd"; read cmd; exit 0`)
+	config.Transcript = transcript.New(&out)
+	config.InstanceID = "engine"
+
+	p, err := Start(config)
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	if err := p.Write("hello"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case line := <-p.Lines():
+		if line != "got hello" {
+			t.Errorf("Expected %q, got %q", "got hello", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No reply from the process")
+	}
+
+	if err := p.Shutdown(time.Second, "quit"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !p.HasExited() {
+		t.Error("Expected the process to be reaped")
+	}
+	if err := p.Err(); err != nil {
+		t.Errorf("Expected a clean exit, got %v", err)
+	}
+
+	for _, expected := range []string{"[engine] # started sh", "[engine] > hello", "[engine] < got hello", "[engine] > quit", "[engine] # exited"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Transcript is missing %q:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestProcessExitStatus(t *testing.T) {
+	p, err := Start(shell(`exit 3`))
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	select {
+	case <-p.Exited():
+	case <-time.After(time.Second):
+		t.Fatal("Process was not reaped")
+	}
+	if _, ok := <-p.Lines(); ok {
+		t.Error("Expected the lines to be closed")
+	}
+	if p.Err() == nil {
+		t.Error("Expected the exit status of the failed process")
+	}
+
+	// Shutting down an exited process does nothing
+	if err := p.Shutdown(time.Second, "quit"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestProcessShutdownKillsHungProcess(t *testing.T) {
+	p, err := Start(shell(`exec sleep 60`))
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	start := time.Now()
+	if err := p.Shutdown(100*time.Millisecond, "quit"); err == nil {
+		t.Error("Expected error for a process that had to be killed")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Shutdown took %v", elapsed)
+	}
+	if !p.HasExited() {
+		t.Error("Expected the killed process to be reaped")
+	}
+}
+
+func TestStartMissingBinary(t *testing.T) {
+	if _, err := Start(Config{Path: "/nonexistent/engine"}); err == nil {
+		t.Error("Expected error but got none")
+	}
+}
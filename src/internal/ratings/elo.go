@@ -0,0 +1,137 @@
+// Package ratings estimates playing strength from game results: the Elo difference
+// of a match with its error margin, ratings for a whole pool of players fitted by
+// maximum likelihood, and sequential tests of whether one player is stronger.
+package ratings
+
+import (
+	"fmt"
+	"math"
+)
+
+// z95 is the standard normal quantile of a two-sided 95% interval
+const z95 = 1.959963984540054
+
+// ErrUnbounded is returned for a match won or lost without exception, whose Elo
+// difference has no finite estimate
+var ErrUnbounded = fmt.Errorf("elo difference is unbounded for a score of 0%% or 100%%")
+
+// Record is a player's wins, draws and losses against an opponent
+type Record struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Games returns the number of games in the record
+func (r Record) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Score returns the fraction of points scored
+func (r Record) Score() float64 {
+	return (float64(r.Wins) + float64(r.Draws)/2) / float64(r.Games())
+}
+
+// Difference is the Elo difference between a player and an opponent estimated from
+// their record
+type Difference struct {
+	Elo       float64 // Positive when the player is stronger
+	Error     float64 // Half-width of the 95% confidence interval
+	LOS       float64 // Likelihood of superiority: the probability that Elo is positive
+	DrawRatio float64
+	Score     float64
+}
+
+// EloDifference estimates the Elo difference from a record. The error margin follows
+// from the variance of the score per game, so it accounts for the draw rate.
+func EloDifference(r Record) (Difference, error) {
+	n := float64(r.Games())
+	if n == 0 {
+		return Difference{}, fmt.Errorf("no games")
+	}
+
+	score := r.Score()
+	d := Difference{
+		Score:     score,
+		DrawRatio: float64(r.Draws) / n,
+		LOS:       los(r),
+	}
+	if r.Wins+r.Draws == 0 || r.Losses+r.Draws == 0 {
+		return d, ErrUnbounded
+	}
+
+	variance := (float64(r.Wins)*math.Pow(1-score, 2) +
+		float64(r.Draws)*math.Pow(0.5-score, 2) +
+		float64(r.Losses)*math.Pow(score, 2)) / n
+	d.Elo, d.Error = eloInterval(score, math.Sqrt(variance/n))
+	return d, nil
+}
+
+// eloInterval returns the Elo difference for a score and the half-width of its 95%
+// confidence interval given the score's standard error
+func eloInterval(score, stderr float64) (elo, margin float64) {
+	deviation := z95 * stderr
+	high := scoreToElo(math.Min(score+deviation, 1-1e-9))
+	low := scoreToElo(math.Max(score-deviation, 1e-9))
+	return scoreToElo(score), (high - low) / 2
+}
+
+// los returns the likelihood of superiority, which depends only on wins and losses
+func los(r Record) float64 {
+	decisive := float64(r.Wins + r.Losses)
+	if decisive == 0 {
+		return 0.5
+	}
+	return 0.5 * (1 + math.Erf(float64(r.Wins-r.Losses)/math.Sqrt(2*decisive)))
+}
+
+// scoreToElo returns the Elo difference at which the expected score is score
+func scoreToElo(score float64) float64 {
+	return -400 * math.Log10(1/score-1)
+}
+
+// expectedScore returns the logistic probability used throughout: the expected score
+// of a player rated elo points above the opponent
+func expectedScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// Match is a player's record against one opponent
+type Match struct {
+	Player   string
+	Opponent string
+	Record
+}
+
+// Matches returns the record of every pair of players who met, in the order the
+// pairs first played. The player of each pair is the one who was white first.
+func Matches(games []Game) []Match {
+	var matches []Match
+	index := map[[2]string]int{}
+	for _, g := range games {
+		i, ok := index[[2]string{g.White, g.Black}]
+		flipped := false
+		if !ok {
+			i, flipped = index[[2]string{g.Black, g.White}]
+			if !flipped {
+				i = len(matches)
+				index[[2]string{g.White, g.Black}] = i
+				matches = append(matches, Match{Player: g.White, Opponent: g.Black})
+			}
+		}
+
+		win, loss := &matches[i].Wins, &matches[i].Losses
+		if flipped {
+			win, loss = loss, win
+		}
+		switch g.Result {
+		case WhiteWins:
+			*win++
+		case BlackWins:
+			*loss++
+		case Draw:
+			matches[i].Draws++
+		}
+	}
+	return matches
+}
@@ -0,0 +1,84 @@
+package ratings
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEloDifference(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      Record
+		elo         float64
+		err         float64
+		los         float64
+		expectError bool
+	}{
+		{name: "Even", record: Record{Wins: 10, Draws: 20, Losses: 10}, elo: 0, err: 77.4, los: 0.5},
+		{name: "Stronger", record: Record{Wins: 60, Draws: 20, Losses: 20}, elo: 147.2, err: 66.0, los: 1},
+		{name: "Weaker", record: Record{Wins: 20, Draws: 20, Losses: 60}, elo: -147.2, err: 66.0, los: 0},
+		{name: "Three to one", record: Record{Wins: 30, Losses: 10}, elo: 190.8, err: 135.6, los: 0.9992},
+		{name: "No games", expectError: true},
+		{name: "All wins", record: Record{Wins: 5}, expectError: true},
+		{name: "All losses", record: Record{Losses: 5}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := EloDifference(test.record)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if math.Abs(d.Elo-test.elo) > 0.1 {
+				t.Errorf("Expected Elo %.1f, got %.1f", test.elo, d.Elo)
+			}
+			if math.Abs(d.Error-test.err) > 0.1 {
+				t.Errorf("Expected error margin %.1f, got %.1f", test.err, d.Error)
+			}
+			if math.Abs(d.LOS-test.los) > 0.001 {
+				t.Errorf("Expected LOS %.4f, got %.4f", test.los, d.LOS)
+			}
+		})
+	}
+}
+
+func TestEloDifferenceUnbounded(t *testing.T) {
+	d, err := EloDifference(Record{Wins: 4})
+	if err != ErrUnbounded {
+		t.Fatalf("Expected ErrUnbounded, got %v", err)
+	}
+	if d.Score != 1 || d.LOS < 0.97 {
+		t.Errorf("Expected the score and LOS of a clean sweep, got %+v", d)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	games := []Game{
+		{White: "A", Black: "B", Result: WhiteWins},
+		{White: "B", Black: "A", Result: WhiteWins},
+		{White: "B", Black: "A", Result: Draw},
+		{White: "C", Black: "A", Result: BlackWins},
+		{White: "A", Black: "C", Result: WhiteWins},
+	}
+
+	expected := []Match{
+		{Player: "A", Opponent: "B", Record: Record{Wins: 1, Draws: 1, Losses: 1}},
+		{Player: "C", Opponent: "A", Record: Record{Losses: 2}},
+	}
+	matches := Matches(games)
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %d matches, got %+v", len(expected), matches)
+	}
+	for i := range expected {
+		if matches[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], matches[i])
+		}
+	}
+}
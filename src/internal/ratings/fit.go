@@ -0,0 +1,400 @@
+package ratings
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Game results
+const (
+	WhiteWins = "1-0"
+	BlackWins = "0-1"
+	Draw      = "1/2-1/2"
+)
+
+// Game is a decided game between two players
+type Game struct {
+	White  string
+	Black  string
+	Result string
+}
+
+// Options control the maximum-likelihood fit
+type Options struct {
+	// Prior is the number of virtual draws each player is given against an opponent
+	// rated at the pool average, which keeps perfect scores finite. BayesElo uses 2.
+	Prior float64
+	// Anchor names a player whose rating is fixed at AnchorElo; without one the
+	// ratings average AnchorElo
+	Anchor    string
+	AnchorElo float64
+}
+
+// Rating is a player's fitted rating
+type Rating struct {
+	Player string
+	Elo    float64
+	Error  float64 // Half-width of the 95% confidence interval, relative to the pool average
+	Games  int
+	Points float64
+}
+
+// Fit is the result of fitting ratings to a pool's games
+type Fit struct {
+	Ratings        []Rating // Best first
+	DrawElo        float64  // How much closer two players must be for a draw to be likely
+	WhiteAdvantage float64  // Elo worth of having the white pieces
+}
+
+// MaximumLikelihood fits ratings to games the way BayesElo does. The probability of a
+// win by white is expectedScore(white - black + advantage - drawElo), a loss is the
+// mirror image and a draw takes the rest; ratings, the draw Elo and the white
+// advantage are chosen together to make the games most likely.
+func MaximumLikelihood(games []Game, opts Options) (Fit, error) {
+	m, err := newModel(games, opts.Prior)
+	if err != nil {
+		return Fit{}, err
+	}
+
+	if _, ok := m.index[opts.Anchor]; opts.Anchor != "" && !ok {
+		return Fit{}, fmt.Errorf("anchor %q played no games", opts.Anchor)
+	}
+
+	fit, err := m.fit(opts)
+	if err == errUndetermined {
+		// Colour and strength cannot be told apart when, say, each player only had one
+		// colour, so fall back to assuming white has no advantage
+		m.fixAdvantage = true
+		fit, err = m.fit(opts)
+	}
+	return fit, err
+}
+
+// fit finds the parameters of the model that make the games most likely
+func (m *model) fit(opts Options) (Fit, error) {
+	params := m.initial()
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		step, ok := m.newtonStep(params)
+		if !ok {
+			break
+		}
+		done := true
+		for _, s := range step {
+			if math.Abs(s) > tolerance {
+				done = false
+			}
+		}
+		params = m.lineSearch(params, step)
+		if done {
+			break
+		}
+	}
+
+	covariance, ok := invert(negate(m.hessian(params)))
+	if !ok {
+		return Fit{}, errUndetermined
+	}
+
+	ratings := m.ratings(params)
+	shift := opts.AnchorElo
+	if opts.Anchor != "" {
+		shift -= ratings[m.index[opts.Anchor]]
+	}
+
+	fit := Fit{
+		DrawElo:        params[len(params)-1],
+		WhiteAdvantage: params[len(params)-2],
+	}
+	for i, player := range m.players {
+		fit.Ratings = append(fit.Ratings, Rating{
+			Player: player,
+			Elo:    ratings[i] + shift,
+			Error:  z95 * math.Sqrt(m.ratingVariance(covariance, i)),
+			Games:  m.games[i],
+			Points: m.points[i],
+		})
+	}
+	sort.SliceStable(fit.Ratings, func(i, j int) bool { return fit.Ratings[i].Elo > fit.Ratings[j].Elo })
+	return fit, nil
+}
+
+// errUndetermined is returned when the games leave some parameter free, such as the
+// rating of a player who won every game with no prior to hold it back
+var errUndetermined = fmt.Errorf("ratings are not determined by the games")
+
+const (
+	maxIterations = 100
+	tolerance     = 1e-6 // Elo
+	hessianStep   = 1e-2 // Elo
+	maxDrawElo    = 1000
+)
+
+// model is the likelihood of a pool's games. Its parameters are the ratings of all
+// players but the last, whose rating makes them average zero, then the white
+// advantage and the draw Elo.
+type model struct {
+	players      []string
+	index        map[string]int
+	results      []modelGame
+	prior        float64
+	fixAdvantage bool // Hold the white advantage at 0
+	games        []int
+	points       []float64
+}
+
+// modelGame is a game between players given by index
+type modelGame struct {
+	white, black int
+	result       string
+}
+
+func newModel(games []Game, prior float64) (*model, error) {
+	m := &model{index: map[string]int{}, prior: prior}
+	add := func(player string) int {
+		i, ok := m.index[player]
+		if !ok {
+			i = len(m.players)
+			m.index[player] = i
+			m.players = append(m.players, player)
+			m.games = append(m.games, 0)
+			m.points = append(m.points, 0)
+		}
+		return i
+	}
+
+	for _, g := range games {
+		if g.White == g.Black {
+			return nil, fmt.Errorf("%s cannot play against itself", g.White)
+		}
+		white, black := add(g.White), add(g.Black)
+		switch g.Result {
+		case WhiteWins:
+			m.points[white]++
+		case BlackWins:
+			m.points[black]++
+		case Draw:
+			m.points[white] += 0.5
+			m.points[black] += 0.5
+		default:
+			return nil, fmt.Errorf("invalid result %q", g.Result)
+		}
+		m.games[white]++
+		m.games[black]++
+		m.results = append(m.results, modelGame{white: white, black: black, result: g.Result})
+	}
+
+	if len(m.players) < 2 {
+		return nil, fmt.Errorf("ratings need games between at least 2 players")
+	}
+	return m, nil
+}
+
+// initial returns the starting parameters: everyone equal, no white advantage and a
+// moderate draw Elo
+func (m *model) initial() []float64 {
+	params := make([]float64, len(m.players)+1)
+	params[len(params)-1] = 100
+	return params
+}
+
+// ratings returns the rating of every player
+func (m *model) ratings(params []float64) []float64 {
+	n := len(m.players)
+	ratings := make([]float64, n)
+	copy(ratings, params[:n-1])
+	for _, r := range params[:n-1] {
+		ratings[n-1] -= r
+	}
+	return ratings
+}
+
+// ratingVariance returns the variance of player i's rating given the covariance of
+// the parameters; the last player's rating is minus the sum of the others
+func (m *model) ratingVariance(covariance [][]float64, i int) float64 {
+	n := len(m.players)
+	if i < n-1 {
+		return covariance[i][i]
+	}
+	variance := 0.0
+	for a := 0; a < n-1; a++ {
+		for b := 0; b < n-1; b++ {
+			variance += covariance[a][b]
+		}
+	}
+	return variance
+}
+
+// logLikelihood returns the log-likelihood of the games and the prior draws
+func (m *model) logLikelihood(params []float64) float64 {
+	ratings := m.ratings(params)
+	advantage, drawElo := params[len(params)-2], params[len(params)-1]
+
+	ll := 0.0
+	for _, g := range m.results {
+		win, loss, draw := probabilities(ratings[g.white]-ratings[g.black]+advantage, drawElo)
+		switch g.result {
+		case WhiteWins:
+			ll += math.Log(win)
+		case BlackWins:
+			ll += math.Log(loss)
+		default:
+			ll += math.Log(draw)
+		}
+	}
+
+	if m.prior > 0 {
+		for _, r := range ratings {
+			_, _, draw := probabilities(r, drawElo)
+			ll += m.prior * math.Log(draw)
+		}
+	}
+	return ll
+}
+
+// probabilities returns the chances of a win, loss and draw for a player with an edge
+// of elo points
+func probabilities(elo, drawElo float64) (win, loss, draw float64) {
+	win = expectedScore(elo - drawElo)
+	loss = expectedScore(-elo - drawElo)
+	draw = math.Max(1-win-loss, 1e-300)
+	return win, loss, draw
+}
+
+// gradient returns the gradient of the log-likelihood
+func (m *model) gradient(params []float64) []float64 {
+	n := len(m.players)
+	ratings := m.ratings(params)
+	advantage, drawElo := params[len(params)-2], params[len(params)-1]
+
+	// Derivatives in every player's rating, the edge of white and the draw Elo
+	byRating := make([]float64, n)
+	byAdvantage, byDrawElo := 0.0, 0.0
+	for _, g := range m.results {
+		dx, dd := slopes(ratings[g.white]-ratings[g.black]+advantage, drawElo, g.result)
+		byRating[g.white] += dx
+		byRating[g.black] -= dx
+		byAdvantage += dx
+		byDrawElo += dd
+	}
+	if m.prior > 0 {
+		for i, r := range ratings {
+			dx, dd := slopes(r, drawElo, Draw)
+			byRating[i] += m.prior * dx
+			byDrawElo += m.prior * dd
+		}
+	}
+
+	g := make([]float64, len(params))
+	for i := 0; i < n-1; i++ {
+		g[i] = byRating[i] - byRating[n-1]
+	}
+	if !m.fixAdvantage {
+		g[len(g)-2] = byAdvantage
+	}
+	g[len(g)-1] = byDrawElo
+	return g
+}
+
+// slopes returns the derivatives of the log-probability of a result in the edge of
+// white and in the draw Elo
+func slopes(elo, drawElo float64, result string) (dx, dd float64) {
+	const c = math.Ln10 / 400
+	win, loss, draw := probabilities(elo, drawElo)
+	switch result {
+	case WhiteWins:
+		return c * (1 - win), -c * (1 - win)
+	case BlackWins:
+		return -c * (1 - loss), -c * (1 - loss)
+	default:
+		dWin, dLoss := c*win*(1-win), c*loss*(1-loss)
+		return -(dWin - dLoss) / draw, (dWin + dLoss) / draw
+	}
+}
+
+// hessian returns the matrix of second derivatives of the log-likelihood
+func (m *model) hessian(params []float64) [][]float64 {
+	h := make([][]float64, len(params))
+	for i := range params {
+		up := append([]float64(nil), params...)
+		down := append([]float64(nil), params...)
+		up[i] += hessianStep
+		down[i] -= hessianStep
+		gUp, gDown := m.gradient(up), m.gradient(down)
+
+		h[i] = make([]float64, len(params))
+		for j := range params {
+			h[i][j] = (gUp[j] - gDown[j]) / (2 * hessianStep)
+		}
+	}
+
+	// Average out the asymmetry left by the differences
+	for i := range h {
+		for j := 0; j < i; j++ {
+			v := (h[i][j] + h[j][i]) / 2
+			h[i][j], h[j][i] = v, v
+		}
+	}
+
+	// A fixed white advantage is left out, with a unit curvature to keep h invertible
+	if m.fixAdvantage {
+		a := len(params) - 2
+		for i := range h {
+			h[i][a], h[a][i] = 0, 0
+		}
+		h[a][a] = -1
+	}
+	return h
+}
+
+// newtonStep returns the Newton step towards the maximum, or a gradient step where
+// the likelihood is not concave. It returns false at a maximum.
+func (m *model) newtonStep(params []float64) ([]float64, bool) {
+	g := m.gradient(params)
+	flat := true
+	for _, v := range g {
+		if math.Abs(v) > 1e-9 {
+			flat = false
+		}
+	}
+	if flat {
+		return nil, false
+	}
+
+	if inverse, ok := invert(negate(m.hessian(params))); ok {
+		step := multiply(inverse, g)
+		ascent := 0.0
+		for i := range g {
+			ascent += g[i] * step[i]
+		}
+		if ascent > 0 {
+			return step, true
+		}
+	}
+
+	step := make([]float64, len(g))
+	for i := range g {
+		step[i] = 100 * g[i]
+	}
+	return step, true
+}
+
+// lineSearch returns params moved along step, halving the step until the likelihood
+// improves
+func (m *model) lineSearch(params, step []float64) []float64 {
+	current := m.logLikelihood(params)
+	scale := 1.0
+	for attempt := 0; attempt < 40; attempt++ {
+		next := make([]float64, len(params))
+		for i := range params {
+			next[i] = params[i] + scale*step[i]
+		}
+		next[len(next)-1] = math.Min(math.Max(next[len(next)-1], 0), maxDrawElo)
+
+		if m.logLikelihood(next) >= current {
+			return next
+		}
+		scale /= 2
+	}
+	return params
+}
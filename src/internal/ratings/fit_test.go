@@ -0,0 +1,201 @@
+package ratings
+
+import (
+	"math"
+	"testing"
+)
+
+// match returns games between a and b, alternating colours, with a scoring the given
+// wins, draws and losses
+func match(a, b string, wins, draws, losses int) []Game {
+	var games []Game
+	add := func(n int, aResult string) {
+		for i := 0; i < n; i++ {
+			white, black, result := a, b, aResult
+			if len(games)%2 == 1 {
+				white, black = b, a
+				switch aResult {
+				case WhiteWins:
+					result = BlackWins
+				case BlackWins:
+					result = WhiteWins
+				}
+			}
+			games = append(games, Game{White: white, Black: black, Result: result})
+		}
+	}
+	add(wins, WhiteWins)
+	add(draws, Draw)
+	add(losses, BlackWins)
+	return games
+}
+
+func TestMaximumLikelihood(t *testing.T) {
+	var pool []Game
+	pool = append(pool, match("A", "B", 30, 40, 10)...)
+	pool = append(pool, match("B", "C", 30, 40, 10)...)
+	pool = append(pool, match("A", "C", 50, 25, 5)...)
+
+	fit, err := MaximumLikelihood(pool, Options{Prior: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(fit.Ratings) != 3 {
+		t.Fatalf("Expected 3 ratings, got %d", len(fit.Ratings))
+	}
+	for i, player := range []string{"A", "B", "C"} {
+		if fit.Ratings[i].Player != player {
+			t.Errorf("Expected %s at rank %d, got %s", player, i+1, fit.Ratings[i].Player)
+		}
+		if fit.Ratings[i].Games != 160 {
+			t.Errorf("Expected 160 games for %s, got %d", player, fit.Ratings[i].Games)
+		}
+		if fit.Ratings[i].Error <= 0 || fit.Ratings[i].Error > 100 {
+			t.Errorf("Expected a plausible error margin for %s, got %.1f", player, fit.Ratings[i].Error)
+		}
+	}
+
+	sum := 0.0
+	for _, r := range fit.Ratings {
+		sum += r.Elo
+	}
+	if math.Abs(sum) > 1e-6 {
+		t.Errorf("Expected ratings averaging 0, got sum %.6f", sum)
+	}
+
+	// B is as far above C as A is above B
+	a, b, c := fit.Ratings[0].Elo, fit.Ratings[1].Elo, fit.Ratings[2].Elo
+	if math.Abs((a-b)-(b-c)) > 1 {
+		t.Errorf("Expected equal gaps, got A-B %.1f and B-C %.1f", a-b, b-c)
+	}
+	if fit.DrawElo <= 0 {
+		t.Errorf("Expected a positive draw Elo, got %.1f", fit.DrawElo)
+	}
+	if math.Abs(fit.WhiteAdvantage) > 5 {
+		t.Errorf("Expected little white advantage with balanced colours, got %.1f", fit.WhiteAdvantage)
+	}
+}
+
+func TestMaximumLikelihoodWhiteAdvantage(t *testing.T) {
+	// White wins every decisive game, so colour rather than player explains the results
+	var pool []Game
+	for i := 0; i < 20; i++ {
+		pool = append(pool, Game{White: "A", Black: "B", Result: WhiteWins})
+		pool = append(pool, Game{White: "B", Black: "A", Result: WhiteWins})
+		pool = append(pool, Game{White: "A", Black: "B", Result: Draw})
+		pool = append(pool, Game{White: "B", Black: "A", Result: Draw})
+	}
+
+	fit, err := MaximumLikelihood(pool, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fit.WhiteAdvantage < 50 {
+		t.Errorf("Expected a large white advantage, got %.1f", fit.WhiteAdvantage)
+	}
+	if math.Abs(fit.Ratings[0].Elo-fit.Ratings[1].Elo) > 1 {
+		t.Errorf("Expected equal ratings, got %+v", fit.Ratings)
+	}
+}
+
+func TestMaximumLikelihoodAnchor(t *testing.T) {
+	pool := match("A", "B", 6, 2, 2)
+
+	fit, err := MaximumLikelihood(pool, Options{Prior: 2, Anchor: "B", AnchorElo: 2000})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, r := range fit.Ratings {
+		if r.Player == "B" && r.Elo != 2000 {
+			t.Errorf("Expected B anchored at 2000, got %.1f", r.Elo)
+		}
+		if r.Player == "A" && r.Elo <= 2000 {
+			t.Errorf("Expected A above the anchor, got %.1f", r.Elo)
+		}
+	}
+}
+
+func TestMaximumLikelihoodErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		games       []Game
+		opts        Options
+		expectError bool
+	}{
+		{name: "Perfect score with prior", games: match("A", "B", 5, 0, 0), opts: Options{Prior: 2}},
+		{name: "Perfect score without prior", games: match("A", "B", 5, 0, 0), expectError: true},
+		{name: "No games", expectError: true},
+		{name: "Self play", games: []Game{{White: "A", Black: "A", Result: Draw}}, expectError: true},
+		{name: "Unknown result", games: []Game{{White: "A", Black: "B", Result: "*"}}, expectError: true},
+		{name: "Unknown anchor", games: match("A", "B", 1, 1, 1), opts: Options{Prior: 2, Anchor: "C"}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fit, err := MaximumLikelihood(test.games, test.opts)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none: %+v", fit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, r := range fit.Ratings {
+				if math.IsNaN(r.Elo) || math.IsInf(r.Elo, 0) || math.Abs(r.Elo) > 1000 {
+					t.Errorf("Expected a finite rating, got %+v", r)
+				}
+			}
+		})
+	}
+}
+
+func TestGradient(t *testing.T) {
+	pool := append(match("A", "B", 3, 4, 1), match("B", "C", 2, 1, 5)...)
+	m, err := newModel(pool, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	params := []float64{40, -25, 30, 120}
+	g := m.gradient(params)
+	for i := range params {
+		up := append([]float64(nil), params...)
+		down := append([]float64(nil), params...)
+		up[i] += 1e-4
+		down[i] -= 1e-4
+		numerical := (m.logLikelihood(up) - m.logLikelihood(down)) / 2e-4
+		if math.Abs(g[i]-numerical) > 1e-6 {
+			t.Errorf("Parameter %d: expected derivative %.8f, got %.8f", i, numerical, g[i])
+		}
+	}
+}
+
+func TestInvert(t *testing.T) {
+	a := [][]float64{{4, 7}, {2, 6}}
+	inverse, ok := invert(a)
+	if !ok {
+		t.Fatal("Expected an invertible matrix")
+	}
+	for i := range a {
+		for j := range a {
+			product := 0.0
+			for k := range a {
+				product += a[i][k] * inverse[k][j]
+			}
+			identity := 0.0
+			if i == j {
+				identity = 1
+			}
+			if math.Abs(product-identity) > 1e-12 {
+				t.Errorf("Expected identity at (%d,%d), got %f", i, j, product)
+			}
+		}
+	}
+
+	if _, ok := invert([][]float64{{1, 2}, {2, 4}}); ok {
+		t.Error("Expected a singular matrix to fail")
+	}
+}
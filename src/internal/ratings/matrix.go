@@ -0,0 +1,71 @@
+package ratings
+
+import "math"
+
+// invert returns the inverse of a square matrix by Gauss-Jordan elimination, and
+// false if it is singular
+func invert(a [][]float64) ([][]float64, bool) {
+	n := len(a)
+	work := make([][]float64, n)
+	for i := range a {
+		work[i] = make([]float64, 2*n)
+		copy(work[i], a[i])
+		work[i][n+i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(work[row][col]) > math.Abs(work[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(work[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+
+		scale := work[col][col]
+		for k := range work[col] {
+			work[col][k] /= scale
+		}
+		for row := 0; row < n; row++ {
+			if row == col || work[row][col] == 0 {
+				continue
+			}
+			factor := work[row][col]
+			for k := range work[row] {
+				work[row][k] -= factor * work[col][k]
+			}
+		}
+	}
+
+	inverse := make([][]float64, n)
+	for i := range work {
+		inverse[i] = work[i][n:]
+	}
+	return inverse, true
+}
+
+// negate returns -a
+func negate(a [][]float64) [][]float64 {
+	out := make([][]float64, len(a))
+	for i := range a {
+		out[i] = make([]float64, len(a[i]))
+		for j := range a[i] {
+			out[i][j] = -a[i][j]
+		}
+	}
+	return out
+}
+
+// multiply returns the product of a matrix and a vector
+func multiply(a [][]float64, v []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		for j := range v {
+			out[i] += a[i][j] * v[j]
+		}
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package ratings
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/shehio/envoy/pkg/types"
+)
+
+// DefaultPrior is the number of virtual draws given to each player when a request does
+// not set one. A request can still ask for none, as Prior is a pointer.
+const DefaultPrior = 2
+
+// Report fits ratings to the decided games of a request and estimates the Elo
+// difference of every pair of players who met
+func Report(req types.RatingsRequest) (types.Ratings, error) {
+	var games []Game
+	for _, g := range req.Games {
+		if g.Bye || g.Black == "" {
+			continue
+		}
+		switch g.Result {
+		case WhiteWins, BlackWins, Draw:
+			games = append(games, Game{White: g.White, Black: g.Black, Result: g.Result})
+		}
+	}
+
+	prior := float64(DefaultPrior)
+	if req.Prior != nil {
+		prior = *req.Prior
+	}
+	if prior < 0 || math.IsNaN(prior) || math.IsInf(prior, 0) {
+		return types.Ratings{}, fmt.Errorf("invalid prior %g", prior)
+	}
+	fit, err := MaximumLikelihood(games, Options{Prior: prior, Anchor: req.Anchor, AnchorElo: req.AnchorElo})
+	if err != nil {
+		return types.Ratings{}, err
+	}
+
+	report := types.Ratings{
+		DrawElo:        fit.DrawElo,
+		WhiteAdvantage: fit.WhiteAdvantage,
+	}
+	for i, r := range fit.Ratings {
+		report.Players = append(report.Players, types.PlayerRating{
+			Rank:   i + 1,
+			Player: r.Player,
+			Elo:    r.Elo,
+			Error:  r.Error,
+			Games:  r.Games,
+			Points: r.Points,
+		})
+	}
+
+	for _, m := range Matches(games) {
+		match := types.MatchRating{
+			Player:   m.Player,
+			Opponent: m.Opponent,
+			Wins:     m.Wins,
+			Draws:    m.Draws,
+			Losses:   m.Losses,
+		}
+		d, err := EloDifference(m.Record)
+		if err == ErrUnbounded {
+			match.Unbounded = true
+		} else if err != nil {
+			return types.Ratings{}, err
+		}
+		match.Elo = d.Elo
+		match.Error = d.Error
+		match.LOS = d.LOS
+		match.DrawRatio = d.DrawRatio
+		report.Matches = append(report.Matches, match)
+	}
+	return report, nil
+}
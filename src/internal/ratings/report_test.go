@@ -0,0 +1,80 @@
+package ratings
+
+import (
+	"testing"
+
+	"github.com/shehio/envoy/pkg/types"
+)
+
+func TestReport(t *testing.T) {
+	req := types.RatingsRequest{
+		Games: []types.TournamentGame{
+			{Round: 1, White: "A", Black: "B", Result: "1-0"},
+			{Round: 1, White: "C", Bye: true, Result: "1-0"},
+			{Round: 2, White: "B", Black: "C", Result: "1/2-1/2"},
+			{Round: 2, White: "A", Result: "1-0", Bye: true},
+			{Round: 3, White: "C", Black: "A", Result: "0-1"},
+			{Round: 4, White: "B", Black: "A", Result: "*"},
+		},
+		Anchor:    "B",
+		AnchorElo: 1500,
+	}
+
+	report, err := Report(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Players) != 3 {
+		t.Fatalf("Expected 3 players, got %+v", report.Players)
+	}
+	if report.Players[0].Player != "A" || report.Players[0].Rank != 1 || report.Players[0].Games != 2 {
+		t.Errorf("Expected A first with 2 games, got %+v", report.Players[0])
+	}
+	for _, p := range report.Players {
+		if p.Player == "B" && p.Elo != 1500 {
+			t.Errorf("Expected B anchored at 1500, got %.1f", p.Elo)
+		}
+	}
+
+	if len(report.Matches) != 3 {
+		t.Fatalf("Expected 3 matches, got %+v", report.Matches)
+	}
+	if m := report.Matches[0]; m.Player != "A" || m.Opponent != "B" || m.Wins != 1 || !m.Unbounded {
+		t.Errorf("Expected an unbounded win for A over B, got %+v", m)
+	}
+	if m := report.Matches[1]; m.Draws != 1 || m.Unbounded || m.Elo != 0 || m.DrawRatio != 1 {
+		t.Errorf("Expected an even drawn match between B and C, got %+v", m)
+	}
+}
+
+func TestReportErrors(t *testing.T) {
+	zero, negative := 0.0, -1.0
+	tests := []struct {
+		name        string
+		req         types.RatingsRequest
+		expectError bool
+	}{
+		{name: "No games", expectError: true},
+		{name: "Only unfinished games", req: types.RatingsRequest{Games: []types.TournamentGame{{White: "A", Black: "B", Result: "*"}}}, expectError: true},
+		{name: "Unknown anchor", req: types.RatingsRequest{Games: []types.TournamentGame{{White: "A", Black: "B", Result: "1-0"}}, Anchor: "C"}, expectError: true},
+		{name: "Negative prior", req: types.RatingsRequest{Games: []types.TournamentGame{{White: "A", Black: "B", Result: "1-0"}}, Prior: &negative}, expectError: true},
+		{name: "Perfect score without prior", req: types.RatingsRequest{Games: []types.TournamentGame{{White: "A", Black: "B", Result: "1-0"}}, Prior: &zero}, expectError: true},
+		{name: "One game", req: types.RatingsRequest{Games: []types.TournamentGame{{White: "A", Black: "B", Result: "1-0"}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Report(test.req)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package ratings
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPentanomialAdd(t *testing.T) {
+	var p Pentanomial
+	p.Add(1, 0)
+	p.Add(0.5, 0.5)
+	p.Add(1, 0.5)
+	p.Add(0, 0)
+	p.Add(1, 1)
+
+	if p != (Pentanomial{1, 0, 2, 1, 1}) {
+		t.Errorf("Unexpected pentanomial %v", p)
+	}
+	if p.Pairs() != 5 {
+		t.Errorf("Expected 5 pairs, got %d", p.Pairs())
+	}
+}
+
+func TestPentanomialElo(t *testing.T) {
+	tests := []struct {
+		name        string
+		pairs       Pentanomial
+		elo         float64
+		err         float64
+		los         float64
+		expectError bool
+	}{
+		{name: "Even", pairs: Pentanomial{5, 20, 50, 20, 5}, elo: 0, err: 30.5, los: 0.5},
+		{name: "Stronger", pairs: Pentanomial{2, 15, 50, 25, 8}, elo: 38.4, err: 30.0, los: 0.9944},
+		{name: "All pairs drawn", pairs: Pentanomial{0, 0, 10, 0, 0}, elo: 0, err: 0, los: 0.5},
+		{name: "No pairs", expectError: true},
+		{name: "Every pair won", pairs: Pentanomial{0, 0, 0, 0, 3}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := PentanomialElo(test.pairs)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if math.Abs(d.Elo-test.elo) > 0.1 {
+				t.Errorf("Expected Elo %.1f, got %.1f", test.elo, d.Elo)
+			}
+			if math.Abs(d.Error-test.err) > 0.1 {
+				t.Errorf("Expected error margin %.1f, got %.1f", test.err, d.Error)
+			}
+			if math.Abs(d.LOS-test.los) > 0.001 {
+				t.Errorf("Expected LOS %.4f, got %.4f", test.los, d.LOS)
+			}
+		})
+	}
+}
+
+func TestSPRTValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		sprt        SPRT
+		expectError bool
+	}{
+		{name: "Valid", sprt: SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}},
+		{name: "Reversed bounds", sprt: SPRT{Elo0: 5, Elo1: 0, Alpha: 0.05, Beta: 0.05}, expectError: true},
+		{name: "Equal bounds", sprt: SPRT{Elo0: 5, Elo1: 5, Alpha: 0.05, Beta: 0.05}, expectError: true},
+		{name: "Zero alpha", sprt: SPRT{Elo0: 0, Elo1: 5, Beta: 0.05}, expectError: true},
+		{name: "Large beta", sprt: SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.5}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.sprt.Validate()
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	sprt := SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}
+
+	lower, upper := sprt.Bounds()
+	if math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Errorf("Expected bounds of ±2.944, got %.3f and %.3f", lower, upper)
+	}
+
+	tests := []struct {
+		name     string
+		pairs    Pentanomial
+		llr      float64
+		decision string
+	}{
+		{name: "No pairs", llr: 0},
+		{name: "Every pair drawn", pairs: Pentanomial{0, 0, 20, 0, 0}, llr: -16.570, decision: AcceptH0},
+		{name: "Two pairs won", pairs: Pentanomial{0, 0, 0, 0, 2}, llr: 7.632, decision: AcceptH1},
+		{name: "One pair won", pairs: Pentanomial{0, 0, 0, 0, 1}, llr: 1.911},
+		{name: "Even", pairs: Pentanomial{5, 20, 50, 20, 5}, llr: -0.052},
+		{name: "Slightly stronger", pairs: Pentanomial{5, 20, 48, 22, 5}, llr: 0.020},
+		{name: "Much stronger", pairs: Pentanomial{10, 180, 500, 270, 40}, llr: 6.220, decision: AcceptH1},
+		{name: "Weaker", pairs: Pentanomial{30, 250, 500, 200, 20}, llr: -3.765, decision: AcceptH0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if llr := sprt.LLR(test.pairs); math.Abs(llr-test.llr) > 0.001 {
+				t.Errorf("Expected LLR %.3f, got %.3f", test.llr, llr)
+			}
+			if decision := sprt.Decide(test.pairs); decision != test.decision {
+				t.Errorf("Expected decision %q, got %q", test.decision, decision)
+			}
+		})
+	}
+}
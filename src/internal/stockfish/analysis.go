@@ -0,0 +1,145 @@
+package stockfish
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+// Analyze starts searching the given position and streams the engine's info lines.
+// Cancelling the context sends "stop" to the engine. The last update on the channel
+// has Done set and carries the best move; the channel is closed afterwards, and
+// callers must drain it until then.
+func (h *Handler) Analyze(ctx context.Context, fen string, limits uci.SearchLimits) (<-chan uci.AnalysisUpdate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := h.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := h.startAnalysis(ctx, fen, limits); err != nil {
+		h.release()
+		return nil, err
+	}
+
+	updates := make(chan uci.AnalysisUpdate)
+	go func() {
+		defer close(updates)
+		defer h.release()
+
+		updates <- h.streamAnalysis(ctx, fen, limits, updates)
+	}()
+
+	return updates, nil
+}
+
+// streamAnalysis forwards info lines until the best move and returns the final update.
+// A bounded search is stopped once its search timeout passes, and an engine that
+// crashes mid-analysis is restarted once and the analysis started again.
+func (h *Handler) streamAnalysis(ctx context.Context, fen string, limits uci.SearchLimits, updates chan<- uci.AnalysisUpdate) uci.AnalysisUpdate {
+	var searchTimer, stopTimer *time.Timer
+	var searchDeadline, deadline <-chan time.Time
+	startDeadline := func() {
+		if searchTimer != nil {
+			searchTimer.Stop()
+		}
+		if limits.Bounded() {
+			searchTimer = time.NewTimer(h.searchTimeout(limits))
+			searchDeadline = searchTimer.C
+		}
+	}
+	defer func() {
+		for _, timer := range []*time.Timer{searchTimer, stopTimer} {
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+	}()
+	startDeadline()
+
+	// Once stopped, keep reading so the best move is consumed, but no longer than
+	// the command timeout
+	stopped, timedOut, restarted := false, false, false
+	cancelled := ctx.Done()
+	stop := func() {
+		stopped = true
+		cancelled = nil
+		searchDeadline = nil
+		h.send("stop")
+		stopTimer = time.NewTimer(h.opts.CommandTimeout)
+		deadline = stopTimer.C
+	}
+
+	for {
+		select {
+		case <-cancelled:
+			stop()
+		case <-searchDeadline:
+			timedOut = true
+			stop()
+		case <-deadline:
+			return uci.AnalysisUpdate{Done: true, Err: fmt.Errorf("failed to stop analysis: %v", errTimeout)}
+		case line, ok := <-h.proc.Lines():
+			if !ok {
+				if stopped || restarted {
+					return uci.AnalysisUpdate{Done: true, Err: errExited}
+				}
+				restarted = true
+				if _, err := h.recoverCrash(ctx); err != nil {
+					return uci.AnalysisUpdate{Done: true, Err: fmt.Errorf("%v: %v", errExited, err)}
+				}
+				if err := h.startAnalysis(ctx, fen, limits); err != nil {
+					return uci.AnalysisUpdate{Done: true, Err: err}
+				}
+				startDeadline()
+				continue
+			}
+			if strings.HasPrefix(line, "info") {
+				info, err := uci.ParseInfo(line)
+				if err != nil || stopped {
+					continue
+				}
+				select {
+				case updates <- uci.AnalysisUpdate{Info: info}:
+				case <-ctx.Done():
+				}
+				continue
+			}
+			if bestMove, ponder, ok := uci.ParseBestMove(line); ok {
+				update := uci.AnalysisUpdate{Done: true, BestMove: bestMove, Ponder: ponder}
+				switch {
+				case timedOut:
+					update.Err = fmt.Errorf("failed to get best move: %v", errTimeout)
+				case bestMove == "" && !stopped:
+					update.Err = uci.ErrNoLegalMove
+				}
+				return update
+			}
+		}
+	}
+}
+
+// startAnalysis sends the position and go command; the caller must own the engine
+func (h *Handler) startAnalysis(ctx context.Context, fen string, limits uci.SearchLimits) error {
+	if err := h.prepareSearch(ctx); err != nil {
+		return err
+	}
+
+	// Set position
+	h.position = uci.Position{FEN: fen}.Command()
+	if err := h.send(h.position); err != nil {
+		return fmt.Errorf("failed to set position: %v", err)
+	}
+
+	// Start analysis
+	if err := h.send(limits.GoCommand()); err != nil {
+		return fmt.Errorf("failed to start analysis: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package stockfish
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The fake engine is the test binary itself, started with TestFakeEngine as the only
+// test and the engine description after "--". It reads UCI commands from stdin and
+// answers them from a script, so handler behavior can be tested without Stockfish.
+
+const fakeEngineArg = "fake-uci-engine"
+
+// fakeStep is one command the fake engine expects and its reaction
+type fakeStep struct {
+	Expect   string        // Expected command; a trailing "*" matches any suffix
+	Respond  []string      // Lines written in reply, verbatim
+	Delay    time.Duration // Wait before replying
+	ExitCode int           // If non-zero, exit with this status after replying
+	Hang     bool          // Stop reading commands after replying, until killed
+}
+
+// fakeScript is the behavior of one run of the fake engine process. Commands not
+// matching the next step are matched against the Always steps, and otherwise answered
+// as an idle engine would: isready with readyok, stop is ignored and quit exits, as it
+// also does after a step expecting it. Anything else is recorded as unexpected.
+//
+// Replies may refer to the engine's state: "{fullmove}" is replaced by the full move
+// number of the last position and "{value}" by the value of the last setoption.
+type fakeScript struct {
+	Steps  []fakeStep
+	Always []fakeStep // Reactions to commands at any point, any number of times; the first match wins
+	Ignore []string   // Command prefixes accepted at any point without a reply, e.g. "setoption"
+}
+
+// fakeEngine describes every run of the fake engine. Each start of the process plays
+// the next script, so restarts after a crash can be scripted.
+type fakeEngine struct {
+	Runs []fakeScript
+	Dir  string // Holds the run counter and one report per run
+}
+
+// uciHandshake returns the step answering the uci command
+func uciHandshake() fakeStep {
+	return fakeStep{Expect: "uci", Respond: []string{"id name Fake", "id author Envoy", "uciok"}}
+}
+
+// fakeOptions returns handler options that start the fake engine. When the test ends,
+// every run is checked to have started and consumed its whole script.
+func fakeOptions(t *testing.T, runs ...fakeScript) Options {
+	t.Helper()
+
+	engine := fakeEngine{Runs: runs, Dir: t.TempDir()}
+	data, err := json.Marshal(engine)
+	if err != nil {
+		t.Fatalf("Failed to encode fake engine: %v", err)
+	}
+
+	// A race-enabled fake engine would otherwise sleep a second on exit, longer than QuitTimeout
+	t.Setenv("GORACE", strings.TrimSpace(os.Getenv("GORACE")+" atexit_sleep_ms=0"))
+
+	t.Cleanup(func() {
+		for i := range runs {
+			report, err := os.ReadFile(filepath.Join(engine.Dir, fmt.Sprintf("report-%d", i)))
+			if err != nil {
+				t.Errorf("Fake engine run %d never started", i)
+				continue
+			}
+			if string(report) != "ok" {
+				t.Errorf("Fake engine run %d: %s", i, report)
+			}
+		}
+	})
+
+	return Options{
+		Path:           os.Args[0],
+		Args:           []string{"-test.run=^TestFakeEngine$", "--", fakeEngineArg, string(data)},
+		CommandTimeout: 300 * time.Millisecond,
+		QuitTimeout:    300 * time.Millisecond,
+	}
+}
+
+// TestFakeEngine runs the fake engine when the test binary is started by fakeOptions
+func TestFakeEngine(t *testing.T) {
+	args := flag.Args()
+	if len(args) != 2 || args[0] != fakeEngineArg {
+		return
+	}
+
+	var engine fakeEngine
+	if err := json.Unmarshal([]byte(args[1]), &engine); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid fake engine: %v\n", err)
+		os.Exit(2)
+	}
+
+	os.Exit(runFakeEngine(engine, os.Stdin, os.Stdout))
+}
+
+// runFakeEngine plays the next run of engine and returns the exit status
+func runFakeEngine(engine fakeEngine, in io.Reader, out io.Writer) int {
+	counter := filepath.Join(engine.Dir, "runs")
+	run := 0
+	if data, err := os.ReadFile(counter); err == nil {
+		run, _ = strconv.Atoi(string(data))
+	}
+	os.WriteFile(counter, []byte(strconv.Itoa(run+1)), 0o644)
+
+	report := filepath.Join(engine.Dir, fmt.Sprintf("report-%d", run))
+	if run >= len(engine.Runs) {
+		os.WriteFile(report, []byte("unscripted run"), 0o644)
+		return 2
+	}
+	script := engine.Runs[run]
+
+	var problems []string
+	next := 0
+	fullmove, value := "", ""
+
+	// The verdict is rewritten after every command so a killed engine still leaves one
+	writeReport := func() {
+		verdict := "ok"
+		if len(problems) > 0 {
+			verdict = strings.Join(problems, "; ")
+		} else if next < len(script.Steps) {
+			verdict = fmt.Sprintf("%d steps not reached, next %q", len(script.Steps)-next, script.Steps[next].Expect)
+		}
+		os.WriteFile(report, []byte(verdict), 0o644)
+	}
+	writeReport()
+
+	writer := bufio.NewWriter(out)
+
+	// react replies to a matched step, returning false if the engine exits
+	react := func(step fakeStep) bool {
+		time.Sleep(step.Delay)
+		for _, line := range step.Respond {
+			line = strings.ReplaceAll(line, "{fullmove}", fullmove)
+			line = strings.ReplaceAll(line, "{value}", value)
+			fmt.Fprintln(writer, line)
+		}
+		writer.Flush()
+
+		if step.Hang {
+			time.Sleep(time.Hour)
+		}
+		return step.ExitCode == 0
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(command)
+		if len(fields) >= 8 && fields[0] == "position" && fields[1] == "fen" {
+			fullmove = fields[7]
+		}
+		if _, v, ok := strings.Cut(command, " value "); ok && len(fields) > 0 && fields[0] == "setoption" {
+			value = v
+		}
+
+		if next < len(script.Steps) && fakeMatch(script.Steps[next].Expect, command) {
+			step := script.Steps[next]
+			next++
+			writeReport()
+
+			if !react(step) {
+				return step.ExitCode
+			}
+			if command == "quit" {
+				return 0
+			}
+			continue
+		}
+		if step, ok := fakeFind(script.Always, command); ok {
+			if !react(step) {
+				return step.ExitCode
+			}
+			continue
+		}
+
+		switch {
+		case command == "isready":
+			fmt.Fprintln(writer, "readyok")
+			writer.Flush()
+		case command == "stop":
+		case command == "quit":
+			return 0
+		case fakeIgnored(script.Ignore, command):
+		default:
+			expected := "end of script"
+			if next < len(script.Steps) {
+				expected = script.Steps[next].Expect
+			}
+			problems = append(problems, fmt.Sprintf("unexpected command %q, expected %q", command, expected))
+			writeReport()
+		}
+	}
+
+	return 0
+}
+
+// fakeMatch reports whether command matches an expected command
+func fakeMatch(expect, command string) bool {
+	if prefix, ok := strings.CutSuffix(expect, "*"); ok {
+		return strings.HasPrefix(command, prefix)
+	}
+	return command == expect
+}
+
+// fakeFind returns the first step matching command
+func fakeFind(steps []fakeStep, command string) (fakeStep, bool) {
+	for _, step := range steps {
+		if fakeMatch(step.Expect, command) {
+			return step, true
+		}
+	}
+	return fakeStep{}, false
+}
+
+// fakeIgnored reports whether command starts with one of the ignored prefixes
+func fakeIgnored(prefixes []string, command string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(command, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,44 +1,103 @@
 package stockfish
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"io"
-	"os/exec"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/engine"
+	"github.com/shehio/envoy/src/internal/transcript"
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+const (
+	defaultPath           = "stockfish"
+	defaultCommandTimeout = 10 * time.Second
+	defaultQuitTimeout    = 5 * time.Second
+	defaultSearchTimeout  = 5 * time.Minute
 )
 
-// Handler manages communication with the Stockfish chess engine
+// Options configures the engine process started by a Handler
+type Options struct {
+	Path           string            // Engine binary, defaults to "stockfish"
+	Args           []string          // Extra command line arguments
+	CommandTimeout time.Duration     // How long to wait for uciok, readyok and stopped searches, defaults to 10s
+	EngineOptions  map[string]string // UCI options set after the handshake, e.g. "Threads" or "Hash"
+	QuitTimeout    time.Duration     // How long Close waits for the engine to quit before killing it, defaults to 5s
+	SearchTimeout  time.Duration     // How long to wait for a search without a time limit, such as depth or nodes, defaults to 5m
+
+	Transcript *transcript.Transcript // Optional record of every line sent and received
+	InstanceID string                 // Names the engine in the transcript, defaults to its process ID
+}
+
+// Handler manages communication with the Stockfish chess engine. It speaks plain UCI,
+// so any UCI engine such as Lc0 or Komodo can be driven by setting Options.Path.
+//
+// A Handler is safe for concurrent use. Every search, option change and ponder
+// transition owns the engine exclusively for its whole command sequence, and callers
+// waiting for the engine are served in arrival order. Before each search the handler
+// synchronizes with the engine using isready, so output left over from an earlier
+// command can never be mistaken for the answer to a later one. An Analyze call keeps
+// the engine until its final update has been delivered.
 type Handler struct {
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
-	stdout io.ReadCloser
+	opts  Options
+	queue chan struct{} // Holds a token while a caller owns the engine
+
+	// Owned by the caller holding the queue token
+	options      map[string]string // Options set with SetOption, replayed after a restart
+	position     string            // Last position command, replayed after a restart
+	ponderLimits uci.SearchLimits  // Limits of the ponder search, which apply after ponderhit
+
+	mu            sync.Mutex // Guards the fields below
+	proc          *engine.Process
+	id            uci.ID
+	closed        bool
+	stats         HandlerStats
+	ponderEnabled bool
+	ponderMove    string // Opponent move being pondered on, empty when not pondering
 }
 
+var _ uci.Engine = (*Handler)(nil)
+
 // NewHandler creates a new Stockfish handler
 func NewHandler() (*Handler, error) {
-	cmd := exec.Command("stockfish")
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %v", err)
-	}
+	return NewHandlerWithOptions(Options{})
+}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %v", err)
+// NewHandlerWithOptions creates a new handler for the engine described by opts.
+// If the engine process dies, the handler restarts it with the same options on
+// the next command.
+func NewHandlerWithOptions(opts Options) (*Handler, error) {
+	if opts.Path == "" {
+		opts.Path = defaultPath
+	}
+	if opts.CommandTimeout <= 0 {
+		opts.CommandTimeout = defaultCommandTimeout
+	}
+	if opts.QuitTimeout <= 0 {
+		opts.QuitTimeout = defaultQuitTimeout
+	}
+	if opts.SearchTimeout <= 0 {
+		opts.SearchTimeout = defaultSearchTimeout
 	}
 
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start stockfish: %v", err)
+	proc, err := startProcess(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	handler := &Handler{
-		cmd:    cmd,
-		stdin:  stdin,
-		stdout: stdout,
+		opts:    opts,
+		queue:   make(chan struct{}, 1),
+		options: make(map[string]string),
+		proc:    proc,
 	}
 
 	if err := handler.initializeEngine(); err != nil {
+		handler.Close()
 		return nil, err
 	}
 
@@ -46,65 +105,383 @@ func NewHandler() (*Handler, error) {
 }
 
 func (h *Handler) initializeEngine() error {
+	ctx := context.Background()
+
 	// Send UCI command
-	if _, err := fmt.Fprintln(h.stdin, "uci"); err != nil {
+	if err := h.send("uci"); err != nil {
 		return fmt.Errorf("failed to send uci command: %v", err)
 	}
 
-	// Wait for uciok
-	scanner := bufio.NewScanner(h.stdout)
-	for scanner.Scan() {
-		if scanner.Text() == "uciok" {
+	// Wait for uciok, recording the engine's identity
+	var id uci.ID
+	timer := time.NewTimer(h.opts.CommandTimeout)
+	defer timer.Stop()
+	for {
+		line, err := h.readLine(ctx, timer.C)
+		if err != nil {
+			return fmt.Errorf("failed waiting for uciok: %v", err)
+		}
+		if strings.TrimSpace(line) == "uciok" {
 			break
 		}
+		uci.ParseID(line, &id)
+	}
+
+	h.mu.Lock()
+	h.id = id
+	h.mu.Unlock()
+
+	// Apply configured options in a stable order
+	names := make([]string, 0, len(h.opts.EngineOptions))
+	for name := range h.opts.EngineOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := h.send(fmt.Sprintf("setoption name %s value %s", name, h.opts.EngineOptions[name])); err != nil {
+			return fmt.Errorf("failed to set option %s: %v", name, err)
+		}
 	}
 
-	// Send isready command
-	if _, err := fmt.Fprintln(h.stdin, "isready"); err != nil {
+	return h.isReady(ctx)
+}
+
+// isReady sends isready and waits for readyok
+func (h *Handler) isReady(ctx context.Context) error {
+	if err := h.send("isready"); err != nil {
 		return fmt.Errorf("failed to send isready command: %v", err)
 	}
+	return h.waitFor(ctx, "readyok", h.opts.CommandTimeout)
+}
 
-	// Wait for readyok
-	for scanner.Scan() {
-		if scanner.Text() == "readyok" {
-			break
+// acquire waits until the caller owns the engine. Blocked callers are queued in
+// arrival order by the channel.
+func (h *Handler) acquire(ctx context.Context) error {
+	select {
+	case h.queue <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release gives up ownership of the engine
+func (h *Handler) release() {
+	<-h.queue
+}
+
+// send writes a single command line to the engine
+func (h *Handler) send(command string) error {
+	return h.proc.Write(command)
+}
+
+// readLine returns the next line from the engine. A nil timeout channel waits indefinitely.
+func (h *Handler) readLine(ctx context.Context, timeout <-chan time.Time) (string, error) {
+	select {
+	case line, ok := <-h.proc.Lines():
+		if !ok {
+			return "", errExited
 		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-timeout:
+		return "", errTimeout
 	}
+}
 
-	return nil
+var (
+	errTimeout = fmt.Errorf("timed out waiting for engine")
+	errExited  = fmt.Errorf("engine process exited")
+)
+
+// waitFor discards lines until the engine prints want
+func (h *Handler) waitFor(ctx context.Context, want string, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		line, err := h.readLine(ctx, timer.C)
+		if err != nil {
+			return fmt.Errorf("failed waiting for %s: %v", want, err)
+		}
+		if strings.TrimSpace(line) == want {
+			return nil
+		}
+	}
+}
+
+// SearchResult holds the outcome of a search
+type SearchResult struct {
+	BestMove string
+	Ponder   string     // Move the engine expects in reply, empty if none was given
+	Info     []uci.Info // Info lines reported during the search, in the order received
+}
+
+// Final returns the last scored info line for the main line, if any
+func (r *SearchResult) Final() (uci.Info, bool) {
+	for i := len(r.Info) - 1; i >= 0; i-- {
+		if r.Info[i].HasScore && r.Info[i].MultiPV == 1 {
+			return r.Info[i], true
+		}
+	}
+	return uci.Info{}, false
 }
 
 // GetMove implements the Player interface
 func (h *Handler) GetMove(fen string) (string, error) {
+	return h.GetMoveContext(context.Background(), fen)
+}
+
+// GetMoveContext returns the engine's move for the given position, giving up when ctx is done
+func (h *Handler) GetMoveContext(ctx context.Context, fen string) (string, error) {
+	result, err := h.SearchContext(ctx, fen, uci.SearchLimits{MoveTime: time.Second})
+	if err != nil {
+		return "", err
+	}
+	return result.BestMove, nil
+}
+
+// GetMoveWithHistory returns the engine's move for the position reached by playing
+// moves, in UCI notation, from startFEN. Sending the history rather than only the
+// current position lets the engine detect repetitions and apply the fifty move rule.
+func (h *Handler) GetMoveWithHistory(startFEN string, moves []string) (string, error) {
+	result, err := h.SearchPosition(context.Background(), uci.Position{FEN: startFEN, Moves: moves}, uci.SearchLimits{MoveTime: time.Second})
+	if err != nil {
+		return "", err
+	}
+	return result.BestMove, nil
+}
+
+// Search searches the given position and returns the best move along with the parsed info lines
+func (h *Handler) Search(fen string) (*SearchResult, error) {
+	return h.SearchWithLimits(fen, uci.SearchLimits{MoveTime: time.Second})
+}
+
+// SearchWithLimits searches the given position within the given limits
+func (h *Handler) SearchWithLimits(fen string, limits uci.SearchLimits) (*SearchResult, error) {
+	return h.SearchContext(context.Background(), fen, limits)
+}
+
+// SearchContext searches the given position within the given limits. If ctx is done
+// before the engine answers, the search is stopped and ctx's error is returned.
+func (h *Handler) SearchContext(ctx context.Context, fen string, limits uci.SearchLimits) (*SearchResult, error) {
+	return h.SearchPosition(ctx, uci.Position{FEN: fen}, limits)
+}
+
+// SearchPosition searches the position reached by the given moves within the given
+// limits. If ctx is done before the engine answers, the search is stopped and ctx's
+// error is returned.
+func (h *Handler) SearchPosition(ctx context.Context, position uci.Position, limits uci.SearchLimits) (*SearchResult, error) {
+	if err := h.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer h.release()
+
+	return h.search(ctx, position, limits)
+}
+
+// search runs a search, restarting the engine and searching again once if it
+// crashes; the caller must own the engine
+func (h *Handler) search(ctx context.Context, position uci.Position, limits uci.SearchLimits) (*SearchResult, error) {
+	if !limits.Bounded() {
+		return nil, fmt.Errorf("search limits must bound the search")
+	}
+
+	result, err := h.searchOnce(ctx, position, limits)
+	if err != nil {
+		if restarted, restartErr := h.recoverCrash(ctx); restartErr != nil {
+			return nil, fmt.Errorf("%v: %v", err, restartErr)
+		} else if restarted {
+			return h.searchOnce(ctx, position, limits)
+		}
+	}
+	return result, err
+}
+
+// searchOnce sends a single search to the engine
+func (h *Handler) searchOnce(ctx context.Context, position uci.Position, limits uci.SearchLimits) (*SearchResult, error) {
+	if err := h.prepareSearch(ctx); err != nil {
+		return nil, err
+	}
+
 	// Set position
-	if _, err := fmt.Fprintf(h.stdin, "position fen %s\n", fen); err != nil {
-		return "", fmt.Errorf("failed to set position: %v", err)
+	h.position = position.Command()
+	if err := h.send(h.position); err != nil {
+		return nil, fmt.Errorf("failed to set position: %v", err)
 	}
 
 	// Start thinking
-	if _, err := fmt.Fprintf(h.stdin, "go movetime %d\n", 1000); err != nil {
-		return "", fmt.Errorf("failed to start thinking: %v", err)
-	}
-
-	// Read best move
-	scanner := bufio.NewScanner(h.stdout)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "bestmove") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				return parts[1], nil
+	if err := h.send(limits.GoCommand()); err != nil {
+		return nil, fmt.Errorf("failed to start thinking: %v", err)
+	}
+
+	return h.readSearchResult(ctx, h.searchTimeout(limits))
+}
+
+// prepareSearch restarts a crashed engine, stops any ponder search and waits until
+// the engine is idle
+func (h *Handler) prepareSearch(ctx context.Context) error {
+	if _, err := h.recoverCrash(ctx); err != nil {
+		return err
+	}
+
+	// A new search replaces any ponder search
+	if err := h.stopPonder(); err != nil {
+		return err
+	}
+	return h.isReady(ctx)
+}
+
+// searchTimeout returns how long to wait for a best move. A timed search gets its time
+// plus the command timeout; on the clock that is the larger of the two clocks, since
+// the side to move is not known here. Other searches get the search timeout.
+func (h *Handler) searchTimeout(limits uci.SearchLimits) time.Duration {
+	switch {
+	case limits.MoveTime > 0:
+		return limits.MoveTime + h.opts.CommandTimeout
+	case limits.WTime > 0 || limits.BTime > 0:
+		clock := limits.WTime + limits.WInc
+		if black := limits.BTime + limits.BInc; black > clock {
+			clock = black
+		}
+		return clock + h.opts.CommandTimeout
+	}
+	return h.opts.SearchTimeout
+}
+
+// readSearchResult reads info lines until the best move. On timeout or cancellation
+// the search is stopped so that its best move does not leak into the next command.
+func (h *Handler) readSearchResult(ctx context.Context, timeout time.Duration) (*SearchResult, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	result := &SearchResult{}
+	for {
+		line, err := h.readLine(ctx, timer.C)
+		if err != nil {
+			if ctx.Err() != nil {
+				h.stopSearch()
+				return nil, ctx.Err()
+			}
+			if err == errTimeout {
+				h.stopSearch()
 			}
+			return nil, fmt.Errorf("failed to get best move: %v", err)
 		}
+
+		if strings.HasPrefix(line, "info") {
+			if info, err := uci.ParseInfo(line); err == nil {
+				result.Info = append(result.Info, info)
+			}
+			continue
+		}
+		if bestMove, ponder, ok := uci.ParseBestMove(line); ok {
+			if bestMove == "" {
+				return nil, uci.ErrNoLegalMove
+			}
+			result.BestMove = bestMove
+			result.Ponder = ponder
+			return result, nil
+		}
+	}
+}
+
+// stopSearch stops the running search and discards its output up to the best move
+func (h *Handler) stopSearch() error {
+	if err := h.send("stop"); err != nil {
+		return fmt.Errorf("failed to send stop: %v", err)
+	}
+
+	timer := time.NewTimer(h.opts.CommandTimeout)
+	defer timer.Stop()
+
+	for {
+		line, err := h.readLine(context.Background(), timer.C)
+		if err != nil {
+			return fmt.Errorf("failed to stop search: %v", err)
+		}
+		if _, _, ok := uci.ParseBestMove(line); ok {
+			return nil
+		}
+	}
+}
+
+// SetOption sets a UCI option on the engine and waits until the engine has applied it
+func (h *Handler) SetOption(name, value string) error {
+	ctx := context.Background()
+	if err := h.acquire(ctx); err != nil {
+		return err
+	}
+	defer h.release()
+
+	return h.setOption(ctx, name, value)
+}
+
+// setOption sets a UCI option; the caller must own the engine
+func (h *Handler) setOption(ctx context.Context, name, value string) error {
+	if _, err := h.recoverCrash(ctx); err != nil {
+		return err
 	}
 
-	return "", fmt.Errorf("failed to get best move")
+	if err := h.send(fmt.Sprintf("setoption name %s value %s", name, value)); err != nil {
+		return fmt.Errorf("failed to set option %s: %v", name, err)
+	}
+	h.options[name] = value
+	return h.isReady(ctx)
+}
+
+// ID returns the engine's identity as reported during the handshake
+func (h *Handler) ID() uci.ID {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.id
+}
+
+// NewGame tells the engine that the following searches belong to a new game
+func (h *Handler) NewGame() error {
+	ctx := context.Background()
+	if err := h.acquire(ctx); err != nil {
+		return err
+	}
+	defer h.release()
+
+	if _, err := h.recoverCrash(ctx); err != nil {
+		return err
+	}
+	if err := h.stopPonder(); err != nil {
+		return err
+	}
+	h.position = ""
+	if err := h.send("ucinewgame"); err != nil {
+		return fmt.Errorf("failed to send ucinewgame: %v", err)
+	}
+	return h.isReady(ctx)
+}
+
+// Ping checks that the engine is alive and responsive
+func (h *Handler) Ping() error {
+	ctx := context.Background()
+	if err := h.acquire(ctx); err != nil {
+		return err
+	}
+	defer h.release()
+
+	return h.isReady(ctx)
 }
 
-// Close closes the Stockfish process
+// Close shuts the engine down gracefully: it sends stop and quit, waits up to
+// QuitTimeout for the process to exit and kills it otherwise. The process is always
+// reaped and its pipes closed. A search running concurrently fails.
 func (h *Handler) Close() error {
-	if h.cmd.Process != nil {
-		return h.cmd.Process.Kill()
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
 	}
-	return nil
-} 
\ No newline at end of file
+	h.closed = true
+	proc := h.proc
+	h.mu.Unlock()
+
+	return proc.Shutdown(h.opts.QuitTimeout, "stop", "quit")
+}
@@ -1,14 +1,34 @@
 package stockfish
 
 import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/uci"
 )
 
-func TestNewHandler(t *testing.T) {
+// newStockfishHandler starts a handler for the real Stockfish binary, skipping the
+// test when it is not installed
+func newStockfishHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	if _, err := exec.LookPath(defaultPath); err != nil {
+		t.Skip("stockfish not found in PATH")
+	}
+
 	handler, err := NewHandler()
 	if err != nil {
 		t.Fatalf("Failed to create handler: %v", err)
 	}
+	return handler
+}
+
+func TestNewHandler(t *testing.T) {
+	handler := newStockfishHandler(t)
 	defer handler.Close()
 
 	if handler == nil {
@@ -17,10 +37,7 @@ func TestNewHandler(t *testing.T) {
 }
 
 func TestGetMove(t *testing.T) {
-	handler, err := NewHandler()
-	if err != nil {
-		t.Fatalf("Failed to create handler: %v", err)
-	}
+	handler := newStockfishHandler(t)
 	defer handler.Close()
 
 	tests := []struct {
@@ -64,4 +81,619 @@ func TestGetMove(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestSearch(t *testing.T) {
+	handler := newStockfishHandler(t)
+	defer handler.Close()
+
+	result, err := handler.Search("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.BestMove == "" {
+		t.Error("Expected a best move but got empty string")
+	}
+
+	info, ok := result.Final()
+	if !ok {
+		t.Fatal("Expected a scored info line")
+	}
+
+	if info.Depth == 0 {
+		t.Error("Expected a non-zero search depth")
+	}
+
+	if len(info.PV) == 0 || info.PV[0] != result.BestMove {
+		t.Errorf("Expected PV to start with the best move %s, got %v", result.BestMove, info.PV)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	handler := newStockfishHandler(t)
+	defer handler.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := handler.Analyze(ctx, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", uci.SearchLimits{Infinite: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var infos int
+	var final uci.AnalysisUpdate
+	for update := range updates {
+		if update.Done {
+			final = update
+			continue
+		}
+		infos++
+		if update.Info.Depth >= 5 {
+			cancel()
+		}
+	}
+
+	if final.Err != nil {
+		t.Fatalf("Unexpected error: %v", final.Err)
+	}
+
+	if infos == 0 {
+		t.Error("Expected info updates before the best move")
+	}
+
+	if final.BestMove == "" {
+		t.Error("Expected a best move but got empty string")
+	}
+
+	// The handler must still be usable after the analysis was stopped
+	if _, err := handler.GetMove("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"); err != nil {
+		t.Errorf("Unexpected error after analysis: %v", err)
+	}
+}
+
+// echoScript answers every search at once with two info lines and e2e4, except a
+// search to depth 99, which only ends when stopped
+func echoScript() fakeScript {
+	return fakeScript{
+		Steps: []fakeStep{uciHandshake()},
+		Always: []fakeStep{
+			{Expect: "go depth 99"},
+			{Expect: "go*", Respond: []string{
+				"info depth 1 score cp 10 pv e2e4",
+				"info depth 2 score cp 12 pv e2e4 e7e5",
+				"bestmove e2e4 ponder e7e5",
+			}},
+			{Expect: "stop", Respond: []string{"bestmove a2a3"}},
+		},
+		Ignore: []string{"position", "setoption", "ucinewgame"},
+	}
+}
+
+func TestInitializeTimeout(t *testing.T) {
+	opts := fakeOptions(t, fakeScript{Steps: []fakeStep{{Expect: "uci", Hang: true}}})
+	opts.CommandTimeout = 200 * time.Millisecond
+
+	start := time.Now()
+	_, err := NewHandlerWithOptions(opts)
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Handshake took %v, expected it to time out", elapsed)
+	}
+}
+
+func TestConsecutiveSearches(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, echoScript()))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	// Lines written in a single burst must not be lost between calls
+	for i := 0; i < 3; i++ {
+		result, err := handler.SearchWithLimits("8/8/8/8/8/8/8/K6k w - - 0 1", uci.SearchLimits{Depth: 2})
+		if err != nil {
+			t.Fatalf("Search %d: unexpected error: %v", i, err)
+		}
+
+		if result.BestMove != "e2e4" || result.Ponder != "e7e5" {
+			t.Errorf("Search %d: got bestmove %s ponder %s", i, result.BestMove, result.Ponder)
+		}
+
+		if len(result.Info) != 2 {
+			t.Errorf("Search %d: expected 2 info lines, got %d", i, len(result.Info))
+		}
+	}
+}
+
+func TestSearchContextCancel(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, echoScript()))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := handler.SearchContext(ctx, "8/8/8/8/8/8/8/K6k w - - 0 1", uci.SearchLimits{Depth: 99}); err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+
+	// The stopped search's best move must not be returned for the next search
+	result, err := handler.SearchWithLimits("8/8/8/8/8/8/8/K6k w - - 0 1", uci.SearchLimits{Depth: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.BestMove != "e2e4" {
+		t.Errorf("Expected e2e4, got %s", result.BestMove)
+	}
+}
+
+// positionScript answers every search with the full move number of the last position,
+// so a reply for another caller's position is detected
+func positionScript() fakeScript {
+	return fakeScript{
+		Steps: []fakeStep{uciHandshake()},
+		Always: []fakeStep{{
+			Expect:  "go*",
+			Delay:   10 * time.Millisecond,
+			Respond: []string{"info depth 1 score cp 0 pv m{fullmove}", "bestmove m{fullmove}"},
+		}},
+		Ignore: []string{"position", "setoption", "ucinewgame"},
+	}
+}
+
+func TestConcurrentSearches(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, positionScript()))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 1; i <= 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				moveNumber := worker*10 + j
+				fen := fmt.Sprintf("8/8/8/8/8/8/8/K6k w - - 0 %d", moveNumber)
+
+				switch j % 4 {
+				case 0:
+					move, err := handler.GetMove(fen)
+					if err != nil {
+						errs <- err
+					} else if move != fmt.Sprintf("m%d", moveNumber) {
+						errs <- fmt.Errorf("got %s for move number %d", move, moveNumber)
+					}
+				case 1:
+					if err := handler.SetOption("Hash", "16"); err != nil {
+						errs <- err
+					}
+				case 2:
+					updates, err := handler.Analyze(context.Background(), fen, uci.SearchLimits{Depth: 1})
+					if err != nil {
+						errs <- err
+						continue
+					}
+					for update := range updates {
+						if update.Done && update.BestMove != fmt.Sprintf("m%d", moveNumber) {
+							errs <- fmt.Errorf("analysis got %s for move number %d", update.BestMove, moveNumber)
+						}
+					}
+				case 3:
+					candidates, err := handler.MultiPV(fen, 1, uci.SearchLimits{Depth: 1})
+					if err != nil {
+						errs <- err
+					} else if len(candidates) != 1 || candidates[0].Move != fmt.Sprintf("m%d", moveNumber) {
+						errs <- fmt.Errorf("multipv got %+v for move number %d", candidates, moveNumber)
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestAcquireContext(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, echoScript()))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	// Hold the engine with an analysis that only ends when stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := handler.Analyze(ctx, "8/8/8/8/8/8/8/K6k w - - 0 1", uci.SearchLimits{Depth: 99})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	if _, err := handler.SearchContext(waitCtx, "8/8/8/8/8/8/8/K6k w - - 0 1", uci.SearchLimits{Depth: 2}); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded while the engine is busy, got %v", err)
+	}
+
+	cancel()
+	for range updates {
+	}
+
+	if _, err := handler.SearchWithLimits("8/8/8/8/8/8/8/K6k w - - 0 1", uci.SearchLimits{Depth: 2}); err != nil {
+		t.Errorf("Unexpected error after analysis: %v", err)
+	}
+}
+
+func TestFakeSearchInfo(t *testing.T) {
+	fen := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{Steps: []fakeStep{
+		uciHandshake(),
+		{Expect: "position fen " + fen},
+		{Expect: "go depth 2", Respond: []string{
+			"info string NNUE enabled",
+			"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+			"info depth x score cp 30",
+			"info depth 2 seldepth 3 multipv 1 score cp 25 upperbound nodes 120 nps 60000 hashfull 1 time 2 pv e2e4 e7e5",
+			"bestmove e2e4 ponder e7e5",
+		}},
+	}}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	result, err := handler.SearchWithLimits(fen, uci.SearchLimits{Depth: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.BestMove != "e2e4" || result.Ponder != "e7e5" {
+		t.Errorf("Got bestmove %s ponder %s", result.BestMove, result.Ponder)
+	}
+
+	// The malformed info line is dropped
+	if len(result.Info) != 3 {
+		t.Fatalf("Expected 3 info lines, got %d", len(result.Info))
+	}
+
+	info, ok := result.Final()
+	if !ok {
+		t.Fatal("Expected a scored info line")
+	}
+	if info.Depth != 2 || info.Score.CP != 25 || !info.Score.UpperBound || info.HashFull != 1 {
+		t.Errorf("Unexpected final info %+v", info)
+	}
+}
+
+func TestFakeMalformedOutput(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{Steps: []fakeStep{
+		{Expect: "uci", Respond: []string{"garbage before handshake", "", "uciok"}},
+		{Expect: "position*"},
+		{Expect: "go*", Respond: []string{"bestmove", "nonsense", "info score", "bestmove g1f3"}},
+	}}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	move, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if move != "g1f3" {
+		t.Errorf("Expected g1f3, got %s", move)
+	}
+}
+
+func TestFakeSearchTimeout(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{Steps: []fakeStep{
+		uciHandshake(),
+		{Expect: "position*"},
+		{Expect: "go movetime 100"},
+		{Expect: "stop", Respond: []string{"bestmove a2a3"}},
+		{Expect: "position*"},
+		{Expect: "go movetime 100", Respond: []string{"bestmove e2e4"}},
+	}}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	limits := uci.SearchLimits{MoveTime: 100 * time.Millisecond}
+	if _, err := handler.SearchWithLimits("8/8/8/8/8/8/8/K6k w - - 0 1", limits); err == nil {
+		t.Fatal("Expected a timeout but got none")
+	}
+
+	// The late best move of the stopped search is not returned
+	result, err := handler.SearchWithLimits("8/8/8/8/8/8/8/K6k w - - 0 1", limits)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.BestMove != "e2e4" {
+		t.Errorf("Expected e2e4, got %s", result.BestMove)
+	}
+}
+
+func TestFakeHandshakeFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		script fakeScript
+	}{
+		{
+			name:   "No uciok",
+			script: fakeScript{Steps: []fakeStep{{Expect: "uci", Respond: []string{"id name Silent"}}}},
+		},
+		{
+			name:   "No readyok",
+			script: fakeScript{Steps: []fakeStep{uciHandshake(), {Expect: "isready", Hang: true}}},
+		},
+		{
+			name:   "Crash",
+			script: fakeScript{Steps: []fakeStep{{Expect: "uci", ExitCode: 1}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, err := NewHandlerWithOptions(fakeOptions(t, test.script))
+			if err == nil {
+				handler.Close()
+				t.Fatal("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestFakeCrashDuringSearch(t *testing.T) {
+	fen := "8/8/8/8/8/8/8/K6k w - - 0 1"
+	handler, err := NewHandlerWithOptions(fakeOptions(t,
+		fakeScript{Steps: []fakeStep{
+			uciHandshake(),
+			{Expect: "setoption name Hash value 64"},
+			{Expect: "position fen " + fen},
+			{Expect: "go*", Respond: []string{"info depth 1 score cp 5 pv e2e4"}, ExitCode: 139},
+		}},
+		// The restarted engine gets the option and position replayed before the retry
+		fakeScript{Steps: []fakeStep{
+			uciHandshake(),
+			{Expect: "setoption name Hash value 64"},
+			{Expect: "position fen " + fen},
+			{Expect: "position fen " + fen},
+			{Expect: "go*", Respond: []string{"bestmove e2e4"}},
+		}},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	if err := handler.SetOption("Hash", "64"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	move, err := handler.GetMove(fen)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if move != "e2e4" {
+		t.Errorf("Expected e2e4, got %s", move)
+	}
+
+	if stats := handler.Stats(); stats.Crashes != 1 || stats.Restarts != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestFakeCrashDuringAnalysis(t *testing.T) {
+	fen := "8/8/8/8/8/8/8/K6k w - - 0 1"
+	handler, err := NewHandlerWithOptions(fakeOptions(t,
+		fakeScript{Steps: []fakeStep{
+			uciHandshake(),
+			{Expect: "position fen " + fen},
+			{Expect: "go depth 5", Respond: []string{"info depth 1 score cp 5 pv e2e4"}, ExitCode: 139},
+		}},
+		// The restarted engine gets the position replayed before the analysis starts again
+		fakeScript{Steps: []fakeStep{
+			uciHandshake(),
+			{Expect: "position fen " + fen},
+			{Expect: "position fen " + fen},
+			{Expect: "go depth 5", Respond: []string{"info depth 5 score cp 20 pv d2d4", "bestmove d2d4"}},
+		}},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	updates, err := handler.Analyze(context.Background(), fen, uci.SearchLimits{Depth: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var final uci.AnalysisUpdate
+	for update := range updates {
+		final = update
+	}
+	if final.Err != nil {
+		t.Fatalf("Unexpected error: %v", final.Err)
+	}
+	if final.BestMove != "d2d4" {
+		t.Errorf("Expected d2d4, got %s", final.BestMove)
+	}
+
+	if stats := handler.Stats(); stats.Crashes != 1 || stats.Restarts != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestFakeEngineID(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{Steps: []fakeStep{uciHandshake()}}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	expected := uci.ID{Name: "Fake", Author: "Envoy"}
+	if id := handler.ID(); id != expected {
+		t.Errorf("Expected %+v, got %+v", expected, id)
+	}
+}
+
+func TestFakeGetMoveWithHistory(t *testing.T) {
+	start := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{Steps: []fakeStep{
+		uciHandshake(),
+		{Expect: "position fen " + start + " moves g1f3 g8f6 f3g1 f6g8"},
+		{Expect: "go movetime 1000", Respond: []string{"bestmove g1f3"}},
+	}}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	move, err := handler.GetMoveWithHistory(start, []string{"g1f3", "g8f6", "f3g1", "f6g8"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if move != "g1f3" {
+		t.Errorf("Expected g1f3, got %s", move)
+	}
+}
+
+func TestFakeNoLegalMove(t *testing.T) {
+	fen := "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1"
+	handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{Steps: []fakeStep{
+		uciHandshake(),
+		{Expect: "position fen " + fen},
+		{Expect: "go movetime 1000", Respond: []string{"info depth 0 score cp 0", "bestmove (none)"}},
+		{Expect: "position fen " + fen},
+		{Expect: "go movetime 1000", Respond: []string{"bestmove (none)"}},
+	}}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	if move, err := handler.GetMove(fen); err != uci.ErrNoLegalMove {
+		t.Errorf("Expected ErrNoLegalMove, got move %q and error %v", move, err)
+	}
+	if result, err := handler.Search(fen); err != uci.ErrNoLegalMove {
+		t.Errorf("Expected ErrNoLegalMove, got %+v and error %v", result, err)
+	}
+}
+
+func TestFakeHungEngine(t *testing.T) {
+	fen := "8/8/8/8/8/8/8/K6k w - - 0 1"
+	ponder := &SearchResult{BestMove: "a1a2", Ponder: "h1h2"}
+
+	tests := []struct {
+		name  string
+		steps []fakeStep
+		run   func(h *Handler) error
+	}{
+		{
+			name:  "Depth search",
+			steps: []fakeStep{{Expect: "position*"}, {Expect: "go depth 30", Hang: true}},
+			run: func(h *Handler) error {
+				_, err := h.SearchWithLimits(fen, uci.SearchLimits{Depth: 30})
+				return err
+			},
+		},
+		{
+			name:  "Clock search",
+			steps: []fakeStep{{Expect: "position*"}, {Expect: "go wtime 100 btime 200", Hang: true}},
+			run: func(h *Handler) error {
+				_, err := h.SearchWithLimits(fen, uci.SearchLimits{WTime: 100 * time.Millisecond, BTime: 200 * time.Millisecond})
+				return err
+			},
+		},
+		{
+			name: "Ponderhit",
+			steps: []fakeStep{
+				{Expect: "setoption name Ponder value true"},
+				{Expect: "position*"},
+				{Expect: "go ponder movetime 100"},
+				{Expect: "ponderhit", Hang: true},
+			},
+			run: func(h *Handler) error {
+				if err := h.StartPonder(uci.Position{FEN: fen}, ponder, uci.SearchLimits{MoveTime: 100 * time.Millisecond}); err != nil {
+					t.Fatalf("Failed to start pondering: %v", err)
+				}
+				_, err := h.PonderHit()
+				return err
+			},
+		},
+		{
+			name: "Bounded analysis",
+			steps: []fakeStep{
+				{Expect: "position*"},
+				{Expect: "go depth 30"},
+				{Expect: "stop", Respond: []string{"bestmove e2e4"}},
+			},
+			run: func(h *Handler) error {
+				updates, err := h.Analyze(context.Background(), fen, uci.SearchLimits{Depth: 30})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				var final uci.AnalysisUpdate
+				for update := range updates {
+					final = update
+				}
+				return final.Err
+			},
+		},
+		{
+			name:  "Stopped analysis",
+			steps: []fakeStep{{Expect: "position*"}, {Expect: "go infinite", Hang: true}},
+			run: func(h *Handler) error {
+				ctx, cancel := context.WithCancel(context.Background())
+				updates, err := h.Analyze(ctx, fen, uci.SearchLimits{Infinite: true})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				cancel()
+
+				var final uci.AnalysisUpdate
+				for update := range updates {
+					final = update
+				}
+				return final.Err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := fakeOptions(t, fakeScript{Steps: append([]fakeStep{uciHandshake()}, test.steps...)})
+			opts.SearchTimeout = 200 * time.Millisecond
+			handler, err := NewHandlerWithOptions(opts)
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+			defer handler.Close()
+
+			start := time.Now()
+			if err := test.run(handler); err == nil {
+				t.Error("Expected error but got none")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Gave up on the hung engine after %v", elapsed)
+			}
+		})
+	}
+}
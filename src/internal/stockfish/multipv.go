@@ -0,0 +1,137 @@
+package stockfish
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+// Candidate is one of the ranked moves returned by a MultiPV search
+type Candidate struct {
+	Move  string
+	Score uci.Score
+	PV    []string
+	Depth int
+}
+
+// MultiPV searches the given position and returns up to n candidate moves, best first.
+// The engine's previous MultiPV setting is restored afterwards.
+func (h *Handler) MultiPV(fen string, n int, limits uci.SearchLimits) (candidates []Candidate, err error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid number of lines: %d", n)
+	}
+
+	ctx := context.Background()
+	if err := h.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer h.release()
+
+	previous, set := h.options["MultiPV"]
+	if !set {
+		if previous = h.opts.EngineOptions["MultiPV"]; previous == "" {
+			previous = "1"
+		}
+	}
+	if err := h.setOption(ctx, "MultiPV", strconv.Itoa(n)); err != nil {
+		return nil, err
+	}
+	defer func() {
+		restoreErr := h.setOption(ctx, "MultiPV", previous)
+		if !set {
+			delete(h.options, "MultiPV")
+		}
+		if restoreErr != nil && err == nil {
+			candidates, err = nil, fmt.Errorf("failed to restore MultiPV: %v", restoreErr)
+		}
+	}()
+
+	result, err := h.search(ctx, uci.Position{FEN: fen}, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectCandidates(result.Info, n), nil
+}
+
+// collectCandidates returns the lines of the deepest iteration that reported every
+// multipv index, so that candidates from different depths are not ranked together.
+// A position with fewer legal moves than n reports fewer indices.
+func collectCandidates(infos []uci.Info, n int) []Candidate {
+	depths := make(map[int]map[int]uci.Info)
+	want := 0
+	for _, info := range infos {
+		if !info.HasScore || len(info.PV) == 0 || info.MultiPV < 1 || info.MultiPV > n {
+			continue
+		}
+		lines, ok := depths[info.Depth]
+		if !ok {
+			lines = make(map[int]uci.Info)
+			depths[info.Depth] = lines
+		}
+		// Prefer exact scores over bounds from an unfinished iteration
+		if prev, ok := lines[info.MultiPV]; ok && isExact(prev.Score) && !isExact(info.Score) {
+			continue
+		}
+		lines[info.MultiPV] = info
+		if info.MultiPV > want {
+			want = info.MultiPV
+		}
+	}
+
+	order := make([]int, 0, len(depths))
+	for depth := range depths {
+		order = append(order, depth)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(order)))
+
+	// Without a complete iteration, fall back to the deepest one with the most lines
+	best := -1
+	for _, depth := range order {
+		if complete(depths[depth], want) {
+			best = depth
+			break
+		}
+		if best < 0 || len(depths[depth]) > len(depths[best]) {
+			best = depth
+		}
+	}
+	lines := depths[best]
+
+	indexes := make([]int, 0, len(lines))
+	for index := range lines {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	candidates := make([]Candidate, 0, len(indexes))
+	for _, index := range indexes {
+		info := lines[index]
+		candidates = append(candidates, Candidate{
+			Move:  info.PV[0],
+			Score: info.Score,
+			PV:    info.PV,
+			Depth: info.Depth,
+		})
+	}
+
+	return candidates
+}
+
+// complete returns whether lines holds every multipv index from 1 to want
+func complete(lines map[int]uci.Info, want int) bool {
+	for index := 1; index <= want; index++ {
+		if _, ok := lines[index]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isExact returns whether the score is neither a lower nor an upper bound
+func isExact(s uci.Score) bool {
+	return !s.LowerBound && !s.UpperBound
+}
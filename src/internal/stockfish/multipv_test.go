@@ -0,0 +1,180 @@
+package stockfish
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+func TestCollectCandidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		n        int
+		expected []Candidate
+	}{
+		{
+			name: "Deepest complete iteration",
+			lines: []string{
+				"info depth 1 multipv 1 score cp 20 pv e2e4",
+				"info depth 1 multipv 2 score cp 15 pv d2d4",
+				"info depth 1 multipv 3 score cp 10 pv g1f3",
+				"info depth 2 multipv 1 score cp 30 pv d2d4 d7d5",
+				"info depth 2 multipv 2 score cp 25 pv e2e4 e7e5",
+				"info depth 2 multipv 3 score cp 5 pv c2c4",
+				"info depth 3 multipv 1 score cp 40 lowerbound pv d2d4",
+				"info depth 3 multipv 4 score cp 0 pv a2a3",
+				"info depth 3 currmove b1c3 currmovenumber 5",
+			},
+			n: 3,
+			expected: []Candidate{
+				{Move: "d2d4", Score: uci.Score{CP: 30}, PV: []string{"d2d4", "d7d5"}, Depth: 2},
+				{Move: "e2e4", Score: uci.Score{CP: 25}, PV: []string{"e2e4", "e7e5"}, Depth: 2},
+				{Move: "c2c4", Score: uci.Score{CP: 5}, PV: []string{"c2c4"}, Depth: 2},
+			},
+		},
+		{
+			name: "Unfinished iteration is not mixed in",
+			lines: []string{
+				"info depth 4 multipv 1 score cp 20 pv e2e4",
+				"info depth 4 multipv 2 score cp 15 pv d2d4",
+				"info depth 5 multipv 1 score cp 60 pv g1f3",
+			},
+			n: 2,
+			expected: []Candidate{
+				{Move: "e2e4", Score: uci.Score{CP: 20}, PV: []string{"e2e4"}, Depth: 4},
+				{Move: "d2d4", Score: uci.Score{CP: 15}, PV: []string{"d2d4"}, Depth: 4},
+			},
+		},
+		{
+			name: "Fewer legal moves than lines",
+			lines: []string{
+				"info depth 1 multipv 1 score cp 0 pv a1b1",
+				"info depth 9 multipv 1 score cp 0 pv a1a2",
+			},
+			n: 3,
+			expected: []Candidate{
+				{Move: "a1a2", Score: uci.Score{CP: 0}, PV: []string{"a1a2"}, Depth: 9},
+			},
+		},
+		{
+			name: "No complete iteration",
+			lines: []string{
+				"info depth 1 multipv 2 score cp 10 pv d2d4",
+				"info depth 1 multipv 3 score cp 5 pv c2c4",
+				"info depth 2 multipv 3 score cp 0 pv g1f3",
+			},
+			n: 3,
+			expected: []Candidate{
+				{Move: "d2d4", Score: uci.Score{CP: 10}, PV: []string{"d2d4"}, Depth: 1},
+				{Move: "c2c4", Score: uci.Score{CP: 5}, PV: []string{"c2c4"}, Depth: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var infos []uci.Info
+			for _, line := range test.lines {
+				info, err := uci.ParseInfo(line)
+				if err != nil {
+					t.Fatalf("Failed to parse %q: %v", line, err)
+				}
+				infos = append(infos, info)
+			}
+
+			candidates := collectCandidates(infos, test.n)
+			if !reflect.DeepEqual(candidates, test.expected) {
+				t.Errorf("collectCandidates() = %+v; want %+v", candidates, test.expected)
+			}
+		})
+	}
+}
+
+func TestMultiPV(t *testing.T) {
+	handler := newStockfishHandler(t)
+	defer handler.Close()
+
+	candidates, err := handler.MultiPV("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 3, uci.SearchLimits{Depth: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(candidates) != 3 {
+		t.Fatalf("Expected 3 candidates, got %d", len(candidates))
+	}
+
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if seen[candidate.Move] {
+			t.Errorf("Duplicate candidate move %s", candidate.Move)
+		}
+		seen[candidate.Move] = true
+	}
+
+	if _, err := handler.MultiPV("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0, uci.SearchLimits{Depth: 10}); err == nil {
+		t.Error("Expected error for zero lines but got none")
+	}
+}
+
+func TestFakeMultiPVRestoresOption(t *testing.T) {
+	fen := "8/8/8/8/8/8/8/K6k w - - 0 1"
+	search := []fakeStep{
+		{Expect: "setoption name MultiPV value 2"},
+		{Expect: "position fen " + fen},
+		{Expect: "go depth 5", Respond: []string{
+			"info depth 5 multipv 1 score cp 10 pv a1a2",
+			"info depth 5 multipv 2 score cp 0 pv a1b1",
+			"bestmove a1a2",
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		previous    string
+		restore     fakeStep
+		expectError bool
+	}{
+		{name: "Default", restore: fakeStep{Expect: "setoption name MultiPV value 1"}},
+		{name: "Configured", previous: "4", restore: fakeStep{Expect: "setoption name MultiPV value 4"}},
+		{name: "Failed restore", restore: fakeStep{Expect: "setoption name MultiPV value 1", ExitCode: 1}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			steps := []fakeStep{uciHandshake()}
+			if test.previous != "" {
+				steps = append(steps, fakeStep{Expect: "setoption name MultiPV value " + test.previous})
+			}
+			steps = append(steps, search...)
+			steps = append(steps, test.restore)
+
+			handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{Steps: steps}))
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+			defer handler.Close()
+
+			if test.previous != "" {
+				if err := handler.SetOption("MultiPV", test.previous); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			candidates, err := handler.MultiPV(fen, 2, uci.SearchLimits{Depth: 5})
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(candidates) != 2 {
+				t.Errorf("Expected 2 candidates, got %d", len(candidates))
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package stockfish
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+// StartPonder starts searching the position the engine expects after its own best move
+// and the ponder move from result, both played from position, the position result was
+// searched in. The limits are those that will apply once the opponent has moved. The
+// search runs until PonderHit or StopPonder is called.
+func (h *Handler) StartPonder(position uci.Position, result *SearchResult, limits uci.SearchLimits) error {
+	if result == nil || result.BestMove == "" || result.Ponder == "" {
+		return fmt.Errorf("no ponder move available")
+	}
+
+	ctx := context.Background()
+	if err := h.acquire(ctx); err != nil {
+		return err
+	}
+	defer h.release()
+
+	if err := h.prepareSearch(ctx); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	ponderEnabled := h.ponderEnabled
+	h.mu.Unlock()
+	if !ponderEnabled {
+		if err := h.setOption(ctx, "Ponder", "true"); err != nil {
+			return err
+		}
+		h.mu.Lock()
+		h.ponderEnabled = true
+		h.mu.Unlock()
+	}
+
+	// Set the position after the expected reply
+	h.position = position.Play(result.BestMove, result.Ponder).Command()
+	if err := h.send(h.position); err != nil {
+		return fmt.Errorf("failed to set position: %v", err)
+	}
+
+	limits.Ponder = true
+	limits.Infinite = false
+	if err := h.send(limits.GoCommand()); err != nil {
+		return fmt.Errorf("failed to start pondering: %v", err)
+	}
+	h.ponderLimits = limits
+
+	h.setPonderMove(result.Ponder)
+	return nil
+}
+
+// PonderMove returns the opponent move the engine is pondering on, or an empty string
+func (h *Handler) PonderMove() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ponderMove
+}
+
+func (h *Handler) setPonderMove(move string) {
+	h.mu.Lock()
+	h.ponderMove = move
+	h.mu.Unlock()
+}
+
+// PonderHit tells the engine the opponent played the expected move and waits for its best move
+func (h *Handler) PonderHit() (*SearchResult, error) {
+	ctx := context.Background()
+	if err := h.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer h.release()
+
+	return h.ponderHit(ctx)
+}
+
+// ponderHit converts the ponder search; the caller must own the engine
+func (h *Handler) ponderHit(ctx context.Context) (*SearchResult, error) {
+	if h.PonderMove() == "" {
+		return nil, fmt.Errorf("engine is not pondering")
+	}
+	h.setPonderMove("")
+
+	if err := h.send("ponderhit"); err != nil {
+		return nil, fmt.Errorf("failed to send ponderhit: %v", err)
+	}
+
+	// After ponderhit the search runs under its own limits
+	return h.readSearchResult(ctx, h.searchTimeout(h.ponderLimits))
+}
+
+// StopPonder stops a ponder search after the opponent played a different move.
+// The engine's best move for the abandoned search is discarded.
+func (h *Handler) StopPonder() error {
+	if err := h.acquire(context.Background()); err != nil {
+		return err
+	}
+	defer h.release()
+
+	return h.stopPonder()
+}
+
+// stopPonder stops the ponder search, if any; the caller must own the engine
+func (h *Handler) stopPonder() error {
+	if h.PonderMove() == "" {
+		return nil
+	}
+	h.setPonderMove("")
+
+	if err := h.stopSearch(); err != nil {
+		return fmt.Errorf("failed to stop pondering: %v", err)
+	}
+
+	return nil
+}
+
+// ResolvePonder returns the engine's reply once the opponent has played move. If the engine
+// was pondering on that move the ponder search is converted with ponderhit; otherwise it is
+// stopped and the position reached by playing move from position is searched within limits.
+func (h *Handler) ResolvePonder(move string, position uci.Position, limits uci.SearchLimits) (*SearchResult, error) {
+	ctx := context.Background()
+	if err := h.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer h.release()
+
+	if ponderMove := h.PonderMove(); ponderMove != "" && ponderMove == move {
+		return h.ponderHit(ctx)
+	}
+
+	return h.search(ctx, position.Play(move), limits)
+}
@@ -0,0 +1,103 @@
+package stockfish
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+func TestPonderHit(t *testing.T) {
+	handler := newStockfishHandler(t)
+	defer handler.Close()
+
+	position := uci.Position{}
+	result, err := handler.SearchPosition(context.Background(), position, uci.SearchLimits{Depth: 12})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.Ponder == "" {
+		t.Skip("Engine did not return a ponder move")
+	}
+
+	limits := uci.SearchLimits{WTime: time.Minute, BTime: time.Minute}
+	if err := handler.StartPonder(position, result, limits); err != nil {
+		t.Fatalf("Failed to start pondering: %v", err)
+	}
+
+	if handler.PonderMove() != result.Ponder {
+		t.Errorf("Expected ponder move %s, got %s", result.Ponder, handler.PonderMove())
+	}
+
+	reply, err := handler.ResolvePonder(result.Ponder, position.Play(result.BestMove), limits)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if reply.BestMove == "" {
+		t.Error("Expected a best move after ponderhit")
+	}
+
+	if handler.PonderMove() != "" {
+		t.Error("Expected pondering to be finished")
+	}
+}
+
+func TestStopPonder(t *testing.T) {
+	handler := newStockfishHandler(t)
+	defer handler.Close()
+
+	position := uci.Position{FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"}
+	result := &SearchResult{BestMove: "e2e4", Ponder: "e7e5"}
+	if err := handler.StartPonder(position, result, uci.SearchLimits{MoveTime: time.Second}); err != nil {
+		t.Fatalf("Failed to start pondering: %v", err)
+	}
+
+	// The opponent played something else
+	reply, err := handler.ResolvePonder("c7c5", position.Play("e2e4"), uci.SearchLimits{Depth: 8})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if reply.BestMove == "" {
+		t.Error("Expected a best move after stopping the ponder search")
+	}
+
+	if err := handler.StartPonder(position, &SearchResult{BestMove: "e2e4"}, uci.SearchLimits{}); err == nil {
+		t.Error("Expected error without a ponder move but got none")
+	}
+}
+
+func TestFakePonderKeepsHistory(t *testing.T) {
+	fen := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{Steps: []fakeStep{
+		uciHandshake(),
+		{Expect: "setoption name Ponder value true"},
+		{Expect: "position fen " + fen + " moves d2d4 d7d5 e2e4 e7e5"},
+		{Expect: "go ponder movetime 100"},
+		{Expect: "stop", Respond: []string{"bestmove g1f3"}},
+		{Expect: "position fen " + fen + " moves d2d4 d7d5 e2e4 c7c5"},
+		{Expect: "go depth 5", Respond: []string{"bestmove g1f3"}},
+	}}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	position := uci.Position{FEN: fen, Moves: []string{"d2d4", "d7d5"}}
+	result := &SearchResult{BestMove: "e2e4", Ponder: "e7e5"}
+	if err := handler.StartPonder(position, result, uci.SearchLimits{MoveTime: 100 * time.Millisecond}); err != nil {
+		t.Fatalf("Failed to start pondering: %v", err)
+	}
+
+	// The opponent played something else, so the game so far is searched
+	reply, err := handler.ResolvePonder("c7c5", position.Play(result.BestMove), uci.SearchLimits{Depth: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reply.BestMove != "g1f3" {
+		t.Errorf("Expected g1f3, got %s", reply.BestMove)
+	}
+}
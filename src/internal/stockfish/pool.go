@@ -0,0 +1,325 @@
+package stockfish
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PoolOptions configures a Pool
+type PoolOptions struct {
+	Size                int                            // Engines started up front and kept available
+	MaxSize             int                            // Maximum number of live engines, defaults to Size
+	Engine              Options                        // Options shared by every engine
+	InstanceOptions     func(id int) map[string]string // Optional extra UCI options for the engine with the given ID
+	HealthCheckInterval time.Duration                  // How often idle engines are checked, zero disables background checks
+}
+
+// PoolStats reports the state of a Pool
+type PoolStats struct {
+	Idle     int // Engines waiting to be checked out
+	InUse    int // Engines currently checked out
+	Replaced int // Dead engines that have been discarded
+}
+
+// Pool keeps a set of running engines that can be checked out for a game and returned.
+// A Pool is safe for concurrent use.
+type Pool struct {
+	opts   PoolOptions
+	tokens chan struct{} // One token per engine that is checked out, checked or being started
+
+	mu       sync.Mutex
+	idle     []*Handler
+	ids      map[*Handler]int
+	nextID   int
+	inUse    int
+	replaced int
+	closed   bool
+	done     chan struct{}
+}
+
+// NewPool starts opts.Size engines and returns a pool serving them
+func NewPool(opts PoolOptions) (*Pool, error) {
+	if opts.Size < 0 {
+		return nil, fmt.Errorf("invalid pool size: %d", opts.Size)
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = opts.Size
+	}
+	if opts.MaxSize < 1 || opts.MaxSize < opts.Size {
+		return nil, fmt.Errorf("invalid maximum pool size: %d", opts.MaxSize)
+	}
+
+	p := &Pool{
+		opts:   opts,
+		tokens: make(chan struct{}, opts.MaxSize),
+		ids:    make(map[*Handler]int),
+		done:   make(chan struct{}),
+	}
+
+	for i := 0; i < opts.Size; i++ {
+		h, err := p.start()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.idle = append(p.idle, h)
+	}
+
+	if opts.HealthCheckInterval > 0 {
+		go p.healthLoop()
+	}
+
+	return p, nil
+}
+
+// start launches a new engine with the next instance ID
+func (p *Pool) start() (*Handler, error) {
+	p.mu.Lock()
+	id := p.nextID
+	p.nextID++
+	p.mu.Unlock()
+
+	opts := p.opts.Engine
+
+	// Name the instance in transcripts after its pool ID
+	opts.InstanceID = fmt.Sprintf("engine-%d", id)
+	if p.opts.Engine.InstanceID != "" {
+		opts.InstanceID = fmt.Sprintf("%s-%d", p.opts.Engine.InstanceID, id)
+	}
+	if p.opts.InstanceOptions != nil {
+		engineOptions := make(map[string]string)
+		for name, value := range p.opts.Engine.EngineOptions {
+			engineOptions[name] = value
+		}
+		for name, value := range p.opts.InstanceOptions(id) {
+			engineOptions[name] = value
+		}
+		opts.EngineOptions = engineOptions
+	}
+
+	h, err := NewHandlerWithOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start engine %d: %v", id, err)
+	}
+
+	p.mu.Lock()
+	p.ids[h] = id
+	p.mu.Unlock()
+
+	return h, nil
+}
+
+// discard closes a dead or unusable engine
+func (p *Pool) discard(h *Handler) {
+	h.Close()
+
+	p.mu.Lock()
+	delete(p.ids, h)
+	p.replaced++
+	p.mu.Unlock()
+}
+
+// tryAcquire takes a token without blocking
+func (p *Pool) tryAcquire() bool {
+	select {
+	case p.tokens <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Get checks out a healthy engine, starting a new one if none is idle. It blocks while
+// MaxSize engines are checked out, until one is returned or ctx is done.
+func (p *Pool) Get(ctx context.Context) (*Handler, error) {
+	select {
+	case p.tokens <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			<-p.tokens
+			return nil, fmt.Errorf("pool is closed")
+		}
+
+		var h *Handler
+		if n := len(p.idle); n > 0 {
+			h = p.idle[n-1]
+			p.idle = p.idle[:n-1]
+		}
+		p.inUse++
+		p.mu.Unlock()
+
+		if h == nil {
+			var err error
+			if h, err = p.start(); err != nil {
+				p.mu.Lock()
+				p.inUse--
+				p.mu.Unlock()
+				<-p.tokens
+				return nil, err
+			}
+			return h, nil
+		}
+
+		if err := h.Ping(); err != nil {
+			// Replace dead engines instead of handing them out
+			p.mu.Lock()
+			p.inUse--
+			p.mu.Unlock()
+			p.discard(h)
+			continue
+		}
+
+		return h, nil
+	}
+}
+
+// Put returns an engine to the pool. The engine is reset with ucinewgame; engines
+// that fail to reset are discarded.
+func (p *Pool) Put(h *Handler) {
+	defer func() { <-p.tokens }()
+
+	p.mu.Lock()
+	p.inUse--
+	closed := p.closed
+	p.mu.Unlock()
+
+	if closed {
+		h.Close()
+		return
+	}
+
+	if err := h.NewGame(); err != nil {
+		p.discard(h)
+		return
+	}
+
+	p.mu.Lock()
+	p.idle = append(p.idle, h)
+	p.mu.Unlock()
+}
+
+// ID returns the instance ID of an engine started by the pool
+func (p *Pool) ID(h *Handler) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id, ok := p.ids[h]
+	return id, ok
+}
+
+// Stats returns the current pool statistics
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{
+		Idle:     len(p.idle),
+		InUse:    p.inUse,
+		Replaced: p.replaced,
+	}
+}
+
+// CheckHealth pings the idle engines, discards dead ones and starts replacements until
+// at least Size engines are live again. Like Get, every check holds a token, so the
+// pool never exceeds MaxSize engines.
+func (p *Pool) CheckHealth() error {
+	p.mu.Lock()
+	n := len(p.idle)
+	p.mu.Unlock()
+
+	// Check the longest idle engines first; Get hands out the most recently returned
+	for i := 0; i < n; i++ {
+		if !p.tryAcquire() {
+			return nil
+		}
+
+		p.mu.Lock()
+		if p.closed || len(p.idle) == 0 {
+			p.mu.Unlock()
+			<-p.tokens
+			break
+		}
+		h := p.idle[0]
+		p.idle = p.idle[1:]
+		p.mu.Unlock()
+
+		if err := h.Ping(); err != nil {
+			p.discard(h)
+		} else {
+			p.mu.Lock()
+			p.idle = append(p.idle, h)
+			p.mu.Unlock()
+		}
+		<-p.tokens
+	}
+
+	for {
+		p.mu.Lock()
+		live := len(p.idle) + p.inUse
+		closed := p.closed
+		p.mu.Unlock()
+		if closed || live >= p.opts.Size || !p.tryAcquire() {
+			return nil
+		}
+
+		h, err := p.start()
+		if err != nil {
+			<-p.tokens
+			return err
+		}
+
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			h.Close()
+			<-p.tokens
+			return nil
+		}
+		p.idle = append(p.idle, h)
+		p.mu.Unlock()
+		<-p.tokens
+	}
+}
+
+// healthLoop runs CheckHealth periodically until the pool is closed
+func (p *Pool) healthLoop() {
+	ticker := time.NewTicker(p.opts.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.CheckHealth()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// Close stops all idle engines. Engines still checked out are closed when returned.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	close(p.done)
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, h := range idle {
+		if err := h.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
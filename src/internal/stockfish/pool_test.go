@@ -0,0 +1,182 @@
+package stockfish
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// optionScript answers every search with the last option value it was given
+func optionScript() fakeScript {
+	return fakeScript{
+		Steps:  []fakeStep{uciHandshake()},
+		Always: []fakeStep{{Expect: "go*", Respond: []string{"bestmove v{value}"}}},
+		Ignore: []string{"position", "setoption", "ucinewgame"},
+	}
+}
+
+func TestPoolGetPut(t *testing.T) {
+	pool, err := NewPool(PoolOptions{Size: 2, Engine: fakeOptions(t, echoScript(), echoScript())})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	first, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first == second {
+		t.Error("Expected distinct engines")
+	}
+
+	if stats := pool.Stats(); stats.InUse != 2 || stats.Idle != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	// The pool is exhausted
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+
+	pool.Put(second)
+	third, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if third != second {
+		t.Error("Expected the returned engine to be reused")
+	}
+
+	if _, err := third.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	pool.Put(first)
+	pool.Put(third)
+	if stats := pool.Stats(); stats.InUse != 0 || stats.Idle != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestPoolMaxSize(t *testing.T) {
+	pool, err := NewPool(PoolOptions{Size: 0, MaxSize: 2, Engine: fakeOptions(t, echoScript(), echoScript())})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	if stats := pool.Stats(); stats.Idle != 0 {
+		t.Errorf("Expected no engines to be started, got %+v", stats)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Get(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+
+	if _, err := NewPool(PoolOptions{Size: 3, MaxSize: 2}); err == nil {
+		t.Error("Expected error for a maximum size below the size")
+	}
+}
+
+func TestPoolReplacesDeadEngine(t *testing.T) {
+	pool, err := NewPool(PoolOptions{Size: 1, Engine: fakeOptions(t, echoScript(), echoScript())})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	handler, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pool.Put(handler)
+
+	// Kill the idle engine behind the pool's back
+	killProcess(t, handler.proc)
+
+	replacement, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if replacement == handler {
+		t.Error("Expected the dead engine to be replaced")
+	}
+
+	if _, err := replacement.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if stats := pool.Stats(); stats.Replaced != 1 {
+		t.Errorf("Expected 1 replaced engine, got %+v", stats)
+	}
+}
+
+func TestPoolCheckHealth(t *testing.T) {
+	pool, err := NewPool(PoolOptions{Size: 2, Engine: fakeOptions(t, echoScript(), echoScript(), echoScript())})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	pool.mu.Lock()
+	killProcess(t, pool.idle[0].proc)
+	pool.mu.Unlock()
+
+	if err := pool.CheckHealth(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if stats := pool.Stats(); stats.Idle != 2 || stats.Replaced != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestPoolInstanceOptions(t *testing.T) {
+	pool, err := NewPool(PoolOptions{
+		Size:   2,
+		Engine: fakeOptions(t, optionScript(), optionScript()),
+		InstanceOptions: func(id int) map[string]string {
+			return map[string]string{"Skill Level": fmt.Sprint(id)}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	for i := 0; i < 2; i++ {
+		handler, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		id, ok := pool.ID(handler)
+		if !ok {
+			t.Fatal("Expected engine to have an instance ID")
+		}
+
+		move, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if move != fmt.Sprintf("v%d", id) {
+			t.Errorf("Engine %d got option value %s", id, move)
+		}
+	}
+}
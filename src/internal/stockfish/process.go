@@ -0,0 +1,111 @@
+package stockfish
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/shehio/envoy/src/internal/engine"
+)
+
+// startProcess starts the engine binary described by opts
+func startProcess(opts Options) (*engine.Process, error) {
+	return engine.Start(engine.Config{
+		Path:       opts.Path,
+		Args:       opts.Args,
+		Transcript: opts.Transcript,
+		InstanceID: opts.InstanceID,
+	})
+}
+
+// HandlerStats reports crash statistics for a Handler
+type HandlerStats struct {
+	Crashes  int   // Times the engine process exited unexpectedly
+	Restarts int   // Times the engine process was restarted successfully
+	LastExit error // Exit status of the last crashed process
+}
+
+// Stats returns the handler's crash statistics
+func (h *Handler) Stats() HandlerStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+// recoverCrash restarts the engine if its process has exited, returning whether a
+// restart happened. The caller must own the engine.
+func (h *Handler) recoverCrash(ctx context.Context) (bool, error) {
+	h.mu.Lock()
+	proc := h.proc
+	closed := h.closed
+	h.mu.Unlock()
+
+	if closed {
+		return false, fmt.Errorf("handler is closed")
+	}
+	if !proc.HasExited() {
+		return false, nil
+	}
+
+	h.mu.Lock()
+	h.stats.Crashes++
+	h.stats.LastExit = proc.Err()
+	h.mu.Unlock()
+
+	if err := h.restart(ctx); err != nil {
+		return true, fmt.Errorf("failed to restart engine: %v", err)
+	}
+	return true, nil
+}
+
+// restart replaces the engine process with a new one using the same options, then
+// replays the options set since and the current position. The caller must own the engine.
+func (h *Handler) restart(ctx context.Context) error {
+	h.proc.Kill()
+
+	proc, err := startProcess(h.opts)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		proc.Kill()
+		return fmt.Errorf("handler is closed")
+	}
+	h.proc = proc
+	h.ponderMove = ""
+	h.mu.Unlock()
+
+	if err := h.initializeEngine(); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(h.options))
+	for name := range h.options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := h.send(fmt.Sprintf("setoption name %s value %s", name, h.options[name])); err != nil {
+			return fmt.Errorf("failed to set option %s: %v", name, err)
+		}
+	}
+
+	if h.position != "" {
+		if err := h.send(h.position); err != nil {
+			return fmt.Errorf("failed to replay position: %v", err)
+		}
+	}
+
+	if err := h.isReady(ctx); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.stats.Restarts++
+	h.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package stockfish
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/engine"
+)
+
+func TestRestartAfterCrash(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t,
+		fakeScript{
+			Steps:  []fakeStep{uciHandshake(), {Expect: "go*", ExitCode: 3}},
+			Ignore: []string{"position", "setoption", "ucinewgame"},
+		},
+		optionScript(),
+	))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	if err := handler.SetOption("Skill Level", "7"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The first search crashes the engine; the handler restarts it and searches again
+	move, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The option set before the crash must have been replayed
+	if move != "v7" {
+		t.Errorf("Expected v7, got %s", move)
+	}
+
+	stats := handler.Stats()
+	if stats.Crashes != 1 || stats.Restarts != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.LastExit == nil {
+		t.Error("Expected the exit status of the crashed process")
+	}
+}
+
+func TestRestartWhileIdle(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, echoScript(), echoScript()))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	if _, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	old := handler.proc
+	killProcess(t, old)
+	<-old.Exited()
+
+	// Ping reports the dead engine instead of hiding it
+	if err := handler.Ping(); err == nil {
+		t.Error("Expected ping to fail for a dead engine")
+	}
+
+	if _, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if handler.proc == old {
+		t.Error("Expected a new engine process")
+	}
+
+	if stats := handler.Stats(); stats.Crashes != 1 || stats.Restarts != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCloseReapsProcess(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, echoScript()))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	proc := handler.proc
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case <-proc.Exited():
+	case <-time.After(time.Second):
+		t.Fatal("Engine process was not reaped")
+	}
+
+	if !proc.HasExited() {
+		t.Error("Expected the process to be reaped")
+	}
+
+	if _, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1"); err == nil {
+		t.Error("Expected error after close but got none")
+	}
+}
+
+func TestCloseSendsQuit(t *testing.T) {
+	handler, err := NewHandlerWithOptions(fakeOptions(t, fakeScript{
+		Steps: []fakeStep{uciHandshake(), {Expect: "quit"}},
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	proc := handler.proc
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := proc.Err(); err != nil {
+		t.Errorf("Expected a clean exit, got %v", err)
+	}
+
+	// Closing twice is harmless
+	if err := handler.Close(); err != nil {
+		t.Errorf("Unexpected error on second close: %v", err)
+	}
+}
+
+func TestCloseKillsHungEngine(t *testing.T) {
+	opts := fakeOptions(t, fakeScript{
+		Steps: []fakeStep{uciHandshake(), {Expect: "quit", Hang: true}},
+	})
+	opts.QuitTimeout = 100 * time.Millisecond
+
+	handler, err := NewHandlerWithOptions(opts)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	proc := handler.proc
+	start := time.Now()
+	if err := handler.Close(); err == nil {
+		t.Error("Expected error for an engine that had to be killed")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Close took %v", elapsed)
+	}
+
+	if !proc.HasExited() {
+		t.Error("Expected the killed process to be reaped")
+	}
+}
+
+// killProcess kills the engine behind the handler's back, as a crash would
+func killProcess(t *testing.T, proc *engine.Process) {
+	t.Helper()
+
+	process, err := os.FindProcess(proc.Pid())
+	if err != nil {
+		t.Fatalf("Failed to find engine process: %v", err)
+	}
+	if err := process.Kill(); err != nil {
+		t.Fatalf("Failed to kill engine process: %v", err)
+	}
+}
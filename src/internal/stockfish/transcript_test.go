@@ -0,0 +1,55 @@
+package stockfish
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shehio/envoy/src/internal/transcript"
+)
+
+func TestTranscript(t *testing.T) {
+	var out strings.Builder
+	opts := fakeOptions(t, fakeScript{Steps: []fakeStep{
+		uciHandshake(),
+		{Expect: "position fen 8/8/8/8/8/8/8/K6k w - - 0 1"},
+		{Expect: "go movetime 1000", Respond: []string{"info depth 1 score cp 0 pv a1a2", "bestmove a1a2"}},
+	}})
+	opts.Transcript = transcript.New(&out)
+	opts.InstanceID = "white"
+
+	handler, err := NewHandlerWithOptions(opts)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	if _, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	handler.Close()
+
+	// The transcript is complete once the process has been reaped
+	expected := []string{
+		"[white] # started",
+		"[white] > uci",
+		"[white] < id name Fake",
+		"[white] < uciok",
+		"[white] > isready",
+		"[white] < readyok",
+		"[white] > position fen 8/8/8/8/8/8/8/K6k w - - 0 1",
+		"[white] > go movetime 1000",
+		"[white] < info depth 1 score cp 0 pv a1a2",
+		"[white] < bestmove a1a2",
+		"[white] > quit",
+		"[white] # exited",
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	next := 0
+	for _, line := range lines {
+		if next < len(expected) && strings.Contains(line, expected[next]) {
+			next++
+		}
+	}
+	if next < len(expected) {
+		t.Errorf("Transcript is missing %q:\n%s", expected[next], out.String())
+	}
+}
@@ -0,0 +1,691 @@
+package tournament
+
+import "sort"
+
+// matchingEdge is an undirected edge between two vertices with a weight
+type matchingEdge struct {
+	a, b   int
+	weight int64
+}
+
+// maxWeightMatching returns a matching of maximum total weight in the graph with the
+// given number of vertices and edges, as the mate of each vertex or -1 for an
+// unmatched one. With maxCardinality set, only matchings of maximum cardinality are
+// considered. It is Edmonds' blossom algorithm with dual variables, running in
+// O(n³) time; weights must be integers, so the dual variables stay integer.
+func maxWeightMatching(vertices int, edges []matchingEdge, maxCardinality bool) []int {
+	m := newBlossomMatcher(vertices, edges)
+	m.solve(maxCardinality)
+
+	mates := make([]int, vertices)
+	for v := range mates {
+		mates[v] = -1
+		if m.mate[v] >= 0 {
+			mates[v] = m.endpoint[m.mate[v]]
+		}
+	}
+	return mates
+}
+
+// blossomMatcher holds the state of the blossom algorithm. Vertices are 0..n-1 and
+// non-trivial blossoms n..2n-1. An edge k has the endpoints 2k and 2k+1; p^1 is the
+// other end of the edge through endpoint p.
+type blossomMatcher struct {
+	n        int
+	edges    []matchingEdge
+	endpoint []int   // Vertex of each endpoint
+	neighbs  [][]int // Remote endpoints of the edges at each vertex
+
+	mate             []int   // Remote endpoint of each vertex's matched edge, or -1
+	label            []int   // 0 free, 1 S, 2 T, for vertices and top-level blossoms; 5 marks a breadcrumb
+	labelEnd         []int   // Endpoint through which a label was assigned, or -1
+	inBlossom        []int   // Top-level blossom containing each vertex
+	blossomParent    []int   // Immediate parent blossom, or -1
+	blossomChilds    [][]int // Sub-blossoms of each blossom, starting with its base
+	blossomBase      []int   // Base vertex of each blossom, or -1 if unused
+	blossomEndps     [][]int // Endpoints connecting consecutive sub-blossoms
+	bestEdge         []int   // Least-slack edge to an S-blossom, or -1
+	blossomBestEdges [][]int // Least-slack edges from each S-blossom to other S-blossoms
+	unusedBlossoms   []int   // Free blossom numbers
+	dual             []int64 // Dual variables, doubled for vertices
+	allowEdge        []bool  // Whether an edge has zero slack
+	queue            []int   // S-vertices to scan
+}
+
+func newBlossomMatcher(n int, edges []matchingEdge) *blossomMatcher {
+	m := &blossomMatcher{
+		n:                n,
+		edges:            edges,
+		endpoint:         make([]int, 2*len(edges)),
+		neighbs:          make([][]int, n),
+		mate:             make([]int, n),
+		label:            make([]int, 2*n),
+		labelEnd:         make([]int, 2*n),
+		inBlossom:        make([]int, n),
+		blossomParent:    make([]int, 2*n),
+		blossomChilds:    make([][]int, 2*n),
+		blossomBase:      make([]int, 2*n),
+		blossomEndps:     make([][]int, 2*n),
+		bestEdge:         make([]int, 2*n),
+		blossomBestEdges: make([][]int, 2*n),
+		dual:             make([]int64, 2*n),
+		allowEdge:        make([]bool, len(edges)),
+	}
+
+	var maxWeight int64
+	for k, e := range edges {
+		m.endpoint[2*k], m.endpoint[2*k+1] = e.a, e.b
+		m.neighbs[e.a] = append(m.neighbs[e.a], 2*k+1)
+		m.neighbs[e.b] = append(m.neighbs[e.b], 2*k)
+		if e.weight > maxWeight {
+			maxWeight = e.weight
+		}
+	}
+
+	for v := 0; v < n; v++ {
+		m.mate[v] = -1
+		m.inBlossom[v] = v
+		m.blossomBase[v] = v
+		m.blossomBase[n+v] = -1
+		m.dual[v] = maxWeight
+		m.unusedBlossoms = append(m.unusedBlossoms, n+v)
+	}
+	for b := range m.blossomParent {
+		m.blossomParent[b] = -1
+		m.labelEnd[b] = -1
+		m.bestEdge[b] = -1
+	}
+	return m
+}
+
+// slack returns the slack of edge k, twice its reduced cost
+func (m *blossomMatcher) slack(k int) int64 {
+	e := m.edges[k]
+	return m.dual[e.a] + m.dual[e.b] - 2*e.weight
+}
+
+// leaves returns the vertices contained in blossom b
+func (m *blossomMatcher) leaves(b int) []int {
+	if b < m.n {
+		return []int{b}
+	}
+	var leaves []int
+	for _, t := range m.blossomChilds[b] {
+		leaves = append(leaves, m.leaves(t)...)
+	}
+	return leaves
+}
+
+// assignLabel labels the top-level blossom containing w with t, reached through endpoint p
+func (m *blossomMatcher) assignLabel(w, t, p int) {
+	b := m.inBlossom[w]
+	m.label[w], m.label[b] = t, t
+	m.labelEnd[w], m.labelEnd[b] = p, p
+	m.bestEdge[w], m.bestEdge[b] = -1, -1
+	if t == 1 {
+		m.queue = append(m.queue, m.leaves(b)...)
+		return
+	}
+
+	// A T-blossom's mate becomes an S-blossom
+	base := m.blossomBase[b]
+	m.assignLabel(m.endpoint[m.mate[base]], 1, m.mate[base]^1)
+}
+
+// scanBlossom traces back from v and w to find the base of a new blossom, or -1 when
+// the paths end at different single vertices, giving an augmenting path
+func (m *blossomMatcher) scanBlossom(v, w int) int {
+	var path []int
+	base := -1
+	for v != -1 || w != -1 {
+		b := m.inBlossom[v]
+		if m.label[b]&4 != 0 {
+			base = m.blossomBase[b]
+			break
+		}
+		path = append(path, b)
+		m.label[b] = 5
+
+		if m.labelEnd[b] == -1 {
+			v = -1
+		} else {
+			v = m.endpoint[m.labelEnd[b]]
+			b = m.inBlossom[v]
+			v = m.endpoint[m.labelEnd[b]]
+		}
+		if w != -1 {
+			v, w = w, v
+		}
+	}
+
+	for _, b := range path {
+		m.label[b] = 1
+	}
+	return base
+}
+
+// addBlossom makes a new blossom with the given base through the S-S edge k
+func (m *blossomMatcher) addBlossom(base, k int) {
+	v, w := m.edges[k].a, m.edges[k].b
+	bb := m.inBlossom[base]
+	bv := m.inBlossom[v]
+	bw := m.inBlossom[w]
+
+	b := m.unusedBlossoms[len(m.unusedBlossoms)-1]
+	m.unusedBlossoms = m.unusedBlossoms[:len(m.unusedBlossoms)-1]
+	m.blossomBase[b] = base
+	m.blossomParent[b] = -1
+	m.blossomParent[bb] = b
+
+	var path, endps []int
+	for bv != bb {
+		m.blossomParent[bv] = b
+		path = append(path, bv)
+		endps = append(endps, m.labelEnd[bv])
+		v = m.endpoint[m.labelEnd[bv]]
+		bv = m.inBlossom[v]
+	}
+	path = append(path, bb)
+	reverse(path)
+	reverse(endps)
+	endps = append(endps, 2*k)
+	for bw != bb {
+		m.blossomParent[bw] = b
+		path = append(path, bw)
+		endps = append(endps, m.labelEnd[bw]^1)
+		w = m.endpoint[m.labelEnd[bw]]
+		bw = m.inBlossom[w]
+	}
+	m.blossomChilds[b] = path
+	m.blossomEndps[b] = endps
+
+	m.label[b] = 1
+	m.labelEnd[b] = m.labelEnd[bb]
+	m.dual[b] = 0
+
+	for _, v := range m.leaves(b) {
+		if m.label[m.inBlossom[v]] == 2 {
+			// T-vertices become S-vertices inside the new S-blossom
+			m.queue = append(m.queue, v)
+		}
+		m.inBlossom[v] = b
+	}
+
+	// Keep the least-slack edges from the new blossom to each other S-blossom
+	bestEdgeTo := make([]int, 2*m.n)
+	for i := range bestEdgeTo {
+		bestEdgeTo[i] = -1
+	}
+	for _, bv := range path {
+		var lists [][]int
+		if m.blossomBestEdges[bv] == nil {
+			for _, v := range m.leaves(bv) {
+				list := make([]int, len(m.neighbs[v]))
+				for i, p := range m.neighbs[v] {
+					list[i] = p / 2
+				}
+				lists = append(lists, list)
+			}
+		} else {
+			lists = [][]int{m.blossomBestEdges[bv]}
+		}
+		for _, list := range lists {
+			for _, k := range list {
+				j := m.edges[k].b
+				if m.inBlossom[j] == b {
+					j = m.edges[k].a
+				}
+				bj := m.inBlossom[j]
+				if bj != b && m.label[bj] == 1 && (bestEdgeTo[bj] == -1 || m.slack(k) < m.slack(bestEdgeTo[bj])) {
+					bestEdgeTo[bj] = k
+				}
+			}
+		}
+		m.blossomBestEdges[bv] = nil
+		m.bestEdge[bv] = -1
+	}
+
+	m.blossomBestEdges[b] = []int{}
+	for _, k := range bestEdgeTo {
+		if k != -1 {
+			m.blossomBestEdges[b] = append(m.blossomBestEdges[b], k)
+		}
+	}
+	m.bestEdge[b] = -1
+	for _, k := range m.blossomBestEdges[b] {
+		if m.bestEdge[b] == -1 || m.slack(k) < m.slack(m.bestEdge[b]) {
+			m.bestEdge[b] = k
+		}
+	}
+}
+
+// expandBlossom turns the sub-blossoms of b into top-level blossoms, relabelling them
+// if b is a T-blossom expanded during a stage
+func (m *blossomMatcher) expandBlossom(b int, endStage bool) {
+	for _, s := range m.blossomChilds[b] {
+		m.blossomParent[s] = -1
+		switch {
+		case s < m.n:
+			m.inBlossom[s] = s
+		case endStage && m.dual[s] == 0:
+			m.expandBlossom(s, endStage)
+		default:
+			for _, v := range m.leaves(s) {
+				m.inBlossom[v] = s
+			}
+		}
+	}
+
+	if !endStage && m.label[b] == 2 {
+		childs := m.blossomChilds[b]
+		entryChild := m.inBlossom[m.endpoint[m.labelEnd[b]^1]]
+		j := indexOf(childs, entryChild)
+		jStep, endpTrick := -1, 1
+		if j&1 != 0 {
+			j -= len(childs)
+			jStep, endpTrick = 1, 0
+		}
+
+		// Relabel the T-sub-blossoms on the way from the entry child to the base
+		p := m.labelEnd[b]
+		for j != 0 {
+			m.label[m.endpoint[p^1]] = 0
+			m.label[m.endpoint[m.blossomEndps[b][mod(j-endpTrick, len(childs))]^endpTrick^1]] = 0
+			m.assignLabel(m.endpoint[p^1], 2, p)
+			m.allowEdge[m.blossomEndps[b][mod(j-endpTrick, len(childs))]/2] = true
+			j += jStep
+			p = m.blossomEndps[b][mod(j-endpTrick, len(childs))] ^ endpTrick
+			m.allowEdge[p/2] = true
+			j += jStep
+		}
+
+		bv := childs[mod(j, len(childs))]
+		m.label[m.endpoint[p^1]], m.label[bv] = 2, 2
+		m.labelEnd[m.endpoint[p^1]], m.labelEnd[bv] = p, p
+		m.bestEdge[bv] = -1
+
+		// Label the remaining sub-blossoms reachable from outside
+		j += jStep
+		for childs[mod(j, len(childs))] != entryChild {
+			bv := childs[mod(j, len(childs))]
+			if m.label[bv] == 1 {
+				j += jStep
+				continue
+			}
+			reached := -1
+			for _, v := range m.leaves(bv) {
+				if m.label[v] != 0 {
+					reached = v
+					break
+				}
+			}
+			if reached != -1 {
+				m.label[reached] = 0
+				m.label[m.endpoint[m.mate[m.blossomBase[bv]]]] = 0
+				m.assignLabel(reached, 2, m.labelEnd[reached])
+			}
+			j += jStep
+		}
+	}
+
+	m.label[b], m.labelEnd[b] = -1, -1
+	m.blossomChilds[b], m.blossomEndps[b] = nil, nil
+	m.blossomBase[b] = -1
+	m.blossomBestEdges[b] = nil
+	m.bestEdge[b] = -1
+	m.unusedBlossoms = append(m.unusedBlossoms, b)
+}
+
+// augmentBlossom swaps matched and unmatched edges inside blossom b on the path from
+// vertex v to the base, making v the new base
+func (m *blossomMatcher) augmentBlossom(b, v int) {
+	t := v
+	for m.blossomParent[t] != b {
+		t = m.blossomParent[t]
+	}
+	if t >= m.n {
+		m.augmentBlossom(t, v)
+	}
+
+	childs := m.blossomChilds[b]
+	i := indexOf(childs, t)
+	j := i
+	jStep, endpTrick := -1, 1
+	if i&1 != 0 {
+		j -= len(childs)
+		jStep, endpTrick = 1, 0
+	}
+	for j != 0 {
+		j += jStep
+		t = childs[mod(j, len(childs))]
+		p := m.blossomEndps[b][mod(j-endpTrick, len(childs))] ^ endpTrick
+		if t >= m.n {
+			m.augmentBlossom(t, m.endpoint[p])
+		}
+		j += jStep
+		t = childs[mod(j, len(childs))]
+		if t >= m.n {
+			m.augmentBlossom(t, m.endpoint[p^1])
+		}
+		m.mate[m.endpoint[p]] = p ^ 1
+		m.mate[m.endpoint[p^1]] = p
+	}
+
+	m.blossomChilds[b] = append(append([]int(nil), childs[i:]...), childs[:i]...)
+	endps := m.blossomEndps[b]
+	m.blossomEndps[b] = append(append([]int(nil), endps[i:]...), endps[:i]...)
+	m.blossomBase[b] = m.blossomBase[m.blossomChilds[b][0]]
+}
+
+// augmentMatching augments the matching along the path through edge k
+func (m *blossomMatcher) augmentMatching(k int) {
+	e := m.edges[k]
+	for _, start := range [][2]int{{e.a, 2*k + 1}, {e.b, 2 * k}} {
+		s, p := start[0], start[1]
+		for {
+			bs := m.inBlossom[s]
+			if bs >= m.n {
+				m.augmentBlossom(bs, s)
+			}
+			m.mate[s] = p
+			if m.labelEnd[bs] == -1 {
+				break
+			}
+
+			t := m.endpoint[m.labelEnd[bs]]
+			bt := m.inBlossom[t]
+			s = m.endpoint[m.labelEnd[bt]]
+			j := m.endpoint[m.labelEnd[bt]^1]
+			if bt >= m.n {
+				m.augmentBlossom(bt, j)
+			}
+			m.mate[j] = m.labelEnd[bt]
+			p = m.labelEnd[bt] ^ 1
+		}
+	}
+}
+
+// solve runs stages until no augmenting path is left
+func (m *blossomMatcher) solve(maxCardinality bool) {
+	n := m.n
+	for stage := 0; stage < n; stage++ {
+		for i := range m.label {
+			m.label[i] = 0
+			m.bestEdge[i] = -1
+		}
+		for b := n; b < 2*n; b++ {
+			m.blossomBestEdges[b] = nil
+		}
+		for k := range m.allowEdge {
+			m.allowEdge[k] = false
+		}
+		m.queue = m.queue[:0]
+
+		for v := 0; v < n; v++ {
+			if m.mate[v] == -1 && m.label[m.inBlossom[v]] == 0 {
+				m.assignLabel(v, 1, -1)
+			}
+		}
+
+		augmented := false
+		for {
+			for len(m.queue) > 0 && !augmented {
+				v := m.queue[len(m.queue)-1]
+				m.queue = m.queue[:len(m.queue)-1]
+
+				for _, p := range m.neighbs[v] {
+					k := p / 2
+					w := m.endpoint[p]
+					if m.inBlossom[v] == m.inBlossom[w] {
+						continue
+					}
+
+					var kSlack int64
+					if !m.allowEdge[k] {
+						kSlack = m.slack(k)
+						if kSlack <= 0 {
+							m.allowEdge[k] = true
+						}
+					}
+
+					switch {
+					case m.allowEdge[k]:
+						switch {
+						case m.label[m.inBlossom[w]] == 0:
+							m.assignLabel(w, 2, p^1)
+						case m.label[m.inBlossom[w]] == 1:
+							if base := m.scanBlossom(v, w); base >= 0 {
+								m.addBlossom(base, k)
+							} else {
+								m.augmentMatching(k)
+								augmented = true
+							}
+						case m.label[w] == 0:
+							m.label[w] = 2
+							m.labelEnd[w] = p ^ 1
+						}
+					case m.label[m.inBlossom[w]] == 1:
+						b := m.inBlossom[v]
+						if m.bestEdge[b] == -1 || kSlack < m.slack(m.bestEdge[b]) {
+							m.bestEdge[b] = k
+						}
+					case m.label[w] == 0:
+						if m.bestEdge[w] == -1 || kSlack < m.slack(m.bestEdge[w]) {
+							m.bestEdge[w] = k
+						}
+					}
+					if augmented {
+						break
+					}
+				}
+			}
+			if augmented {
+				break
+			}
+
+			// No augmenting path with the current duals; find the smallest dual change
+			deltaType := -1
+			var delta int64
+			deltaEdge, deltaBlossom := -1, -1
+			if !maxCardinality {
+				deltaType = 1
+				delta = m.dual[0]
+				for v := 1; v < n; v++ {
+					if m.dual[v] < delta {
+						delta = m.dual[v]
+					}
+				}
+			}
+			for v := 0; v < n; v++ {
+				if m.label[m.inBlossom[v]] == 0 && m.bestEdge[v] != -1 {
+					if d := m.slack(m.bestEdge[v]); deltaType == -1 || d < delta {
+						delta, deltaType, deltaEdge = d, 2, m.bestEdge[v]
+					}
+				}
+			}
+			for b := 0; b < 2*n; b++ {
+				if m.blossomParent[b] == -1 && m.label[b] == 1 && m.bestEdge[b] != -1 {
+					if d := m.slack(m.bestEdge[b]) / 2; deltaType == -1 || d < delta {
+						delta, deltaType, deltaEdge = d, 3, m.bestEdge[b]
+					}
+				}
+			}
+			for b := n; b < 2*n; b++ {
+				if m.blossomBase[b] >= 0 && m.blossomParent[b] == -1 && m.label[b] == 2 && (deltaType == -1 || m.dual[b] < delta) {
+					delta, deltaType, deltaBlossom = m.dual[b], 4, b
+				}
+			}
+			if deltaType == -1 {
+				// Maximum cardinality reached; a final update keeps the duals optimal
+				deltaType = 1
+				delta = m.dual[0]
+				for v := 1; v < n; v++ {
+					if m.dual[v] < delta {
+						delta = m.dual[v]
+					}
+				}
+				if delta < 0 {
+					delta = 0
+				}
+			}
+
+			for v := 0; v < n; v++ {
+				switch m.label[m.inBlossom[v]] {
+				case 1:
+					m.dual[v] -= delta
+				case 2:
+					m.dual[v] += delta
+				}
+			}
+			for b := n; b < 2*n; b++ {
+				if m.blossomBase[b] >= 0 && m.blossomParent[b] == -1 {
+					switch m.label[b] {
+					case 1:
+						m.dual[b] += delta
+					case 2:
+						m.dual[b] -= delta
+					}
+				}
+			}
+
+			switch deltaType {
+			case 1:
+				// Optimum reached
+			case 2:
+				m.allowEdge[deltaEdge] = true
+				i := m.edges[deltaEdge].a
+				if m.label[m.inBlossom[i]] == 0 {
+					i = m.edges[deltaEdge].b
+				}
+				m.queue = append(m.queue, i)
+				continue
+			case 3:
+				m.allowEdge[deltaEdge] = true
+				m.queue = append(m.queue, m.edges[deltaEdge].a)
+				continue
+			case 4:
+				m.expandBlossom(deltaBlossom, false)
+				continue
+			}
+			break
+		}
+
+		if !augmented {
+			break
+		}
+
+		// Expand the S-blossoms whose dual variable dropped to zero
+		for b := n; b < 2*n; b++ {
+			if m.blossomParent[b] == -1 && m.blossomBase[b] >= 0 && m.label[b] == 1 && m.dual[b] == 0 {
+				m.expandBlossom(b, true)
+			}
+		}
+	}
+}
+
+// indexOf returns the position of x in s
+func indexOf(s []int, x int) int {
+	for i, y := range s {
+		if y == x {
+			return i
+		}
+	}
+	return -1
+}
+
+// mod returns i modulo n as a non-negative index, for Python-style negative indexing
+func mod(i, n int) int {
+	return ((i % n) + n) % n
+}
+
+// reverse reverses s in place
+func reverse(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// perfectMatchings looks for count disjoint perfect matchings in the graph and returns
+// the index of the matching each edge belongs to, or -1. It searches by exact cover,
+// first filling the vertex and matching with the fewest edges left to choose from and
+// trying heavier edges first, and gives up after budget steps.
+func perfectMatchings(vertices int, edges []matchingEdge, count, budget int) ([]int, bool) {
+	byWeight := make([]int, len(edges))
+	for k := range byWeight {
+		byWeight[k] = k
+	}
+	sort.SliceStable(byWeight, func(i, j int) bool { return edges[byWeight[i]].weight > edges[byWeight[j]].weight })
+
+	incident := make([][]int, vertices)
+	for _, k := range byWeight {
+		incident[edges[k].a] = append(incident[edges[k].a], k)
+		incident[edges[k].b] = append(incident[edges[k].b], k)
+	}
+
+	matching := make([]int, len(edges))
+	for k := range matching {
+		matching[k] = -1
+	}
+	covered := make([][]bool, vertices)
+	for v := range covered {
+		covered[v] = make([]bool, count)
+	}
+	other := func(k, v int) int {
+		if edges[k].a == v {
+			return edges[k].b
+		}
+		return edges[k].a
+	}
+
+	var search func() bool
+	search = func() bool {
+		if budget == 0 {
+			return false
+		}
+		budget--
+
+		vertex, index, choices := -1, -1, 0
+		for v := 0; v < vertices; v++ {
+			for i := 0; i < count; i++ {
+				if covered[v][i] {
+					continue
+				}
+				n := 0
+				for _, k := range incident[v] {
+					if matching[k] == -1 && !covered[other(k, v)][i] {
+						n++
+					}
+				}
+				if n == 0 {
+					return false
+				}
+				if vertex == -1 || n < choices {
+					vertex, index, choices = v, i, n
+				}
+			}
+		}
+		if vertex == -1 {
+			return true
+		}
+
+		for _, k := range incident[vertex] {
+			w := other(k, vertex)
+			if matching[k] != -1 || covered[w][index] {
+				continue
+			}
+			matching[k] = index
+			covered[vertex][index], covered[w][index] = true, true
+			if search() {
+				return true
+			}
+			matching[k] = -1
+			covered[vertex][index], covered[w][index] = false, false
+		}
+		return false
+	}
+
+	if !search() {
+		return nil, false
+	}
+	return matching, true
+}
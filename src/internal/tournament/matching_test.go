@@ -0,0 +1,92 @@
+package tournament
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// bruteForceMatching returns the best total weight of a matching, among the
+// matchings of maximum cardinality if maxCardinality is set
+func bruteForceMatching(vertices int, edges []matchingEdge, maxCardinality bool) (int, int64) {
+	used := make([]bool, vertices)
+	var best func(k int) (int, int64)
+	best = func(k int) (int, int64) {
+		if k == len(edges) {
+			return 0, 0
+		}
+		size, weight := best(k + 1)
+		e := edges[k]
+		if !used[e.a] && !used[e.b] {
+			used[e.a], used[e.b] = true, true
+			s, w := best(k + 1)
+			used[e.a], used[e.b] = false, false
+			s, w = s+1, w+e.weight
+			if (maxCardinality && (s > size || (s == size && w > weight))) || (!maxCardinality && w > weight) {
+				size, weight = s, w
+			}
+		}
+		return size, weight
+	}
+	return best(0)
+}
+
+func TestMaxWeightMatching(t *testing.T) {
+	tests := []struct {
+		name           string
+		vertices       int
+		edges          []matchingEdge
+		maxCardinality bool
+		expected       []int
+	}{
+		{"Empty", 0, nil, false, []int{}},
+		{"Single edge", 2, []matchingEdge{{0, 1, 1}}, false, []int{1, 0}},
+		{"Heavier middle edge", 4, []matchingEdge{{0, 1, 2}, {1, 2, 5}, {2, 3, 2}}, false, []int{-1, 2, 1, -1}},
+		{"Maximum cardinality", 4, []matchingEdge{{0, 1, 2}, {1, 2, 5}, {2, 3, 2}}, true, []int{1, 0, 3, 2}},
+		{"Blossom", 6, []matchingEdge{{0, 1, 8}, {0, 2, 9}, {1, 2, 10}, {2, 3, 7}, {0, 5, 3}, {3, 4, 6}}, false, []int{5, 2, 1, 4, 3, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mates := maxWeightMatching(tt.vertices, tt.edges, tt.maxCardinality)
+			for v := range tt.expected {
+				if mates[v] != tt.expected[v] {
+					t.Errorf("maxWeightMatching() = %v; want %v", mates, tt.expected)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMaxWeightMatchingRandom(t *testing.T) {
+	rand := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		vertices := 2 + rand.Intn(8)
+		var edges []matchingEdge
+		for a := 0; a < vertices; a++ {
+			for b := a + 1; b < vertices; b++ {
+				if rand.Intn(3) > 0 {
+					edges = append(edges, matchingEdge{a, b, int64(rand.Intn(20))})
+				}
+			}
+		}
+		maxCardinality := i%2 == 0
+
+		mates := maxWeightMatching(vertices, edges, maxCardinality)
+		size, weight := 0, int64(0)
+		for _, e := range edges {
+			if mates[e.a] == e.b {
+				if mates[e.b] != e.a {
+					t.Fatalf("Matching is not symmetric: %v", mates)
+				}
+				size++
+				weight += e.weight
+			}
+		}
+
+		expectedSize, expectedWeight := bruteForceMatching(vertices, edges, maxCardinality)
+		if weight != expectedWeight || (maxCardinality && size != expectedSize) {
+			t.Fatalf("Graph %v: got %d edges of weight %d; want %d of weight %d", edges, size, weight, expectedSize, expectedWeight)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package tournament pairs players for chess tournaments and scores the results.
+// Players are identified by their index in the tournament's player list.
+package tournament
+
+import "fmt"
+
+// Tournament formats
+const (
+	RoundRobin       = "round-robin"
+	DoubleRoundRobin = "double-round-robin"
+	Gauntlet         = "gauntlet"
+)
+
+// Pairing is a game to be played in a round
+type Pairing struct {
+	Round int // Starting at 1
+	White int
+	Black int
+}
+
+// Pairings returns every game of a tournament in the given format between players
+// players, in round order. For a gauntlet, player 0 meets each other player rounds
+// times, alternating colours; rounds is ignored for round robins. Swiss tournaments
+// are paired a round at a time with SwissRound instead.
+func Pairings(format string, players, rounds int) ([]Pairing, error) {
+	if players < 2 {
+		return nil, fmt.Errorf("a tournament needs at least 2 players, got %d", players)
+	}
+
+	switch format {
+	case RoundRobin:
+		return roundRobin(players), nil
+	case DoubleRoundRobin:
+		first := roundRobin(players)
+		cycle := first[len(first)-1].Round
+		pairings := first
+		for _, p := range first {
+			pairings = append(pairings, Pairing{Round: p.Round + cycle, White: p.Black, Black: p.White})
+		}
+		return pairings, nil
+	case Gauntlet:
+		if rounds < 1 {
+			return nil, fmt.Errorf("a gauntlet needs at least 1 round, got %d", rounds)
+		}
+		return gauntlet(players, rounds), nil
+	case Swiss:
+		return nil, fmt.Errorf("swiss pairings depend on results and are made a round at a time")
+	default:
+		return nil, fmt.Errorf("unknown tournament format %q", format)
+	}
+}
+
+// roundRobin pairs every player with every other once using Berger tables, so each
+// player's colours alternate as far as possible. With an odd number of players one
+// sits out each round.
+func roundRobin(players int) []Pairing {
+	n := players
+	if n%2 == 1 {
+		n++ // The extra player is a bye
+	}
+
+	var pairings []Pairing
+	for round := 0; round < n-1; round++ {
+		for i := 0; i < n/2; i++ {
+			white, black := bergerPair(n, round, i)
+			if white >= players || black >= players {
+				continue
+			}
+			pairings = append(pairings, Pairing{Round: round + 1, White: white, Black: black})
+		}
+	}
+	return pairings
+}
+
+// bergerPair returns the players of board i in a round of the Berger tables for n
+// players, n even. The last player is fixed and alternates colours; the others
+// rotate by n/2 places each round.
+func bergerPair(n, round, i int) (white, black int) {
+	last := n - 1
+	shift := round * (n / 2)
+	seat := func(k int) int { return (k + shift) % last }
+
+	if i == 0 {
+		if round%2 == 0 {
+			return seat(0), last
+		}
+		return last, seat(0)
+	}
+	return seat(i), seat(last - i)
+}
+
+// gauntlet pairs player 0 with each other player in every round, alternating colours
+// between rounds and opponents
+func gauntlet(players, rounds int) []Pairing {
+	var pairings []Pairing
+	for round := 0; round < rounds; round++ {
+		for opponent := 1; opponent < players; opponent++ {
+			p := Pairing{Round: round + 1, White: 0, Black: opponent}
+			if (round+opponent)%2 == 0 {
+				p.White, p.Black = opponent, 0
+			}
+			pairings = append(pairings, p)
+		}
+	}
+	return pairings
+}
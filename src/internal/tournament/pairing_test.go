@@ -0,0 +1,108 @@
+package tournament
+
+import "testing"
+
+func TestPairings(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		players     int
+		rounds      int
+		games       int
+		lastRound   int
+		meetings    int // Games between each pair of players that meet
+		expectError bool
+	}{
+		{name: "Round robin, even", format: RoundRobin, players: 6, games: 15, lastRound: 5, meetings: 1},
+		{name: "Round robin, odd", format: RoundRobin, players: 5, games: 10, lastRound: 5, meetings: 1},
+		{name: "Round robin, two players", format: RoundRobin, players: 2, games: 1, lastRound: 1, meetings: 1},
+		{name: "Double round robin", format: DoubleRoundRobin, players: 4, games: 12, lastRound: 6, meetings: 2},
+		{name: "Gauntlet", format: Gauntlet, players: 4, rounds: 2, games: 6, lastRound: 2, meetings: 2},
+		{name: "Too few players", format: RoundRobin, players: 1, expectError: true},
+		{name: "Gauntlet without rounds", format: Gauntlet, players: 3, expectError: true},
+		{name: "Unknown format", format: "knockout", players: 4, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairings, err := Pairings(tt.format, tt.players, tt.rounds)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(pairings) != tt.games {
+				t.Errorf("Expected %d games, got %d", tt.games, len(pairings))
+			}
+			if last := pairings[len(pairings)-1].Round; last != tt.lastRound {
+				t.Errorf("Expected %d rounds, got %d", tt.lastRound, last)
+			}
+
+			met := map[[2]int]int{}
+			whites := make([]int, tt.players)
+			played := map[[2]int]bool{} // Player and round
+			for _, p := range pairings {
+				if p.White == p.Black {
+					t.Errorf("Player %d paired with itself", p.White)
+				}
+				for _, player := range []int{p.White, p.Black} {
+					// The gauntlet's challenger meets every opponent in each round
+					if played[[2]int{player, p.Round}] && !(tt.format == Gauntlet && player == 0) {
+						t.Errorf("Player %d plays twice in round %d", player, p.Round)
+					}
+					played[[2]int{player, p.Round}] = true
+				}
+
+				a, b := p.White, p.Black
+				if a > b {
+					a, b = b, a
+				}
+				met[[2]int{a, b}]++
+				whites[p.White]++
+			}
+
+			for pair, count := range met {
+				if count != tt.meetings {
+					t.Errorf("Players %v met %d times, expected %d", pair, count, tt.meetings)
+				}
+			}
+
+			// Colours are balanced: every player has white in about half their games
+			games := make([]int, tt.players)
+			for _, p := range pairings {
+				games[p.White]++
+				games[p.Black]++
+			}
+			for player, w := range whites {
+				if diff := 2*w - games[player]; diff < -1 || diff > 1 {
+					t.Errorf("Player %d has white in %d of %d games", player, w, games[player])
+				}
+			}
+		})
+	}
+}
+
+func TestRoundRobinColoursAlternate(t *testing.T) {
+	for players := 2; players <= 12; players++ {
+		last := map[int]bool{}
+		streak := map[int]int{}
+		for _, p := range roundRobin(players) {
+			for player, white := range map[int]bool{p.White: true, p.Black: false} {
+				if prev, ok := last[player]; ok && prev == white {
+					streak[player]++
+				} else {
+					streak[player] = 1
+				}
+				last[player] = white
+				if streak[player] > 2 {
+					t.Errorf("%d players: player %d has the same colour three times running in round %d", players, player, p.Round)
+				}
+			}
+		}
+	}
+}
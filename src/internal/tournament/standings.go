@@ -0,0 +1,157 @@
+package tournament
+
+import "sort"
+
+// Game results
+const (
+	WhiteWins = "1-0"
+	BlackWins = "0-1"
+	Draw      = "1/2-1/2"
+)
+
+// Game is a pairing with its result, which is empty or "*" until the game is decided
+type Game struct {
+	Pairing
+	Result string
+}
+
+// score returns the points white and black earned in the game, and false if it has
+// no result yet
+func (g Game) score() (white, black float64, ok bool) {
+	switch g.Result {
+	case WhiteWins:
+		return 1, 0, true
+	case BlackWins:
+		return 0, 1, true
+	case Draw:
+		return 0.5, 0.5, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// Standing is a player's record in a tournament. Buchholz is the sum of the
+// opponents' points and SonnebornBerger the sum of the points of the opponents beaten
+// plus half of those drawn; byes count for neither.
+type Standing struct {
+	Player          int
+	Points          float64
+	Games           int
+	Wins            int
+	Draws           int
+	Losses          int
+	Byes            int
+	Buchholz        float64
+	SonnebornBerger float64
+}
+
+// Tied returns whether two players cannot be separated by points or tie-breaks
+func (s Standing) Tied(o Standing) bool {
+	return s.Points == o.Points && s.Buchholz == o.Buchholz && s.SonnebornBerger == o.SonnebornBerger && s.Wins == o.Wins
+}
+
+// Standings returns the records of all players, best first: by points, then
+// Buchholz, Sonneborn-Berger and wins, then the order players were registered in
+func Standings(players int, games []Game) []Standing {
+	standings := make([]Standing, players)
+	for i := range standings {
+		standings[i].Player = i
+	}
+
+	for _, g := range games {
+		white, black, ok := g.score()
+		if !ok {
+			continue
+		}
+		if g.Black == Bye {
+			standings[g.White].Byes++
+			standings[g.White].Points += white
+			continue
+		}
+		standings[g.White].add(white)
+		standings[g.Black].add(black)
+	}
+
+	// Tie-breaks need everyone's final points
+	for _, g := range games {
+		white, black, ok := g.score()
+		if !ok || g.Black == Bye {
+			continue
+		}
+		standings[g.White].tieBreak(white, standings[g.Black].Points)
+		standings[g.Black].tieBreak(black, standings[g.White].Points)
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		switch {
+		case a.Points != b.Points:
+			return a.Points > b.Points
+		case a.Buchholz != b.Buchholz:
+			return a.Buchholz > b.Buchholz
+		case a.SonnebornBerger != b.SonnebornBerger:
+			return a.SonnebornBerger > b.SonnebornBerger
+		default:
+			return a.Wins > b.Wins
+		}
+	})
+	return standings
+}
+
+// tieBreak adds a game in which the player scored points against an opponent who
+// finished with opponentPoints
+func (s *Standing) tieBreak(points, opponentPoints float64) {
+	s.Buchholz += opponentPoints
+	s.SonnebornBerger += points * opponentPoints
+}
+
+// add records a game in which the player scored points
+func (s *Standing) add(points float64) {
+	s.Games++
+	s.Points += points
+	switch points {
+	case 1:
+		s.Wins++
+	case 0.5:
+		s.Draws++
+	default:
+		s.Losses++
+	}
+}
+
+// Crosstable returns, for each pair of players, the results of the row player
+// against the column player in round order: "1" for a win, "½" for a draw and "0" for
+// a loss. A player's own cell is "x"; pairs that have not finished a game are empty.
+// Byes are left out.
+func Crosstable(players int, games []Game) [][]string {
+	table := make([][]string, players)
+	for i := range table {
+		table[i] = make([]string, players)
+		table[i][i] = "x"
+	}
+
+	sorted := append([]Game(nil), games...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Round < sorted[j].Round })
+
+	for _, g := range sorted {
+		white, black, ok := g.score()
+		if !ok || g.Black == Bye {
+			continue
+		}
+		table[g.White][g.Black] += scoreSymbol(white)
+		table[g.Black][g.White] += scoreSymbol(black)
+	}
+	return table
+}
+
+// scoreSymbol returns the crosstable symbol for a game's points
+func scoreSymbol(points float64) string {
+	switch points {
+	case 1:
+		return "1"
+	case 0.5:
+		return "½"
+	default:
+		return "0"
+	}
+}
@@ -0,0 +1,88 @@
+package tournament
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStandings(t *testing.T) {
+	games := []Game{
+		{Pairing{Round: 1, White: 0, Black: 1}, WhiteWins},
+		{Pairing{Round: 1, White: 2, Black: 3}, Draw},
+		{Pairing{Round: 2, White: 1, Black: 2}, BlackWins},
+		{Pairing{Round: 2, White: 3, Black: 0}, Draw},
+		{Pairing{Round: 3, White: 0, Black: 2}, ""},
+		{Pairing{Round: 3, White: 1, Black: 3}, "*"},
+	}
+
+	// Players 0 and 2 are level on points and tie-breaks, so registration order decides
+	expected := []Standing{
+		{Player: 0, Points: 1.5, Games: 2, Wins: 1, Draws: 1, Buchholz: 1, SonnebornBerger: 0.5},
+		{Player: 2, Points: 1.5, Games: 2, Wins: 1, Draws: 1, Buchholz: 1, SonnebornBerger: 0.5},
+		{Player: 3, Points: 1, Games: 2, Draws: 2, Buchholz: 3, SonnebornBerger: 1.5},
+		{Player: 1, Points: 0, Games: 2, Losses: 2, Buchholz: 3},
+	}
+
+	if got := Standings(4, games); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Standings() = %+v; want %+v", got, expected)
+	}
+
+	table := [][]string{
+		{"x", "1", "", "½"},
+		{"0", "x", "0", ""},
+		{"", "1", "x", "½"},
+		{"½", "", "½", "x"},
+	}
+	if got := Crosstable(4, games); !reflect.DeepEqual(got, table) {
+		t.Errorf("Crosstable() = %v; want %v", got, table)
+	}
+}
+
+func TestCrosstableRepeatedGames(t *testing.T) {
+	games := []Game{
+		{Pairing{Round: 2, White: 1, Black: 0}, Draw},
+		{Pairing{Round: 1, White: 0, Black: 1}, WhiteWins},
+	}
+
+	table := Crosstable(2, games)
+	if table[0][1] != "1½" || table[1][0] != "0½" {
+		t.Errorf("Expected results in round order, got %v", table)
+	}
+}
+
+func TestTieBreaks(t *testing.T) {
+	// Players 0 and 1 finish on 2 points; 0 beat the stronger opponents. Player 4 has a bye.
+	games := []Game{
+		{Pairing{Round: 1, White: 0, Black: 2}, WhiteWins},
+		{Pairing{Round: 1, White: 1, Black: 3}, WhiteWins},
+		{Pairing{Round: 1, White: 4, Black: Bye}, WhiteWins},
+		{Pairing{Round: 2, White: 2, Black: 4}, WhiteWins},
+		{Pairing{Round: 2, White: 3, Black: 0}, BlackWins},
+		{Pairing{Round: 2, White: 1, Black: Bye}, WhiteWins},
+	}
+
+	standings := Standings(5, games)
+	var order []int
+	for _, s := range standings {
+		order = append(order, s.Player)
+	}
+	if !reflect.DeepEqual(order, []int{0, 1, 2, 4, 3}) {
+		t.Errorf("Expected order [0 1 2 4 3], got %v", order)
+	}
+
+	first, second := standings[0], standings[1]
+	if first.Points != 2 || first.Buchholz != 1 || first.SonnebornBerger != 1 {
+		t.Errorf("Unexpected standing for player 0: %+v", first)
+	}
+	if second.Points != 2 || second.Byes != 1 || second.Games != 1 || second.Buchholz != 0 {
+		t.Errorf("Unexpected standing for player 1: %+v", second)
+	}
+	if standings[2].Tied(standings[3]) {
+		t.Errorf("Expected players 2 and 4 to be separated by Buchholz: %+v %+v", standings[2], standings[3])
+	}
+
+	table := Crosstable(5, games)
+	if table[4][0] != "" || table[1][1] != "x" {
+		t.Errorf("Expected byes to be left out of the crosstable, got %v", table)
+	}
+}
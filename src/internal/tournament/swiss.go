@@ -0,0 +1,402 @@
+package tournament
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Swiss is the format pairing players with similar scores round by round, so
+// pairings are made with SwissRound as results come in
+const Swiss = "swiss"
+
+// Bye stands in for the opponent of a player who sits out a round. A bye counts as a
+// win without a game.
+const Bye = -1
+
+// Colour preferences in the Swiss system
+const (
+	preferBlack = -1
+	preferNone  = 0
+	preferWhite = 1
+)
+
+// swissPlayer is a player's history as far as Swiss pairing is concerned
+type swissPlayer struct {
+	score     float64
+	opponents map[int]bool
+	colours   []int // preferWhite or preferBlack for each game played, in round order
+	hadBye    bool
+}
+
+// SwissRounds returns the recommended number of Swiss rounds for a number of players:
+// enough for a single player to finish on a perfect score
+func SwissRounds(players int) int {
+	rounds := 1
+	for 1<<rounds < players {
+		rounds++
+	}
+	return rounds
+}
+
+// maxSwissPlayers keeps the weights of a round's pairing within an int64
+const maxSwissPlayers = 1000
+
+// SwissRound pairs a round of a Swiss tournament of the given number of rounds
+// following the Dutch system, given the games of the previous rounds. Players are
+// ranked by score and then by their index, which serves as the initial ranking. Within
+// each score group the top half meets the bottom half, players left over float down
+// to the next group, nobody meets the same opponent twice and colours are balanced.
+// With an odd number of players the lowest ranked player who has not had a bye sits
+// out, paired with Bye.
+//
+// The round is paired as a maximum weight matching, which takes polynomial time
+// however many players there are. In the last rounds, a pairing is only chosen if the
+// rest of the tournament can still be paired without rematches.
+func SwissRound(players, round, rounds int, games []Game) ([]Pairing, error) {
+	if players < 2 {
+		return nil, fmt.Errorf("a tournament needs at least 2 players, got %d", players)
+	}
+	if players > maxSwissPlayers {
+		return nil, fmt.Errorf("a Swiss tournament has at most %d players, got %d", maxSwissPlayers, players)
+	}
+
+	s := newSwissPairer(players, games)
+	mates, ok := s.pair()
+	if !ok {
+		return nil, fmt.Errorf("no pairing for round %d avoids a rematch", round)
+	}
+	if remaining := rounds - round; remaining > 0 {
+		mates = s.lookahead(mates, remaining)
+	}
+	return s.pairings(round, mates), nil
+}
+
+// swissHistory collects each player's score, opponents and colours from games
+func swissHistory(players int, games []Game) []swissPlayer {
+	history := make([]swissPlayer, players)
+	for i := range history {
+		history[i].opponents = map[int]bool{}
+	}
+
+	sorted := append([]Game(nil), games...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Round < sorted[j].Round })
+
+	for _, g := range sorted {
+		white, black, ok := g.score()
+		if g.Black == Bye {
+			history[g.White].hadBye = true
+			history[g.White].score += white
+			continue
+		}
+
+		history[g.White].opponents[g.Black] = true
+		history[g.Black].opponents[g.White] = true
+		history[g.White].colours = append(history[g.White].colours, preferWhite)
+		history[g.Black].colours = append(history[g.Black].colours, preferBlack)
+		if ok {
+			history[g.White].score += white
+			history[g.Black].score += black
+		}
+	}
+	return history
+}
+
+// preference returns the colour a player should get next and how strongly: 3 when
+// absolute, after two games in a row or two more games with one colour, 2 when the
+// player has had one colour more often, 1 to alternate and 0 before the first game
+func (p swissPlayer) preference() (colour, strength int) {
+	difference := 0
+	for _, c := range p.colours {
+		difference += c
+	}
+
+	n := len(p.colours)
+	switch {
+	case n == 0:
+		return preferNone, 0
+	case difference <= -2 || (n >= 2 && p.colours[n-1] == preferBlack && p.colours[n-2] == preferBlack):
+		return preferWhite, 3
+	case difference >= 2 || (n >= 2 && p.colours[n-1] == preferWhite && p.colours[n-2] == preferWhite):
+		return preferBlack, 3
+	case difference < 0:
+		return preferWhite, 2
+	case difference > 0:
+		return preferBlack, 2
+	default:
+		return -p.colours[n-1], 1
+	}
+}
+
+// swissLookahead is the number of rounds left from which a pairing is checked against
+// the rest of the tournament. Earlier, players have enough opponents left.
+const swissLookahead = 3
+
+// swissSearchBudget caps the steps spent pairing the remaining rounds in advance
+const swissSearchBudget = 20000
+
+// swissPairer pairs a round as a matching between the players, with an extra vertex
+// standing for the bye when their number is odd
+type swissPairer struct {
+	history  []swissPlayer
+	order    []int // Players in ranking order
+	rank     []int // Position of each player in the ranking
+	group    []int // First position of the score group of each position
+	size     []int // Size of the score group of each position
+	vertices int
+	bye      int // Vertex standing for the bye, or -1
+}
+
+func newSwissPairer(players int, games []Game) *swissPairer {
+	s := &swissPairer{
+		history:  swissHistory(players, games),
+		order:    make([]int, players),
+		rank:     make([]int, players),
+		group:    make([]int, players),
+		size:     make([]int, players),
+		vertices: players,
+		bye:      -1,
+	}
+	if players%2 == 1 {
+		s.bye = players
+		s.vertices++
+	}
+
+	for i := range s.order {
+		s.order[i] = i
+	}
+	sort.SliceStable(s.order, func(i, j int) bool {
+		return s.history[s.order[i]].score > s.history[s.order[j]].score
+	})
+
+	for i, p := range s.order {
+		s.rank[p] = i
+		s.group[i] = i
+		if i > 0 && s.history[s.order[i-1]].score == s.history[p].score {
+			s.group[i] = s.group[i-1]
+		}
+		s.size[s.group[i]]++
+	}
+	for i := range s.size {
+		s.size[i] = s.size[s.group[i]]
+	}
+	return s
+}
+
+// pair returns the mate of each vertex in the best pairing of the round. Colour
+// constraints are given up only if there is no pairing that keeps them.
+func (s *swissPairer) pair() ([]int, bool) {
+	for _, strict := range []bool{true, false} {
+		mates := maxWeightMatching(s.vertices, s.edges(strict), true)
+		if complete(mates) {
+			return mates, true
+		}
+	}
+	return nil, false
+}
+
+// edges returns the games the round may contain, weighted by how well they follow the
+// Dutch system. Players who have met are not connected, nor are players who have had
+// a bye to the bye. The weights put first the bye going to the lowest ranked player,
+// then players meeting within their score group or the closest one, then colour
+// preferences being met, then the top half of a score group meeting the bottom half;
+// each unit is larger than the most the lower ones can add up to in a round.
+func (s *swissPairer) edges(strict bool) []matchingEdge {
+	n := int64(s.vertices)
+	colourUnit := n*n + 1
+	scoreUnit := colourUnit * (n/2 + 1)
+	byeUnit := scoreUnit * ((n/2)*(3*n+1) + 1)
+
+	var edges []matchingEdge
+	for i, a := range s.order {
+		for _, b := range s.order[i+1:] {
+			if s.history[a].opponents[b] || (strict && s.clash(a, b)) {
+				continue
+			}
+
+			weight := scoreUnit*s.scoreWeight(a, b) + s.positionWeight(a, b)
+			colourA, strengthA := s.history[a].preference()
+			colourB, strengthB := s.history[b].preference()
+			if strengthA == 0 || strengthB == 0 || colourA != colourB {
+				weight += colourUnit
+			}
+			edges = append(edges, matchingEdge{a, b, weight})
+		}
+
+		if s.bye != -1 && !s.history[a].hadBye {
+			edges = append(edges, matchingEdge{a, s.bye, byeUnit * int64(i+1)})
+		}
+	}
+	return edges
+}
+
+// scoreWeight rates a game by the players' score difference: highest within a score
+// group, and lower the further a player floats
+func (s *swissPairer) scoreWeight(a, b int) int64 {
+	n := int64(s.vertices)
+	difference := 2 * (s.history[a].score - s.history[b].score) // In half points
+	if difference < 0 {
+		difference = -difference
+	}
+	if difference == 0 {
+		return 3*n + 1
+	}
+	if difference > float64(2*n) {
+		difference = float64(2 * n)
+	}
+	return 3*n + 1 - n - int64(difference)
+}
+
+// positionWeight rates a game by the players' positions in their score groups. Within
+// a group, the highest weight goes to a player meeting the one half the group further
+// down; a player floating down should be the lowest of their group and meet the top
+// of the next.
+func (s *swissPairer) positionWeight(a, b int) int64 {
+	pa, pb := s.rank[a], s.rank[b]
+	if pa > pb {
+		pa, pb = pb, pa
+	}
+	ia, ib := pa-s.group[pa], pb-s.group[pb]
+
+	penalty := ib + s.size[pa] - 1 - ia
+	if s.group[pa] == s.group[pb] {
+		penalty = ib - ia - s.size[pa]/2
+		if penalty < 0 {
+			penalty = -penalty
+		}
+	}
+	return int64(2*s.vertices - penalty)
+}
+
+// clash returns whether two players have the same absolute colour preference
+func (s *swissPairer) clash(a, b int) bool {
+	colourA, strengthA := s.history[a].preference()
+	colourB, strengthB := s.history[b].preference()
+	return strengthA == 3 && strengthB == 3 && colourA == colourB
+}
+
+// lookahead returns mates if the remaining rounds can still be paired after them, and
+// otherwise the best round of a set of pairings that completes the tournament. Only
+// the last rounds are checked, and not even those while every player has at least
+// half the field left to meet after them, as any pairing then leaves a pairing for the
+// next round.
+func (s *swissPairer) lookahead(mates []int, remaining int) []int {
+	if remaining > swissLookahead {
+		return mates
+	}
+
+	edges := s.edges(false)
+	degrees := make([]int, s.vertices)
+	for _, e := range edges {
+		degrees[e.a]++
+		degrees[e.b]++
+	}
+	safe := true
+	for _, d := range degrees {
+		if d-remaining < s.vertices/2 {
+			safe = false
+		}
+	}
+	if safe {
+		return mates
+	}
+
+	var rest []matchingEdge
+	for _, e := range edges {
+		if mates[e.a] != e.b {
+			rest = append(rest, e)
+		}
+	}
+	if _, ok := perfectMatchings(s.vertices, rest, remaining, swissSearchBudget); ok {
+		return mates
+	}
+
+	rounds, ok := perfectMatchings(s.vertices, edges, remaining+1, swissSearchBudget)
+	if !ok {
+		return mates
+	}
+
+	// Prefer the round with the fewest colour clashes, then the highest weight
+	best, bestClashes, bestWeight := -1, 0, int64(0)
+	for r := 0; r <= remaining; r++ {
+		clashes, weight := 0, int64(0)
+		for k, e := range edges {
+			if rounds[k] != r {
+				continue
+			}
+			if e.b != s.bye && s.clash(e.a, e.b) {
+				clashes++
+			}
+			weight += e.weight
+		}
+		if best == -1 || clashes < bestClashes || (clashes == bestClashes && weight > bestWeight) {
+			best, bestClashes, bestWeight = r, clashes, weight
+		}
+	}
+
+	mates = make([]int, s.vertices)
+	for k, e := range edges {
+		if rounds[k] == best {
+			mates[e.a], mates[e.b] = e.b, e.a
+		}
+	}
+	return mates
+}
+
+// pairings returns the games of the round given the mate of each vertex, in board
+// order with the bye last
+func (s *swissPairer) pairings(round int, mates []int) []Pairing {
+	var pairings []Pairing
+	bye := Bye
+	for _, a := range s.order {
+		b := mates[a]
+		if b == s.bye {
+			bye = a
+			continue
+		}
+		if s.rank[b] < s.rank[a] {
+			continue
+		}
+
+		white, black := s.colours(a, b, len(pairings))
+		pairings = append(pairings, Pairing{Round: round, White: white, Black: black})
+	}
+	if bye != Bye {
+		pairings = append(pairings, Pairing{Round: round, White: bye, Black: Bye})
+	}
+	return pairings
+}
+
+// complete returns whether every vertex is matched
+func complete(mates []int) bool {
+	for _, m := range mates {
+		if m == -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// colours decides who has white between a and the lower ranked b. Each player gets
+// their preferred colour if they differ; otherwise the stronger preference wins, and
+// the higher ranked player's on equal terms. Before any games, colours alternate
+// between boards.
+func (s *swissPairer) colours(a, b, board int) (white, black int) {
+	colourA, strengthA := s.history[a].preference()
+	colourB, strengthB := s.history[b].preference()
+
+	colour := colourA // The colour a gets
+	switch {
+	case colourA == preferNone && colourB == preferNone:
+		colour = preferWhite
+		if board%2 == 1 {
+			colour = preferBlack
+		}
+	case colourA == preferNone || (colourA == colourB && strengthB > strengthA):
+		colour = -colourB
+	}
+
+	if colour == preferBlack {
+		return b, a
+	}
+	return a, b
+}
@@ -0,0 +1,183 @@
+package tournament
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSwissFirstRound(t *testing.T) {
+	pairings, err := SwissRound(8, 1, 1, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The top half meets the bottom half, with colours alternating between boards
+	expected := []Pairing{
+		{Round: 1, White: 0, Black: 4},
+		{Round: 1, White: 5, Black: 1},
+		{Round: 1, White: 2, Black: 6},
+		{Round: 1, White: 7, Black: 3},
+	}
+	if !reflect.DeepEqual(pairings, expected) {
+		t.Errorf("SwissRound() = %v; want %v", pairings, expected)
+	}
+}
+
+func TestSwissScoreGroups(t *testing.T) {
+	games := []Game{
+		{Pairing{Round: 1, White: 0, Black: 2}, WhiteWins},
+		{Pairing{Round: 1, White: 3, Black: 1}, BlackWins},
+	}
+
+	pairings, err := SwissRound(4, 2, 2, games)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Winners meet winners; each player gets the colour they did not have
+	expected := []Pairing{
+		{Round: 2, White: 1, Black: 0},
+		{Round: 2, White: 2, Black: 3},
+	}
+	if !reflect.DeepEqual(pairings, expected) {
+		t.Errorf("SwissRound() = %v; want %v", pairings, expected)
+	}
+}
+
+func TestSwissByes(t *testing.T) {
+	first, err := SwissRound(5, 1, 1, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bye := first[len(first)-1]
+	if bye.White != 4 || bye.Black != Bye {
+		t.Fatalf("Expected the lowest ranked player to get the bye, got %v", first)
+	}
+
+	var games []Game
+	for _, p := range first {
+		games = append(games, Game{p, WhiteWins})
+	}
+	second, err := SwissRound(5, 2, 2, games)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bye := second[len(second)-1]; bye.Black != Bye || bye.White == 4 {
+		t.Errorf("Expected a different player to get the second bye, got %v", second)
+	}
+}
+
+func TestSwissNoPairing(t *testing.T) {
+	games := []Game{{Pairing{Round: 1, White: 0, Black: 1}, Draw}}
+	if _, err := SwissRound(2, 2, 2, games); err == nil {
+		t.Error("Expected error but got none")
+	}
+	if _, err := SwissRound(1, 1, 1, nil); err == nil {
+		t.Error("Expected error but got none")
+	}
+}
+
+func TestSwissTournament(t *testing.T) {
+	for _, players := range []int{6, 7, 8, 11, 16, 25} {
+		games := playSwiss(t, players, SwissRounds(players))
+
+		for player, p := range swissHistory(players, games) {
+			difference := 0
+			for _, c := range p.colours {
+				difference += c
+			}
+			if difference < -2 || difference > 2 {
+				t.Errorf("%d players: player %d has colours %v", players, player, p.colours)
+			}
+		}
+	}
+}
+
+func TestSwissFullLength(t *testing.T) {
+	tests := []struct {
+		players int
+		rounds  int
+	}{
+		{4, 3},
+		{10, 9},
+		{10, 8},
+		{31, 30},
+		{31, 31},
+		{41, 40},
+		{64, 63},
+	}
+
+	for _, tt := range tests {
+		games := playSwiss(t, tt.players, tt.rounds)
+
+		// With a round for every opponent, and for the bye, everyone met everyone
+		if tt.rounds == tt.players-1+tt.players%2 {
+			expected := tt.players * (tt.players - 1) / 2
+			if played := len(games) - tt.players%2*tt.rounds; played != expected {
+				t.Errorf("%d players: %d games played; want %d", tt.players, played, expected)
+			}
+		}
+	}
+}
+
+// playSwiss pairs every round of a Swiss tournament with random results, checking that
+// each round pairs every player once, nobody meets an opponent twice and nobody has
+// two byes
+func playSwiss(t *testing.T, players, rounds int) []Game {
+	t.Helper()
+	rand := rand.New(rand.NewSource(int64(players)))
+
+	var games []Game
+	for round := 1; round <= rounds; round++ {
+		pairings, err := SwissRound(players, round, rounds, games)
+		if err != nil {
+			t.Fatalf("%d players, round %d: unexpected error: %v", players, round, err)
+		}
+
+		seen := map[int]bool{}
+		for _, p := range pairings {
+			for _, player := range []int{p.White, p.Black} {
+				if player == Bye {
+					continue
+				}
+				if seen[player] {
+					t.Errorf("%d players: player %d paired twice in round %d", players, player, round)
+				}
+				seen[player] = true
+			}
+
+			result := []string{WhiteWins, BlackWins, Draw}[rand.Intn(3)]
+			if p.Black == Bye {
+				result = WhiteWins
+			}
+			games = append(games, Game{p, result})
+		}
+		if len(seen) != players {
+			t.Errorf("%d players: %d paired in round %d", players, len(seen), round)
+		}
+	}
+
+	met := map[[2]int]bool{}
+	byes := map[int]int{}
+	for _, g := range games {
+		if g.Black == Bye {
+			byes[g.White]++
+			continue
+		}
+		pair := [2]int{g.White, g.Black}
+		if pair[0] > pair[1] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		if met[pair] {
+			t.Errorf("%d players: rematch between %v", players, pair)
+		}
+		met[pair] = true
+	}
+	for player, count := range byes {
+		if count > 1 {
+			t.Errorf("%d players: player %d had %d byes", players, player, count)
+		}
+	}
+	return games
+}
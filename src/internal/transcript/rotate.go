@@ -0,0 +1,113 @@
+package transcript
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is an io.WriteCloser appending to a file that is rotated once it would
+// grow beyond a size limit. Rotated files get the suffixes .1 (newest) to .N.
+type RotatingFile struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu     sync.Mutex
+	file   *os.File // Nil after a rotation that could not open the new file
+	size   int64
+	closed bool
+}
+
+// OpenRotatingFile opens path for appending, keeping at most maxBackups rotated files
+// of about maxBytes each. A maxBytes of zero or less never rotates.
+func OpenRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the current file and records its size
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat transcript: %v", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would not fit in the current file. A failed
+// rotation does not stop the transcript: writing goes on in whichever file is open,
+// and a file that could not be reopened is opened again by the next write.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, fmt.Errorf("transcript is closed")
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil && r.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups by one, dropping the oldest, and starts a new file. If the
+// current file cannot be moved aside it is reopened and kept.
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		err = fmt.Errorf("failed to close transcript: %v", err)
+	} else if r.maxBackups > 0 {
+		for i := r.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if renameErr := os.Rename(r.path, r.path+".1"); renameErr != nil {
+			err = fmt.Errorf("failed to rotate transcript: %v", renameErr)
+		}
+	} else if removeErr := os.Remove(r.path); removeErr != nil {
+		err = fmt.Errorf("failed to rotate transcript: %v", removeErr)
+	}
+
+	if openErr := r.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// Close closes the current file; later writes fail
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
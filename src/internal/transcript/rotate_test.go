@@ -0,0 +1,114 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engine.log")
+	file, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	// Each write fills a file, so every later write rotates
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q; want %q", filepath.Base(name), data, content)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected the oldest file to be dropped")
+	}
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engine.log")
+	if err := os.WriteFile(path, []byte("earlier\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	file, err := OpenRotatingFile(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	file.Write([]byte("later\n"))
+	if err := file.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "earlier\nlater\n" {
+		t.Errorf("Got %q", data)
+	}
+
+	if _, err := file.Write([]byte("closed\n")); err == nil {
+		t.Error("Expected error after close but got none")
+	}
+}
+
+func TestRotatingFileFailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engine.log")
+	file, err := OpenRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	// A non-empty directory in the backup's place makes the rotation fail
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocker"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	for _, line := range []string{"first\n", "second\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != "first\nsecond\n" {
+		t.Errorf("Expected writing to go on in the current file, got %q", data)
+	}
+
+	// Once the rotation can happen again, it does
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+	if _, err := file.Write([]byte("third\n")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "third\n" {
+		t.Errorf("Expected a new file after the rotation, got %q", data)
+	}
+
+	// A file that could not be reopened is opened on the next write
+	file.file.Close()
+	file.file = nil
+	if _, err := file.Write([]byte("fourth\n")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "fourth\n" {
+		t.Errorf("Expected the file to be reopened and rotated, got %q", data)
+	}
+	if data, _ := os.ReadFile(path + ".1"); string(data) != "third\n" {
+		t.Errorf("Expected the previous file as the backup, got %q", data)
+	}
+}
@@ -0,0 +1,78 @@
+// Package transcript records the lines exchanged with engines and players, so a
+// misbehaving peer can be diagnosed after the fact.
+package transcript
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Transcript writes timestamped lines to an io.Writer. It is safe for concurrent use,
+// so several engines may share one transcript. A nil *Transcript discards everything.
+type Transcript struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer // File opened by Open, nil for transcripts created with New
+	now    func() time.Time
+}
+
+// New creates a transcript writing to w. Closing the transcript leaves w open.
+func New(w io.Writer) *Transcript {
+	return &Transcript{w: w, now: time.Now}
+}
+
+// Open creates a transcript writing to a RotatingFile at path, see OpenRotatingFile
+func Open(path string, maxBytes int64, maxBackups int) (*Transcript, error) {
+	file, err := OpenRotatingFile(path, maxBytes, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return &Transcript{w: file, closer: file, now: time.Now}, nil
+}
+
+// Close closes the file of a transcript created with Open. Lines recorded afterwards
+// are dropped. Closing a nil or already closed transcript does nothing.
+func (t *Transcript) Close() error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closer == nil {
+		return nil
+	}
+	err := t.closer.Close()
+	t.closer = nil
+	t.w = io.Discard
+	return err
+}
+
+// Sent records a line sent to the peer identified by instance
+func (t *Transcript) Sent(instance, line string) {
+	t.write(instance, ">", line)
+}
+
+// Received records a line received from the peer identified by instance
+func (t *Transcript) Received(instance, line string) {
+	t.write(instance, "<", line)
+}
+
+// Note records an event that is not part of the exchange, such as a process exiting
+func (t *Transcript) Note(instance, line string) {
+	t.write(instance, "#", line)
+}
+
+// write formats a single transcript line; write errors are ignored so that a full
+// disk never interrupts a game
+func (t *Transcript) write(instance, direction, line string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "%s [%s] %s %s\n", t.now().UTC().Format("2006-01-02T15:04:05.000000Z"), instance, direction, line)
+}
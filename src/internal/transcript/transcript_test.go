@@ -0,0 +1,98 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTranscript(t *testing.T) {
+	var out strings.Builder
+	transcript := New(&out)
+	transcript.now = func() time.Time {
+		return time.Date(2024, 3, 1, 12, 30, 45, 123456000, time.UTC)
+	}
+
+	transcript.Sent("engine-1", "uci")
+	transcript.Received("engine-1", "uciok")
+	transcript.Note("engine-2", "exited")
+
+	expected := "2024-03-01T12:30:45.123456Z [engine-1] > uci\n" +
+		"2024-03-01T12:30:45.123456Z [engine-1] < uciok\n" +
+		"2024-03-01T12:30:45.123456Z [engine-2] # exited\n"
+	if out.String() != expected {
+		t.Errorf("Got transcript:\n%s\nwant:\n%s", out.String(), expected)
+	}
+}
+
+func TestNilTranscript(t *testing.T) {
+	var transcript *Transcript
+	transcript.Sent("engine", "uci")
+	transcript.Received("engine", "uciok")
+	if err := transcript.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestTranscriptClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	transcript, err := Open(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	transcript.Sent("player", "request")
+	if err := transcript.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	transcript.Received("player", "reply")
+
+	// Closing twice is harmless
+	if err := transcript.Close(); err != nil {
+		t.Errorf("Unexpected error on second close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read transcript: %v", err)
+	}
+	if !strings.Contains(string(data), "[player] > request") || strings.Contains(string(data), "reply") {
+		t.Errorf("Unexpected transcript after close:\n%s", data)
+	}
+
+	// A transcript created with New leaves its writer open
+	var out strings.Builder
+	if err := New(&out).Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestTranscriptConcurrentWriters(t *testing.T) {
+	var out strings.Builder
+	transcript := New(&out)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				transcript.Sent("engine", "isready")
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 400 {
+		t.Fatalf("Expected 400 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasSuffix(line, "[engine] > isready") {
+			t.Fatalf("Interleaved line %q", line)
+		}
+	}
+}
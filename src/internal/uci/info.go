@@ -0,0 +1,175 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Score represents an engine evaluation from the point of view of the side to move
+type Score struct {
+	CP         int  // Evaluation in centipawns, set when IsMate is false
+	Mate       int  // Moves until mate, negative when the side to move is getting mated
+	IsMate     bool // Whether the score is a mate score
+	LowerBound bool // The score is only a lower bound
+	UpperBound bool // The score is only an upper bound
+}
+
+// String returns the score in UCI notation (e.g., "cp 35" or "mate -3")
+func (s Score) String() string {
+	var str string
+	if s.IsMate {
+		str = fmt.Sprintf("mate %d", s.Mate)
+	} else {
+		str = fmt.Sprintf("cp %d", s.CP)
+	}
+	if s.LowerBound {
+		str += " lowerbound"
+	} else if s.UpperBound {
+		str += " upperbound"
+	}
+	return str
+}
+
+// Info holds the search information reported by an engine on an "info" line
+type Info struct {
+	Depth    int
+	SelDepth int
+	Score    Score
+	HasScore bool // Whether the line carried a score
+	Nodes    int64
+	NPS      int64
+	HashFull int // Hash table usage in permill
+	TBHits   int64
+	Time     time.Duration
+	MultiPV  int      // Index of the line when running in MultiPV mode, 1 otherwise
+	PV       []string // Principal variation in UCI move notation
+	String   string   // Free-form text sent with "info string"
+}
+
+// ParseInfo parses a UCI "info" line into an Info
+func ParseInfo(line string) (Info, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return Info{}, fmt.Errorf("not an info line: %q", line)
+	}
+
+	info := Info{MultiPV: 1}
+	for i := 1; i < len(fields); i++ {
+		key := fields[i]
+		switch key {
+		case "string":
+			info.String = strings.Join(fields[i+1:], " ")
+			return info, nil
+		case "pv":
+			info.PV = append([]string(nil), fields[i+1:]...)
+			return info, nil
+		case "score":
+			n, err := parseScore(fields[i+1:], &info.Score)
+			if err != nil {
+				return Info{}, err
+			}
+			info.HasScore = true
+			i += n
+		case "depth", "seldepth", "nodes", "nps", "hashfull", "tbhits", "time", "multipv":
+			if i+1 >= len(fields) {
+				return Info{}, fmt.Errorf("missing value for %s", key)
+			}
+			value, err := strconv.ParseInt(fields[i+1], 10, 64)
+			if err != nil {
+				return Info{}, fmt.Errorf("invalid value for %s: %v", key, err)
+			}
+			i++
+			switch key {
+			case "depth":
+				info.Depth = int(value)
+			case "seldepth":
+				info.SelDepth = int(value)
+			case "nodes":
+				info.Nodes = value
+			case "nps":
+				info.NPS = value
+			case "hashfull":
+				info.HashFull = int(value)
+			case "tbhits":
+				info.TBHits = value
+			case "time":
+				info.Time = time.Duration(value) * time.Millisecond
+			case "multipv":
+				info.MultiPV = int(value)
+			}
+		case "currmove":
+			// Not tracked, skip the move
+			i++
+		case "refutation", "currline":
+			// Not tracked, both consume the rest of the line
+			return info, nil
+		default:
+			// Unknown or untracked token (e.g., currmovenumber, cpuload), skip its value
+			if i+1 < len(fields) {
+				if _, err := strconv.ParseInt(fields[i+1], 10, 64); err == nil {
+					i++
+				}
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// parseScore parses the tokens following "score" and returns how many were consumed
+func parseScore(fields []string, score *Score) (int, error) {
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("incomplete score")
+	}
+
+	value, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid score value: %v", err)
+	}
+
+	switch fields[0] {
+	case "cp":
+		score.CP = value
+	case "mate":
+		score.Mate = value
+		score.IsMate = true
+	default:
+		return 0, fmt.Errorf("invalid score type: %s", fields[0])
+	}
+
+	consumed := 2
+	if len(fields) > 2 {
+		switch fields[2] {
+		case "lowerbound":
+			score.LowerBound = true
+			consumed++
+		case "upperbound":
+			score.UpperBound = true
+			consumed++
+		}
+	}
+
+	return consumed, nil
+}
+
+// ParseBestMove parses a "bestmove" line, returning the best move and the optional ponder move.
+// An engine with no legal move answers "bestmove (none)" or "bestmove 0000", for which the best
+// move is empty.
+func ParseBestMove(line string) (string, string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "bestmove" {
+		return "", "", false
+	}
+	if fields[1] == "(none)" || fields[1] == "0000" {
+		return "", "", true
+	}
+
+	ponder := ""
+	if len(fields) >= 4 && fields[2] == "ponder" {
+		ponder = fields[3]
+	}
+
+	return fields[1], ponder, true
+}
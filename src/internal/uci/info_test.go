@@ -0,0 +1,190 @@
+package uci
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		expected    Info
+		expectError bool
+	}{
+		{
+			name: "Full search line",
+			line: "info depth 20 seldepth 28 multipv 1 score cp 35 nodes 1234567 nps 987654 hashfull 412 tbhits 3 time 1250 pv e2e4 e7e5 g1f3",
+			expected: Info{
+				Depth:    20,
+				SelDepth: 28,
+				MultiPV:  1,
+				Score:    Score{CP: 35},
+				HasScore: true,
+				Nodes:    1234567,
+				NPS:      987654,
+				HashFull: 412,
+				TBHits:   3,
+				Time:     1250 * time.Millisecond,
+				PV:       []string{"e2e4", "e7e5", "g1f3"},
+			},
+		},
+		{
+			name: "Mate score",
+			line: "info depth 12 multipv 2 score mate -3 pv h7h8q",
+			expected: Info{
+				Depth:    12,
+				MultiPV:  2,
+				Score:    Score{Mate: -3, IsMate: true},
+				HasScore: true,
+				PV:       []string{"h7h8q"},
+			},
+		},
+		{
+			name: "Lower bound",
+			line: "info depth 15 score cp 120 lowerbound nodes 1000",
+			expected: Info{
+				Depth:    15,
+				MultiPV:  1,
+				Score:    Score{CP: 120, LowerBound: true},
+				HasScore: true,
+				Nodes:    1000,
+			},
+		},
+		{
+			name: "Upper bound",
+			line: "info depth 15 score cp -40 upperbound",
+			expected: Info{
+				Depth:    15,
+				MultiPV:  1,
+				Score:    Score{CP: -40, UpperBound: true},
+				HasScore: true,
+			},
+		},
+		{
+			name: "Current move",
+			line: "info depth 5 currmove e2e4 currmovenumber 1",
+			expected: Info{
+				Depth:   5,
+				MultiPV: 1,
+			},
+		},
+		{
+			name: "Info string",
+			line: "info string NNUE evaluation using nn-b1a57edbea57.nnue enabled",
+			expected: Info{
+				MultiPV: 1,
+				String:  "NNUE evaluation using nn-b1a57edbea57.nnue enabled",
+			},
+		},
+		{
+			name:        "Not an info line",
+			line:        "bestmove e2e4",
+			expectError: true,
+		},
+		{
+			name:        "Invalid depth",
+			line:        "info depth x",
+			expectError: true,
+		},
+		{
+			name:        "Invalid score type",
+			line:        "info score wdl 10",
+			expectError: true,
+		},
+		{
+			name:        "Missing value",
+			line:        "info nodes",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info, err := ParseInfo(test.line)
+			if test.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(info, test.expected) {
+				t.Errorf("ParseInfo(%q) = %+v; want %+v", test.line, info, test.expected)
+			}
+		})
+	}
+}
+
+func TestScoreString(t *testing.T) {
+	tests := []struct {
+		score    Score
+		expected string
+	}{
+		{Score{CP: 35}, "cp 35"},
+		{Score{Mate: -3, IsMate: true}, "mate -3"},
+		{Score{CP: 120, LowerBound: true}, "cp 120 lowerbound"},
+		{Score{CP: -40, UpperBound: true}, "cp -40 upperbound"},
+	}
+
+	for _, test := range tests {
+		result := test.score.String()
+		if result != test.expected {
+			t.Errorf("Score.String() = %s; want %s", result, test.expected)
+		}
+	}
+}
+
+func TestParseBestMove(t *testing.T) {
+	tests := []struct {
+		line     string
+		bestMove string
+		ponder   string
+		ok       bool
+	}{
+		{"bestmove e2e4", "e2e4", "", true},
+		{"bestmove e2e4 ponder e7e5", "e2e4", "e7e5", true},
+		{"bestmove (none)", "", "", true},
+		{"bestmove 0000", "", "", true},
+		{"bestmove", "", "", false},
+		{"info depth 1", "", "", false},
+	}
+
+	for _, test := range tests {
+		bestMove, ponder, ok := ParseBestMove(test.line)
+		if bestMove != test.bestMove || ponder != test.ponder || ok != test.ok {
+			t.Errorf("ParseBestMove(%q) = %q, %q, %v; want %q, %q, %v",
+				test.line, bestMove, ponder, ok, test.bestMove, test.ponder, test.ok)
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	var id ID
+	lines := []string{
+		"id name Stockfish 16.1",
+		"id author the Stockfish developers (see AUTHORS file)",
+		"option name Hash type spin default 16 min 1 max 33554432",
+	}
+
+	var parsed int
+	for _, line := range lines {
+		if ParseID(line, &id) {
+			parsed++
+		}
+	}
+
+	if parsed != 2 {
+		t.Errorf("Expected 2 id lines, got %d", parsed)
+	}
+
+	expected := ID{Name: "Stockfish 16.1", Author: "the Stockfish developers (see AUTHORS file)"}
+	if id != expected {
+		t.Errorf("ParseID() = %+v; want %+v", id, expected)
+	}
+}
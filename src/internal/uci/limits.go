@@ -0,0 +1,133 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchLimits controls how long the engine searches. Zero values are omitted
+// from the go command; a search without any limit runs until stopped.
+type SearchLimits struct {
+	Depth     int
+	Nodes     int64
+	Mate      int
+	MoveTime  time.Duration
+	WTime     time.Duration
+	BTime     time.Duration
+	WInc      time.Duration
+	BInc      time.Duration
+	MovesToGo int
+	Infinite  bool
+	Ponder    bool // Search in ponder mode until ponderhit or stop
+}
+
+// GoCommand builds the UCI go command for the limits
+func (l SearchLimits) GoCommand() string {
+	var args []string
+	if l.Depth > 0 {
+		args = append(args, fmt.Sprintf("depth %d", l.Depth))
+	}
+	if l.Nodes > 0 {
+		args = append(args, fmt.Sprintf("nodes %d", l.Nodes))
+	}
+	if l.Mate > 0 {
+		args = append(args, fmt.Sprintf("mate %d", l.Mate))
+	}
+	if l.MoveTime > 0 {
+		args = append(args, fmt.Sprintf("movetime %d", l.MoveTime.Milliseconds()))
+	}
+	if l.WTime > 0 {
+		args = append(args, fmt.Sprintf("wtime %d", l.WTime.Milliseconds()))
+	}
+	if l.BTime > 0 {
+		args = append(args, fmt.Sprintf("btime %d", l.BTime.Milliseconds()))
+	}
+	if l.WInc > 0 {
+		args = append(args, fmt.Sprintf("winc %d", l.WInc.Milliseconds()))
+	}
+	if l.BInc > 0 {
+		args = append(args, fmt.Sprintf("binc %d", l.BInc.Milliseconds()))
+	}
+	if l.MovesToGo > 0 {
+		args = append(args, fmt.Sprintf("movestogo %d", l.MovesToGo))
+	}
+	if l.Infinite || (len(args) == 0 && !l.Ponder) {
+		args = append(args, "infinite")
+	}
+	if l.Ponder {
+		args = append([]string{"ponder"}, args...)
+	}
+	return "go " + strings.Join(args, " ")
+}
+
+// Bounded returns whether the limits end the search without a stop command
+func (l SearchLimits) Bounded() bool {
+	if l.Infinite || l.Ponder {
+		return false
+	}
+	return l.Depth > 0 || l.Nodes > 0 || l.Mate > 0 || l.MoveTime > 0 || l.WTime > 0 || l.BTime > 0
+}
+
+// ParseGo parses a UCI go command into search limits. Unknown tokens such as
+// searchmoves are skipped, and a go command without limits searches until stopped,
+// as GoCommand writes it.
+func ParseGo(line string) (SearchLimits, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "go" {
+		return SearchLimits{}, fmt.Errorf("not a go command: %q", line)
+	}
+
+	var limits SearchLimits
+	for i := 1; i < len(fields); i++ {
+		key := fields[i]
+		switch key {
+		case "infinite":
+			limits.Infinite = true
+			continue
+		case "ponder":
+			limits.Ponder = true
+			continue
+		case "depth", "nodes", "mate", "movetime", "wtime", "btime", "winc", "binc", "movestogo":
+		default:
+			continue
+		}
+
+		if i+1 >= len(fields) {
+			return SearchLimits{}, fmt.Errorf("missing value for %s", key)
+		}
+		i++
+		value, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return SearchLimits{}, fmt.Errorf("invalid %s: %v", key, err)
+		}
+
+		ms := time.Duration(value) * time.Millisecond
+		switch key {
+		case "depth":
+			limits.Depth = int(value)
+		case "nodes":
+			limits.Nodes = value
+		case "mate":
+			limits.Mate = int(value)
+		case "movetime":
+			limits.MoveTime = ms
+		case "wtime":
+			limits.WTime = ms
+		case "btime":
+			limits.BTime = ms
+		case "winc":
+			limits.WInc = ms
+		case "binc":
+			limits.BInc = ms
+		case "movestogo":
+			limits.MovesToGo = int(value)
+		}
+	}
+
+	if limits == (SearchLimits{}) {
+		limits.Infinite = true
+	}
+	return limits, nil
+}
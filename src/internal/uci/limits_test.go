@@ -0,0 +1,166 @@
+package uci
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGoCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   SearchLimits
+		expected string
+	}{
+		{
+			name:     "No limits",
+			limits:   SearchLimits{},
+			expected: "go infinite",
+		},
+		{
+			name:     "Infinite",
+			limits:   SearchLimits{Infinite: true},
+			expected: "go infinite",
+		},
+		{
+			name:     "Move time",
+			limits:   SearchLimits{MoveTime: time.Second},
+			expected: "go movetime 1000",
+		},
+		{
+			name:     "Depth and nodes",
+			limits:   SearchLimits{Depth: 12, Nodes: 50000},
+			expected: "go depth 12 nodes 50000",
+		},
+		{
+			name: "Clock",
+			limits: SearchLimits{
+				WTime:     5 * time.Minute,
+				BTime:     4 * time.Minute,
+				WInc:      2 * time.Second,
+				BInc:      2 * time.Second,
+				MovesToGo: 20,
+			},
+			expected: "go wtime 300000 btime 240000 winc 2000 binc 2000 movestogo 20",
+		},
+		{
+			name:     "Ponder",
+			limits:   SearchLimits{Ponder: true, WTime: time.Minute, BTime: time.Minute},
+			expected: "go ponder wtime 60000 btime 60000",
+		},
+		{
+			name:     "Ponder without limits",
+			limits:   SearchLimits{Ponder: true},
+			expected: "go ponder",
+		},
+		{
+			name:     "Mate search",
+			limits:   SearchLimits{Mate: 3},
+			expected: "go mate 3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.limits.GoCommand()
+			if result != test.expected {
+				t.Errorf("GoCommand() = %q; want %q", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestBounded(t *testing.T) {
+	tests := []struct {
+		limits   SearchLimits
+		expected bool
+	}{
+		{SearchLimits{}, false},
+		{SearchLimits{Infinite: true, Depth: 10}, false},
+		{SearchLimits{Depth: 10}, true},
+		{SearchLimits{MoveTime: time.Second}, true},
+		{SearchLimits{WTime: time.Minute, BTime: time.Minute}, true},
+		{SearchLimits{MovesToGo: 10}, false},
+		{SearchLimits{Ponder: true, MoveTime: time.Second}, false},
+	}
+
+	for _, test := range tests {
+		result := test.limits.Bounded()
+		if result != test.expected {
+			t.Errorf("%+v.Bounded() = %v; want %v", test.limits, result, test.expected)
+		}
+	}
+}
+
+func TestParseGo(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		expected    SearchLimits
+		expectError bool
+	}{
+		{
+			name:     "Clock",
+			line:     "go wtime 300000 btime 290000 winc 2000 binc 2000 movestogo 30",
+			expected: SearchLimits{WTime: 5 * time.Minute, BTime: 290 * time.Second, WInc: 2 * time.Second, BInc: 2 * time.Second, MovesToGo: 30},
+		},
+		{
+			name:     "Ponder with depth",
+			line:     "go ponder depth 12",
+			expected: SearchLimits{Ponder: true, Depth: 12},
+		},
+		{
+			name:     "Search moves are skipped",
+			line:     "go searchmoves e2e4 d2d4 movetime 500",
+			expected: SearchLimits{MoveTime: 500 * time.Millisecond},
+		},
+		{
+			name:     "Bare go",
+			line:     "go",
+			expected: SearchLimits{Infinite: true},
+		},
+		{
+			name:        "Missing value",
+			line:        "go depth",
+			expectError: true,
+		},
+		{
+			name:        "Invalid value",
+			line:        "go nodes many",
+			expectError: true,
+		},
+		{
+			name:        "Not a go command",
+			line:        "position startpos",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits, err := ParseGo(tt.line)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if limits != tt.expected {
+				t.Errorf("ParseGo(%q) = %+v; want %+v", tt.line, limits, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseGoRoundTrip(t *testing.T) {
+	limits := SearchLimits{Depth: 8, Nodes: 1000, MoveTime: 250 * time.Millisecond, WTime: time.Minute, BInc: time.Second}
+	parsed, err := ParseGo(limits.GoCommand())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if parsed != limits {
+		t.Errorf("ParseGo(GoCommand()) = %+v; want %+v", parsed, limits)
+	}
+}
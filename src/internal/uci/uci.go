@@ -0,0 +1,85 @@
+// Package uci defines the engine abstraction and protocol types shared by clients
+// of engines speaking the Universal Chess Interface, such as Stockfish, Lc0 or Komodo.
+package uci
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ErrNoLegalMove is returned when the engine reports that the position has no legal move
+var ErrNoLegalMove = fmt.Errorf("engine has no legal move")
+
+// Engine is a chess engine driven over UCI
+type Engine interface {
+	// GetMove returns the engine's move for the position
+	GetMove(fen string) (string, error)
+	// Analyze streams the engine's info lines for the position until the search ends or ctx is done
+	Analyze(ctx context.Context, fen string, limits SearchLimits) (<-chan AnalysisUpdate, error)
+	// SetOption sets a UCI option
+	SetOption(name, value string) error
+	// NewGame tells the engine that the following searches belong to a new game
+	NewGame() error
+	// Close shuts the engine down
+	Close() error
+}
+
+// ID identifies an engine as reported by its "id" lines during the handshake
+type ID struct {
+	Name   string
+	Author string
+}
+
+// ParseID updates id from an "id name" or "id author" line, returning whether the line was one
+func ParseID(line string, id *ID) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "id" {
+		return false
+	}
+
+	value := strings.Join(fields[2:], " ")
+	switch fields[1] {
+	case "name":
+		id.Name = value
+	case "author":
+		id.Author = value
+	default:
+		return false
+	}
+	return true
+}
+
+// AnalysisUpdate is a single event of a running analysis
+type AnalysisUpdate struct {
+	Info     Info   // Parsed info line, unset on the final update
+	Done     bool   // Whether this is the final update
+	BestMove string // Best move, set on the final update
+	Ponder   string // Ponder move, set on the final update if the engine gave one
+	Err      error  // Set on the final update if the analysis failed
+}
+
+// Position is a start position and the moves played from it, in UCI notation
+type Position struct {
+	FEN   string // Start position, the standard starting position when empty
+	Moves []string
+}
+
+// Command builds the UCI position command for the position
+func (p Position) Command() string {
+	command := "position startpos"
+	if p.FEN != "" {
+		command = "position fen " + p.FEN
+	}
+	if len(p.Moves) > 0 {
+		command += " moves " + strings.Join(p.Moves, " ")
+	}
+	return command
+}
+
+// Play returns the position after the given moves, leaving p unchanged
+func (p Position) Play(moves ...string) Position {
+	played := make([]string, 0, len(p.Moves)+len(moves))
+	played = append(played, p.Moves...)
+	return Position{FEN: p.FEN, Moves: append(played, moves...)}
+}
@@ -0,0 +1,55 @@
+package uci
+
+import (
+	"testing"
+)
+
+func TestPositionCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		position Position
+		expected string
+	}{
+		{
+			name:     "FEN only",
+			position: Position{FEN: "8/8/8/8/8/8/8/K6k w - - 0 1"},
+			expected: "position fen 8/8/8/8/8/8/8/K6k w - - 0 1",
+		},
+		{
+			name:     "FEN with moves",
+			position: Position{FEN: "8/8/8/8/8/8/8/K6k w - - 0 1", Moves: []string{"a1a2", "h1h2"}},
+			expected: "position fen 8/8/8/8/8/8/8/K6k w - - 0 1 moves a1a2 h1h2",
+		},
+		{
+			name:     "Start position with moves",
+			position: Position{Moves: []string{"e2e4"}},
+			expected: "position startpos moves e2e4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if command := tt.position.Command(); command != tt.expected {
+				t.Errorf("Command() = %q; want %q", command, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPositionPlay(t *testing.T) {
+	position := Position{FEN: "8/8/8/8/8/8/8/K6k w - - 0 1", Moves: make([]string, 1, 4)}
+	position.Moves[0] = "a1a2"
+
+	first := position.Play("h1h2")
+	second := position.Play("h1g1")
+
+	if command := first.Command(); command != "position fen 8/8/8/8/8/8/8/K6k w - - 0 1 moves a1a2 h1h2" {
+		t.Errorf("Play(h1h2) = %q", command)
+	}
+	if command := second.Command(); command != "position fen 8/8/8/8/8/8/8/K6k w - - 0 1 moves a1a2 h1g1" {
+		t.Errorf("Play(h1g1) = %q", command)
+	}
+	if len(position.Moves) != 1 {
+		t.Errorf("Play changed the original position: %v", position.Moves)
+	}
+}
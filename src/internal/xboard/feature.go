@@ -0,0 +1,115 @@
+package xboard
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Features holds what an engine announced with "feature" commands after protover 2.
+// Features the engine does not mention keep the protocol defaults.
+type Features struct {
+	Ping     bool     // Engine answers "ping N" with "pong N"
+	SetBoard bool     // Engine accepts "setboard FEN"
+	UserMove bool     // Moves must be prefixed with "usermove"
+	Time     bool     // Engine accepts "time" and "otim"
+	Name     string   // Engine name from "myname"
+	Variants []string // Supported variants
+	Options  []string // Option descriptions from "option"
+	Done     bool     // Engine sent done=1
+}
+
+// supportedFeatures lists the features the client accepts
+var supportedFeatures = map[string]bool{
+	"ping":     true,
+	"setboard": true,
+	"usermove": true,
+	"time":     true,
+	"myname":   true,
+	"variants": true,
+	"option":   true,
+	"done":     true,
+	"sigint":   true,
+	"sigterm":  true,
+	"reuse":    true,
+	"colors":   true,
+	"name":     true,
+	"debug":    true,
+	"analyze":  true,
+	"draw":     true,
+}
+
+// featurePair is a single name=value pair of a feature command
+type featurePair struct {
+	Name  string
+	Value string
+}
+
+// parseFeatureLine splits a "feature" line into its name=value pairs. Values may be
+// quoted to contain spaces.
+func parseFeatureLine(line string) ([]featurePair, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "feature")
+	if !ok {
+		return nil, fmt.Errorf("not a feature line: %q", line)
+	}
+
+	var pairs []featurePair
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return pairs, nil
+		}
+
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 || strings.ContainsAny(rest[:eq], " \t") {
+			return nil, fmt.Errorf("malformed feature in %q", line)
+		}
+		name := rest[:eq]
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated value for %s in %q", name, line)
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+
+		pairs = append(pairs, featurePair{Name: name, Value: value})
+	}
+}
+
+// apply records a feature and returns whether the client accepts it
+func (f *Features) apply(pair featurePair) bool {
+	on := pair.Value == "1"
+	switch pair.Name {
+	case "ping":
+		f.Ping = on
+	case "setboard":
+		f.SetBoard = on
+	case "usermove":
+		f.UserMove = on
+	case "san":
+		// Moves are always exchanged in coordinate notation
+		return !on
+	case "time":
+		f.Time = on
+	case "myname":
+		f.Name = pair.Value
+	case "variants":
+		f.Variants = strings.Split(pair.Value, ",")
+	case "option":
+		f.Options = append(f.Options, pair.Value)
+	case "done":
+		f.Done = on
+	}
+	return supportedFeatures[pair.Name]
+}
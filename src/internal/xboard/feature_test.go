@@ -0,0 +1,88 @@
+package xboard
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFeatureLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		expected    []featurePair
+		expectError bool
+	}{
+		{
+			name:     "Plain values",
+			line:     "feature ping=1 setboard=1 done=0",
+			expected: []featurePair{{"ping", "1"}, {"setboard", "1"}, {"done", "0"}},
+		},
+		{
+			name:     "Quoted values",
+			line:     `feature myname="Fake Engine 1.0" variants="normal,fischerandom" usermove=1`,
+			expected: []featurePair{{"myname", "Fake Engine 1.0"}, {"variants", "normal,fischerandom"}, {"usermove", "1"}},
+		},
+		{
+			name:     "Option with spaces",
+			line:     `feature option="Hash -spin 64 1 1024"`,
+			expected: []featurePair{{"option", "Hash -spin 64 1 1024"}},
+		},
+		{
+			name:        "Unterminated quote",
+			line:        `feature myname="Fake`,
+			expectError: true,
+		},
+		{
+			name:        "Missing value",
+			line:        "feature ping",
+			expectError: true,
+		},
+		{
+			name:        "Not a feature line",
+			line:        "move e2e4",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := parseFeatureLine(tt.line)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(pairs, tt.expected) {
+				t.Errorf("parseFeatureLine(%q) = %+v; want %+v", tt.line, pairs, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyFeature(t *testing.T) {
+	tests := []struct {
+		pair     featurePair
+		accepted bool
+	}{
+		{featurePair{"ping", "1"}, true},
+		{featurePair{"san", "1"}, false},
+		{featurePair{"san", "0"}, true},
+		{featurePair{"smp", "1"}, false},
+		{featurePair{"unknown", "1"}, false},
+	}
+
+	var features Features
+	for _, tt := range tests {
+		if accepted := features.apply(tt.pair); accepted != tt.accepted {
+			t.Errorf("apply(%+v) = %v; want %v", tt.pair, accepted, tt.accepted)
+		}
+	}
+
+	if !features.Ping {
+		t.Error("Expected ping to be recorded")
+	}
+}
@@ -0,0 +1,444 @@
+// Package xboard drives chess engines speaking the XBoard protocol (CECP), so engines
+// without UCI support can play behind the same move-fetching contract as
+// stockfish.Handler.
+package xboard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/engine"
+	"github.com/shehio/envoy/src/internal/transcript"
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+const (
+	defaultCommandTimeout = 10 * time.Second
+	defaultQuitTimeout    = 5 * time.Second
+	defaultSearchTimeout  = 5 * time.Minute
+)
+
+// Options configures the engine process started by a Handler
+type Options struct {
+	Path           string            // Engine binary
+	Args           []string          // Extra command line arguments
+	CommandTimeout time.Duration     // How long to wait for features, pongs and forced moves, defaults to 10s
+	EngineOptions  map[string]string // Engine options set after feature negotiation with "option"
+	QuitTimeout    time.Duration     // How long Close waits for the engine to quit before killing it, defaults to 5s
+	SearchTimeout  time.Duration     // How long to wait for a search without a time limit, such as a depth search, defaults to 5m
+
+	Transcript *transcript.Transcript // Optional record of every line sent and received
+	InstanceID string                 // Names the engine in the transcript, defaults to its process ID
+}
+
+// Handler manages communication with an XBoard engine. The engine is kept in force
+// mode between searches; every search sets the board with setboard, replays any moves
+// with usermove and lets the engine move once.
+//
+// A Handler is safe for concurrent use; searches and commands own the engine
+// exclusively for their whole command sequence.
+type Handler struct {
+	opts     Options
+	features Features
+	mu       sync.Mutex // Held while a caller owns the engine
+	proc     *engine.Process
+	pings    int // Number of the last ping sent
+	closed   bool
+}
+
+// NewHandler starts the engine described by opts and negotiates its features
+func NewHandler(opts Options) (*Handler, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("engine path is required")
+	}
+	if opts.CommandTimeout <= 0 {
+		opts.CommandTimeout = defaultCommandTimeout
+	}
+	if opts.QuitTimeout <= 0 {
+		opts.QuitTimeout = defaultQuitTimeout
+	}
+	if opts.SearchTimeout <= 0 {
+		opts.SearchTimeout = defaultSearchTimeout
+	}
+
+	proc, err := startProcess(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := &Handler{
+		opts: opts,
+		proc: proc,
+	}
+
+	if err := handler.initializeEngine(); err != nil {
+		handler.Close()
+		return nil, err
+	}
+
+	return handler, nil
+}
+
+func (h *Handler) initializeEngine() error {
+	ctx := context.Background()
+
+	if err := h.send("xboard"); err != nil {
+		return fmt.Errorf("failed to send xboard command: %v", err)
+	}
+	if err := h.send("protover 2"); err != nil {
+		return fmt.Errorf("failed to send protover command: %v", err)
+	}
+
+	if err := h.negotiateFeatures(ctx); err != nil {
+		return err
+	}
+	if !h.features.SetBoard {
+		return fmt.Errorf("engine does not support setboard")
+	}
+
+	// Show thinking, never ponder and wait in force mode until the first search
+	for _, command := range []string{"new", "force", "post", "easy"} {
+		if err := h.send(command); err != nil {
+			return fmt.Errorf("failed to send %s: %v", command, err)
+		}
+	}
+
+	// Apply configured options in a stable order
+	names := make([]string, 0, len(h.opts.EngineOptions))
+	for name := range h.opts.EngineOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := h.send(fmt.Sprintf("option %s=%s", name, h.opts.EngineOptions[name])); err != nil {
+			return fmt.Errorf("failed to set option %s: %v", name, err)
+		}
+	}
+
+	return h.sync(ctx)
+}
+
+// negotiateFeatures answers the engine's feature commands until it sends done=1. An
+// engine that never sends done is assumed to have finished after CommandTimeout, as
+// protocol version 1 engines send no features at all; done=0 asks for more time.
+func (h *Handler) negotiateFeatures(ctx context.Context) error {
+	h.features = Features{Time: true}
+
+	timer := time.NewTimer(h.opts.CommandTimeout)
+	defer timer.Stop()
+
+	for !h.features.Done {
+		line, err := h.readLine(ctx, timer.C)
+		if err == errTimeout {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed waiting for features: %v", err)
+		}
+
+		if !strings.HasPrefix(line, "feature") {
+			continue
+		}
+		pairs, err := parseFeatureLine(line)
+		if err != nil {
+			return err
+		}
+
+		for _, pair := range pairs {
+			reply := "rejected"
+			if h.features.apply(pair) {
+				reply = "accepted"
+			}
+			if err := h.send(fmt.Sprintf("%s %s", reply, pair.Name)); err != nil {
+				return fmt.Errorf("failed to answer feature %s: %v", pair.Name, err)
+			}
+
+			if pair.Name == "done" && pair.Value == "0" {
+				timer.Reset(10 * h.opts.CommandTimeout)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Features returns the features the engine announced
+func (h *Handler) Features() Features {
+	return h.features
+}
+
+// send writes a single command line to the engine
+func (h *Handler) send(command string) error {
+	return h.proc.Write(command)
+}
+
+// readLine returns the next line from the engine. A nil timeout channel waits indefinitely.
+func (h *Handler) readLine(ctx context.Context, timeout <-chan time.Time) (string, error) {
+	select {
+	case line, ok := <-h.proc.Lines():
+		if !ok {
+			return "", errExited
+		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-timeout:
+		return "", errTimeout
+	}
+}
+
+var (
+	errTimeout = fmt.Errorf("timed out waiting for engine")
+	errExited  = fmt.Errorf("engine process exited")
+)
+
+// sync waits until the engine has processed every command sent so far, using ping
+// when the engine supports it
+func (h *Handler) sync(ctx context.Context) error {
+	if !h.features.Ping {
+		return nil
+	}
+
+	h.pings++
+	if err := h.send(fmt.Sprintf("ping %d", h.pings)); err != nil {
+		return fmt.Errorf("failed to send ping: %v", err)
+	}
+
+	want := fmt.Sprintf("pong %d", h.pings)
+	timer := time.NewTimer(h.opts.CommandTimeout)
+	defer timer.Stop()
+
+	for {
+		line, err := h.readLine(ctx, timer.C)
+		if err != nil {
+			return fmt.Errorf("failed waiting for %s: %v", want, err)
+		}
+		if strings.TrimSpace(line) == want {
+			return nil
+		}
+	}
+}
+
+// SearchResult holds the outcome of a search
+type SearchResult struct {
+	BestMove string
+	Info     []uci.Info // Thinking output reported during the search, in the order received
+}
+
+// GetMove returns the engine's move for the given position
+func (h *Handler) GetMove(fen string) (string, error) {
+	return h.GetMoveContext(context.Background(), fen)
+}
+
+// GetMoveContext returns the engine's move for the given position, giving up when ctx is done
+func (h *Handler) GetMoveContext(ctx context.Context, fen string) (string, error) {
+	result, err := h.SearchContext(ctx, fen, nil, Limits{MoveTime: time.Second})
+	if err != nil {
+		return "", err
+	}
+	return result.BestMove, nil
+}
+
+// SearchContext sets up the position given by fen followed by moves in coordinate
+// notation and lets the engine move within limits. Limits persist in the engine until
+// they are changed or NewGame is called. If ctx is done before the engine moves, the
+// engine is forced to move and ctx's error is returned.
+func (h *Handler) SearchContext(ctx context.Context, fen string, moves []string, limits Limits) (*SearchResult, error) {
+	if !limits.Bounded() {
+		return nil, fmt.Errorf("search limits must bound the search")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, fmt.Errorf("handler is closed")
+	}
+	if err := h.sync(ctx); err != nil {
+		return nil, err
+	}
+
+	commands := []string{"force", "setboard " + fen}
+	for _, move := range moves {
+		if h.features.UserMove {
+			move = "usermove " + move
+		}
+		commands = append(commands, move)
+	}
+	commands = append(commands, limits.Commands(h.features.Time)...)
+	commands = append(commands, "go")
+
+	for _, command := range commands {
+		if err := h.send(command); err != nil {
+			return nil, fmt.Errorf("failed to send %s: %v", command, err)
+		}
+	}
+
+	result, err := h.readSearchResult(ctx, h.searchTimeout(limits))
+	if err != nil {
+		return nil, err
+	}
+
+	result.BestMove = normalizeCastling(result.BestMove, whiteToMove(fen, len(moves)))
+	return result, nil
+}
+
+// searchTimeout returns how long to wait for a move: a timed search gets its time plus
+// the command timeout, any other the search timeout
+func (h *Handler) searchTimeout(limits Limits) time.Duration {
+	switch {
+	case limits.MoveTime > 0:
+		return limits.MoveTime + h.opts.CommandTimeout
+	case limits.Time > 0:
+		return limits.Time + limits.Increment + h.opts.CommandTimeout
+	case limits.Base > 0:
+		return limits.Base + limits.Increment + h.opts.CommandTimeout
+	}
+	return h.opts.SearchTimeout
+}
+
+// readSearchResult reads thinking output until the engine moves. On timeout or
+// cancellation the engine is forced to move so that its move does not leak into the
+// next command.
+func (h *Handler) readSearchResult(ctx context.Context, timeout time.Duration) (*SearchResult, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	result := &SearchResult{}
+	for {
+		line, err := h.readLine(ctx, timer.C)
+		if err != nil {
+			if ctx.Err() != nil {
+				h.moveNow()
+				return nil, ctx.Err()
+			}
+			if err == errTimeout {
+				h.moveNow()
+			}
+			return nil, fmt.Errorf("failed to get move: %v", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if move, ok := parseMove(line); ok {
+			result.BestMove = move
+			return result, nil
+		}
+		if info, ok := ParseThinking(line); ok {
+			result.Info = append(result.Info, info)
+			continue
+		}
+		if isFailure(line) {
+			return nil, fmt.Errorf("engine did not move: %s", line)
+		}
+	}
+}
+
+// moveNow forces the engine to move and discards its move
+func (h *Handler) moveNow() error {
+	if err := h.send("?"); err != nil {
+		return fmt.Errorf("failed to send move now: %v", err)
+	}
+
+	timer := time.NewTimer(h.opts.CommandTimeout)
+	defer timer.Stop()
+
+	for {
+		line, err := h.readLine(context.Background(), timer.C)
+		if err != nil {
+			return fmt.Errorf("failed to stop search: %v", err)
+		}
+		if _, ok := parseMove(line); ok {
+			return nil
+		}
+	}
+}
+
+// parseMove parses a "move" command sent by the engine
+func parseMove(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || fields[0] != "move" {
+		return "", false
+	}
+	return fields[1], true
+}
+
+// isFailure returns whether line tells that the engine will not move
+func isFailure(line string) bool {
+	for _, prefix := range []string{"Illegal move", "Error", "tellusererror", "resign", "1-0", "0-1", "1/2-1/2"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// whiteToMove returns whether white is to move after plies moves from fen
+func whiteToMove(fen string, plies int) bool {
+	fields := strings.Fields(fen)
+	white := len(fields) < 2 || fields[1] != "b"
+	return white == (plies%2 == 0)
+}
+
+// normalizeCastling converts castling in O-O notation, which some engines send
+// even in coordinate mode, to the king's move
+func normalizeCastling(move string, white bool) string {
+	rank := "1"
+	if !white {
+		rank = "8"
+	}
+
+	switch strings.ReplaceAll(move, "0", "O") {
+	case "O-O":
+		return "e" + rank + "g" + rank
+	case "O-O-O":
+		return "e" + rank + "c" + rank
+	}
+	return move
+}
+
+// SetOption sets an engine option announced with the option feature
+func (h *Handler) SetOption(name, value string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return fmt.Errorf("handler is closed")
+	}
+	if err := h.send(fmt.Sprintf("option %s=%s", name, value)); err != nil {
+		return fmt.Errorf("failed to set option %s: %v", name, err)
+	}
+	return h.sync(context.Background())
+}
+
+// NewGame resets the engine for a new game, clearing its limits
+func (h *Handler) NewGame() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return fmt.Errorf("handler is closed")
+	}
+	for _, command := range []string{"new", "force"} {
+		if err := h.send(command); err != nil {
+			return fmt.Errorf("failed to send %s: %v", command, err)
+		}
+	}
+	return h.sync(context.Background())
+}
+
+// Close asks the engine to quit, killing it if it does not exit in time. Closing an
+// already closed handler does nothing.
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	return h.proc.Shutdown(h.opts.QuitTimeout, "quit")
+}
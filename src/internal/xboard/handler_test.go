@@ -0,0 +1,315 @@
+package xboard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/transcript"
+)
+
+// shellEngine returns options running script as the engine
+func shellEngine(script string) Options {
+	return Options{
+		Path:           "sh",
+		Args:           []string{"-c", script},
+		CommandTimeout: 500 * time.Millisecond,
+	}
+}
+
+// scriptedEngine negotiates features, logs every command to log and answers "go" with
+// reply. A search for depth 99 only ends when the engine is told to move now.
+func scriptedEngine(log, reply string) string {
+	return fmt.Sprintf(`while read cmd; do
+	echo "$cmd" >> %s
+	case "$cmd" in
+	"protover 2") echo 'feature ping=1 setboard=1 usermove=1 san=1 myname="Shell Engine" done=1' ;;
+	ping*) echo "pong ${cmd#ping }" ;;
+	"sd 99") deep=1 ;;
+	sd*|st*) deep= ;;
+	go) if [ -z "$deep" ]; then printf '%s\n'; fi ;;
+	"?") echo "move a2a3" ;;
+	quit) exit 0 ;;
+	esac
+done`, log, reply)
+}
+
+const searchReply = `1 10 5 100 e2e4\n2 12 10 300 e2e4 e7e5\nmove e2e4`
+
+// readLog returns the commands the engine received
+func readLog(t *testing.T, log string) []string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("Failed to read engine log: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestNegotiateFeatures(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	handler, err := NewHandler(shellEngine(scriptedEngine(log, searchReply)))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	features := handler.Features()
+	if !features.Ping || !features.SetBoard || !features.UserMove || !features.Time {
+		t.Errorf("Unexpected features %+v", features)
+	}
+	if features.Name != "Shell Engine" {
+		t.Errorf("Expected name Shell Engine, got %q", features.Name)
+	}
+
+	expected := []string{
+		"xboard", "protover 2",
+		"accepted ping", "accepted setboard", "accepted usermove", "rejected san", "accepted myname", "accepted done",
+		"new", "force", "post", "easy", "ping 1",
+	}
+	if commands := readLog(t, log); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Engine received %q; want %q", commands, expected)
+	}
+}
+
+func TestProtocolOneEngine(t *testing.T) {
+	opts := shellEngine(`while read cmd; do :; done`)
+	opts.CommandTimeout = 100 * time.Millisecond
+
+	// Without features the engine cannot be given positions
+	if _, err := NewHandler(opts); err == nil {
+		t.Error("Expected error for an engine without setboard but got none")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	handler, err := NewHandler(shellEngine(scriptedEngine(log, searchReply)))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	fen := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	result, err := handler.SearchContext(context.Background(), fen, []string{"g1f3", "g8f6"}, Limits{MoveTime: time.Second, Depth: 6})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.BestMove != "e2e4" {
+		t.Errorf("Expected e2e4, got %s", result.BestMove)
+	}
+	if len(result.Info) != 2 || result.Info[1].Depth != 2 || result.Info[1].Score.CP != 12 {
+		t.Errorf("Unexpected thinking output %+v", result.Info)
+	}
+
+	commands := readLog(t, log)
+	expected := []string{"ping 2", "force", "setboard " + fen, "usermove g1f3", "usermove g8f6", "st 1", "sd 6", "go"}
+	if tail := commands[len(commands)-len(expected):]; !reflect.DeepEqual(tail, expected) {
+		t.Errorf("Engine received %q; want %q", tail, expected)
+	}
+
+	move, err := handler.GetMove(fen)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if move != "e2e4" {
+		t.Errorf("Expected e2e4, got %s", move)
+	}
+}
+
+func TestSearchContextCancel(t *testing.T) {
+	handler, err := NewHandler(shellEngine(scriptedEngine(filepath.Join(t.TempDir(), "log"), searchReply)))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	fen := "8/8/8/8/8/8/8/K6k w - - 0 1"
+	if _, err := handler.SearchContext(ctx, fen, nil, Limits{Depth: 99}); err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+
+	// The forced move must not be mistaken for the next search's answer
+	result, err := handler.SearchContext(context.Background(), fen, nil, Limits{Depth: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.BestMove != "e2e4" {
+		t.Errorf("Expected e2e4, got %s", result.BestMove)
+	}
+}
+
+func TestDepthSearchTimeout(t *testing.T) {
+	opts := shellEngine(scriptedEngine(filepath.Join(t.TempDir(), "log"), searchReply))
+	opts.SearchTimeout = 200 * time.Millisecond
+	handler, err := NewHandler(opts)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	// A depth search that never ends is given up after the search timeout
+	fen := "8/8/8/8/8/8/8/K6k w - - 0 1"
+	start := time.Now()
+	if _, err := handler.SearchContext(context.Background(), fen, nil, Limits{Depth: 99}); err == nil {
+		t.Fatal("Expected a timeout but got none")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Gave up on the search after %v", elapsed)
+	}
+
+	result, err := handler.SearchContext(context.Background(), fen, nil, Limits{Depth: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.BestMove != "e2e4" {
+		t.Errorf("Expected e2e4, got %s", result.BestMove)
+	}
+}
+
+func TestSearchFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		reply       string
+		fen         string
+		moves       []string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "Castling for black",
+			reply:    "move O-O",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			moves:    []string{"a1b1"},
+			expected: "e8g8",
+		},
+		{
+			name:     "Long castling for white",
+			reply:    "move 0-0-0",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			expected: "e1c1",
+		},
+		{
+			name:        "Resignation",
+			reply:       "resign",
+			fen:         "8/8/8/8/8/8/8/K6k w - - 0 1",
+			expectError: true,
+		},
+		{
+			name:        "Illegal position",
+			reply:       "tellusererror Illegal position",
+			fen:         "8/8/8/8/8/8/8/8 w - - 0 1",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewHandler(shellEngine(scriptedEngine(filepath.Join(t.TempDir(), "log"), tt.reply)))
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+			defer handler.Close()
+
+			result, err := handler.SearchContext(context.Background(), tt.fen, tt.moves, Limits{Depth: 1})
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.BestMove != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result.BestMove)
+			}
+		})
+	}
+}
+
+func TestSearchRequiresLimits(t *testing.T) {
+	handler, err := NewHandler(shellEngine(scriptedEngine(filepath.Join(t.TempDir(), "log"), searchReply)))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	defer handler.Close()
+
+	if _, err := handler.SearchContext(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1", nil, Limits{}); err == nil {
+		t.Error("Expected error for unbounded search but got none")
+	}
+}
+
+func TestClose(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	handler, err := NewHandler(shellEngine(scriptedEngine(log, searchReply)))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	commands := readLog(t, log)
+	if commands[len(commands)-1] != "quit" {
+		t.Errorf("Expected the engine to receive quit, got %q", commands)
+	}
+
+	if _, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1"); err == nil {
+		t.Error("Expected error after close but got none")
+	}
+
+	// Closing twice is harmless
+	if err := handler.Close(); err != nil {
+		t.Errorf("Unexpected error on second close: %v", err)
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	var out strings.Builder
+	opts := shellEngine(scriptedEngine(filepath.Join(t.TempDir(), "log"), searchReply))
+	opts.Transcript = transcript.New(&out)
+	opts.InstanceID = "black"
+
+	handler, err := NewHandler(opts)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	if _, err := handler.GetMove("8/8/8/8/8/8/8/K6k w - - 0 1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	handler.Close()
+
+	// The transcript is complete once the process has been reaped
+	expected := []string{
+		"[black] # started",
+		"[black] > xboard",
+		"[black] > protover 2",
+		"[black] < feature ping=1",
+		"[black] > go",
+		"[black] < move e2e4",
+		"[black] > quit",
+		"[black] # exited",
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	next := 0
+	for _, line := range lines {
+		if next < len(expected) && strings.Contains(line, expected[next]) {
+			next++
+		}
+	}
+	if next < len(expected) {
+		t.Errorf("Transcript is missing %q:\n%s", expected[next], out.String())
+	}
+}
@@ -0,0 +1,15 @@
+package xboard
+
+import (
+	"github.com/shehio/envoy/src/internal/engine"
+)
+
+// startProcess starts the engine binary described by opts
+func startProcess(opts Options) (*engine.Process, error) {
+	return engine.Start(engine.Config{
+		Path:       opts.Path,
+		Args:       opts.Args,
+		Transcript: opts.Transcript,
+		InstanceID: opts.InstanceID,
+	})
+}
@@ -0,0 +1,125 @@
+package xboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+// mateScore is the score engines conventionally report for a mate, plus or minus the
+// distance to mate in moves
+const mateScore = 100000
+
+// ParseThinking parses a thinking output line of the form "ply score time nodes pv",
+// with the time in centiseconds. The PV is kept in the engine's own notation.
+func ParseThinking(line string) (uci.Info, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return uci.Info{}, false
+	}
+
+	// Some engines decorate the depth, e.g. "12." or "12&"
+	depth, err := strconv.Atoi(strings.TrimRight(fields[0], ".&"))
+	if err != nil {
+		return uci.Info{}, false
+	}
+
+	score, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return uci.Info{}, false
+	}
+
+	centis, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return uci.Info{}, false
+	}
+
+	nodes, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return uci.Info{}, false
+	}
+
+	info := uci.Info{
+		Depth:    depth,
+		HasScore: true,
+		Nodes:    nodes,
+		Time:     time.Duration(centis) * 10 * time.Millisecond,
+		MultiPV:  1,
+	}
+
+	switch {
+	case score >= mateScore:
+		info.Score = uci.Score{Mate: score - mateScore, IsMate: true}
+	case score <= -mateScore:
+		info.Score = uci.Score{Mate: score + mateScore, IsMate: true}
+	default:
+		info.Score = uci.Score{CP: score}
+	}
+
+	// Extra statistics and move numbers may be mixed into the PV; only moves are kept
+	for _, field := range fields[4:] {
+		if _, err := strconv.Atoi(strings.TrimRight(field, ".")); err == nil {
+			continue
+		}
+		info.PV = append(info.PV, field)
+	}
+
+	return info, true
+}
+
+// Limits constrains an engine's thinking. A zero value leaves the engine's current
+// setting unchanged.
+type Limits struct {
+	Depth        int           // Maximum depth in plies, sent with "sd"
+	MoveTime     time.Duration // Exact time per move, sent with "st"
+	MovesPerTC   int           // Moves per time control for "level", 0 for incremental
+	Base         time.Duration // Base time for "level"
+	Increment    time.Duration // Increment per move for "level"
+	Time         time.Duration // Engine's remaining clock time, sent with "time"
+	OpponentTime time.Duration // Opponent's remaining clock time, sent with "otim"
+}
+
+// Commands returns the commands setting the limits, in the order they must be sent
+// before "go"
+func (l Limits) Commands(clockFeature bool) []string {
+	var commands []string
+	if l.Base > 0 {
+		commands = append(commands, fmt.Sprintf("level %d %s %s", l.MovesPerTC, formatBase(l.Base), formatSeconds(l.Increment)))
+	}
+	if l.MoveTime > 0 {
+		// st takes whole seconds
+		seconds := (l.MoveTime + time.Second - 1) / time.Second
+		commands = append(commands, fmt.Sprintf("st %d", seconds))
+	}
+	if l.Depth > 0 {
+		commands = append(commands, fmt.Sprintf("sd %d", l.Depth))
+	}
+	if clockFeature && l.Time > 0 {
+		commands = append(commands, fmt.Sprintf("time %d", l.Time/(10*time.Millisecond)))
+		commands = append(commands, fmt.Sprintf("otim %d", l.OpponentTime/(10*time.Millisecond)))
+	}
+	return commands
+}
+
+// Bounded returns whether the limits end the engine's thinking by themselves
+func (l Limits) Bounded() bool {
+	return l.Depth > 0 || l.MoveTime > 0 || l.Base > 0 || l.Time > 0
+}
+
+// formatBase formats the base time of a "level" command as minutes or minutes:seconds
+func formatBase(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	seconds := int((d % time.Minute) / time.Second)
+	if seconds == 0 {
+		return strconv.Itoa(minutes)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+// formatSeconds formats a duration in seconds, with a fraction only when needed
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
@@ -0,0 +1,119 @@
+package xboard
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shehio/envoy/src/internal/uci"
+)
+
+func TestParseThinking(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected uci.Info
+		ok       bool
+	}{
+		{
+			name: "Centipawn score",
+			line: "9 156 1084 48000 Nf3 Nc6 Nc3 Nf6",
+			expected: uci.Info{
+				Depth: 9, Score: uci.Score{CP: 156}, HasScore: true, Nodes: 48000,
+				Time: 10840 * time.Millisecond, MultiPV: 1, PV: []string{"Nf3", "Nc6", "Nc3", "Nf6"},
+			},
+			ok: true,
+		},
+		{
+			name: "Decorated depth and move numbers",
+			line: "12. -35 250 1000000 1. e4 e5 2. Nf3",
+			expected: uci.Info{
+				Depth: 12, Score: uci.Score{CP: -35}, HasScore: true, Nodes: 1000000,
+				Time: 2500 * time.Millisecond, MultiPV: 1, PV: []string{"e4", "e5", "Nf3"},
+			},
+			ok: true,
+		},
+		{
+			name: "Mate score",
+			line: "20 100003 10 500 Qh5",
+			expected: uci.Info{
+				Depth: 20, Score: uci.Score{Mate: 3, IsMate: true}, HasScore: true, Nodes: 500,
+				Time: 100 * time.Millisecond, MultiPV: 1, PV: []string{"Qh5"},
+			},
+			ok: true,
+		},
+		{
+			name: "Mated score",
+			line: "20 -100002 10 500",
+			expected: uci.Info{
+				Depth: 20, Score: uci.Score{Mate: -2, IsMate: true}, HasScore: true, Nodes: 500,
+				Time: 100 * time.Millisecond, MultiPV: 1,
+			},
+			ok: true,
+		},
+		{
+			name: "Move command",
+			line: "move e2e4",
+		},
+		{
+			name: "Too few fields",
+			line: "9 156 1084",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseThinking(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ParseThinking(%q) ok = %v; want %v", tt.line, ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(info, tt.expected) {
+				t.Errorf("ParseThinking(%q) = %+v; want %+v", tt.line, info, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLimitsCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   Limits
+		clock    bool
+		expected []string
+	}{
+		{
+			name:     "Fixed time per move",
+			limits:   Limits{MoveTime: 1500 * time.Millisecond},
+			expected: []string{"st 2"},
+		},
+		{
+			name:     "Depth",
+			limits:   Limits{Depth: 8},
+			expected: []string{"sd 8"},
+		},
+		{
+			name:     "Incremental level with clocks",
+			limits:   Limits{Base: 5 * time.Minute, Increment: 3 * time.Second, Time: 290 * time.Second, OpponentTime: 280 * time.Second},
+			clock:    true,
+			expected: []string{"level 0 5 3", "time 29000", "otim 28000"},
+		},
+		{
+			name:     "Classical level with seconds",
+			limits:   Limits{MovesPerTC: 40, Base: 90 * time.Second, Increment: 500 * time.Millisecond},
+			expected: []string{"level 40 1:30 0.5"},
+		},
+		{
+			name:     "Clocks without time feature",
+			limits:   Limits{Time: time.Minute, OpponentTime: time.Minute},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if commands := tt.limits.Commands(tt.clock); !reflect.DeepEqual(commands, tt.expected) {
+				t.Errorf("Commands() = %q; want %q", commands, tt.expected)
+			}
+		})
+	}
+}